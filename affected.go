@@ -0,0 +1,24 @@
+package uow
+
+import "context"
+
+// RecordAffected adds n to the number of rows or documents affected by the
+// unit of work that ctx belongs to. The accumulated total is reported in the
+// Summary and returned by RunCounting, which avoids threading counts through
+// closures. Outside a unit of work the call is ignored.
+func RecordAffected(ctx context.Context, n int64) {
+	if rs := runStateFrom(ctx); rs != nil {
+		rs.affected.Add(n)
+	}
+}
+
+// RunCounting runs fn through u like UoW.Run and additionally returns the
+// number of affected rows or documents reported with RecordAffected during the
+// final attempt.
+func RunCounting(ctx context.Context, u *UoW, fn func(ctx context.Context) error) (int64, error) {
+	rs, err := u.execute(ctx, fn)
+	if rs == nil {
+		return 0, err
+	}
+	return rs.affected.Load(), err
+}
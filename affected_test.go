@@ -0,0 +1,44 @@
+package uow
+
+import (
+	"context"
+	"testing"
+)
+
+// TestRunCounting verifies that counts reported across several operations
+// inside fn are accumulated and returned.
+func TestRunCounting(t *testing.T) {
+	u := New(NewMockTx())
+	n, err := RunCounting(context.Background(), &u, func(ctx context.Context) error {
+		RecordAffected(ctx, 2)
+		RecordAffected(ctx, 3)
+		RecordAffected(ctx, 0)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 5 {
+		t.Errorf("expected 5 affected, got %d", n)
+	}
+}
+
+// TestRecordAffected_Summary verifies that the accumulated count is reported
+// in the Summary handed to the audit writer.
+func TestRecordAffected_Summary(t *testing.T) {
+	var summary Summary
+	u := New(NewMockTx(), WithAuditWriter(func(_ context.Context, s Summary) error {
+		summary = s
+		return nil
+	}))
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		RecordAffected(ctx, 4)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Affected != 4 {
+		t.Errorf("expected summary to report 4 affected, got %d", summary.Affected)
+	}
+}
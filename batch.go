@@ -0,0 +1,91 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// BatchOption configures RunInBatches.
+type BatchOption func(*batchConfig)
+
+// batchConfig holds the settings of a RunInBatches call.
+type batchConfig struct {
+	// continueOnError keeps processing the remaining batches after a failure.
+	continueOnError bool
+}
+
+// ContinueOnError makes RunInBatches process every batch even when some of
+// them fail. The failures are collected and returned together.
+func ContinueOnError() BatchOption {
+	return func(c *batchConfig) {
+		c.continueOnError = true
+	}
+}
+
+// RunInBatches splits items into consecutive batches of at most size items and
+// runs fn for each of them in its own unit of work through u, keeping every
+// transaction small. A batch that fails is rolled back; batches committed
+// before it stay committed. By default processing stops at the first failed
+// batch; with ContinueOnError the remaining batches are still processed and
+// the failures are joined. Errors name the failed batch and the range of
+// items it covered.
+func RunInBatches[T any](ctx context.Context, u *UoW, items []T, size int, fn func(ctx context.Context, batch []T) error, opts ...BatchOption) error {
+	if size <= 0 {
+		return fmt.Errorf("invalid batch size %d", size)
+	}
+	var cfg batchConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var errs []error
+	for start, n := 0, 0; start < len(items); start, n = start+size, n+1 {
+		end := min(start+size, len(items))
+		// Cap the batch so that appending to it does not overwrite the
+		// items of the next batches.
+		batch := items[start:end:end]
+		err := u.Run(ctx, func(ctx context.Context) error {
+			return fn(ctx, batch)
+		})
+		if err == nil {
+			continue
+		}
+		err = fmt.Errorf("batch %d (items %d-%d) failed: %w", n, start, end-1, err)
+		if !cfg.continueOnError {
+			return err
+		}
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
+
+// ErrSkipped is reported by RunAllFailFast for the functions it did not run
+// because an earlier one failed.
+var ErrSkipped = errors.New("unit of work skipped after an earlier failure")
+
+// RunAll runs each of fns in its own unit of work through u, one after the
+// other, and returns their errors in the order of fns, with nil entries for
+// the ones that committed. A failing function does not affect the others.
+func RunAll(ctx context.Context, u *UoW, fns ...func(ctx context.Context) error) []error {
+	errs := make([]error, len(fns))
+	for i, fn := range fns {
+		errs[i] = u.Run(ctx, fn)
+	}
+	return errs
+}
+
+// RunAllFailFast is like RunAll but stops at the first function that fails.
+// The entries of the functions that were not run are ErrSkipped.
+func RunAllFailFast(ctx context.Context, u *UoW, fns ...func(ctx context.Context) error) []error {
+	errs := make([]error, len(fns))
+	for i, fn := range fns {
+		if errs[i] = u.Run(ctx, fn); errs[i] != nil {
+			for j := i + 1; j < len(fns); j++ {
+				errs[j] = ErrSkipped
+			}
+			break
+		}
+	}
+	return errs
+}
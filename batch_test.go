@@ -0,0 +1,125 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestRunInBatches verifies that every batch commits in its own transaction
+// and that processing stops at the first failure by default.
+func TestRunInBatches(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	mt := NewMockTx()
+	u := New(mt)
+
+	var batches [][]int
+	err := RunInBatches(context.Background(), &u, items, 2, func(_ context.Context, batch []int) error {
+		batches = append(batches, batch)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := [][]int{{1, 2}, {3, 4}, {5}}; !reflect.DeepEqual(batches, want) {
+		t.Errorf("expected batches %v, got %v", want, batches)
+	}
+	if n := mt.CallCount("commit"); n != 3 {
+		t.Errorf("expected 3 commits, got %d", n)
+	}
+
+	batchErr := errors.New("batch failed")
+	calls := 0
+	err = RunInBatches(context.Background(), &u, items, 2, func(_ context.Context, batch []int) error {
+		calls++
+		if batch[0] == 3 {
+			return batchErr
+		}
+		return nil
+	})
+	if !errors.Is(err, batchErr) || !strings.Contains(err.Error(), "batch 1 (items 2-3)") {
+		t.Errorf("expected the failed batch to be reported, got %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("expected processing to stop after the failed batch, got %d calls", calls)
+	}
+}
+
+// TestRunInBatches_ContinueOnError verifies that all batches are processed
+// and every failure is returned.
+func TestRunInBatches_ContinueOnError(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+	mt := NewMockTx()
+	u := New(mt)
+
+	errOdd := errors.New("odd batch")
+	calls := 0
+	err := RunInBatches(context.Background(), &u, items, 2, func(_ context.Context, batch []int) error {
+		calls++
+		if batch[0]%4 == 1 {
+			return errOdd
+		}
+		return nil
+	}, ContinueOnError())
+	if calls != 3 {
+		t.Errorf("expected every batch to be processed, got %d calls", calls)
+	}
+	if !errors.Is(err, errOdd) || !strings.Contains(err.Error(), "batch 0") || !strings.Contains(err.Error(), "batch 2") {
+		t.Errorf("expected the failures of batches 0 and 2, got %v", err)
+	}
+	if n := mt.CallCount("commit"); n != 1 {
+		t.Errorf("expected 1 commit, got %d", n)
+	}
+
+	if err := RunInBatches(context.Background(), &u, items, 0, func(context.Context, []int) error { return nil }); err == nil {
+		t.Error("expected an error for a zero batch size")
+	}
+}
+
+// TestRunInBatches_AppendToBatch verifies that appending to a batch leaves
+// the items of the next batches untouched.
+func TestRunInBatches_AppendToBatch(t *testing.T) {
+	u := New(NewMockTx())
+	items := []int{1, 2, 3, 4}
+
+	var seen []int
+	err := RunInBatches(context.Background(), &u, items, 2, func(_ context.Context, batch []int) error {
+		seen = append(seen, batch...)
+		_ = append(batch, 0)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{1, 2, 3, 4}; !reflect.DeepEqual(seen, want) || !reflect.DeepEqual(items, want) {
+		t.Errorf("expected batches and items %v, got %v and %v", want, seen, items)
+	}
+}
+
+// TestRunAll verifies that every function runs in its own unit of work and
+// that the errors are reported per function.
+func TestRunAll(t *testing.T) {
+	mt := NewMockTx()
+	u := New(mt)
+	fnErr := errors.New("fn failed")
+	ok := func(context.Context) error { return nil }
+	fail := func(context.Context) error { return fnErr }
+
+	errs := RunAll(context.Background(), &u, ok, fail, ok)
+	if want := []error{nil, fnErr, nil}; !reflect.DeepEqual(errs, want) {
+		t.Errorf("expected %v, got %v", want, errs)
+	}
+	if n := mt.CallCount("commit"); n != 2 {
+		t.Errorf("expected 2 commits, got %d", n)
+	}
+
+	errs = RunAllFailFast(context.Background(), &u, ok, fail, ok)
+	if want := []error{nil, fnErr, ErrSkipped}; !reflect.DeepEqual(errs, want) {
+		t.Errorf("expected %v, got %v", want, errs)
+	}
+	if n := mt.CallCount("commit"); n != 3 {
+		t.Errorf("expected the skipped function not to run, got %d commits", n)
+	}
+}
@@ -0,0 +1,198 @@
+package uow
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"strconv"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltTxKey is the context key for storing the BoltDB transaction.
+var boltTxKey = ctxKey{"bolt_tx"}
+
+// boltJoinedKey is the context key marking a unit of work that joined the
+// BoltDB transaction of an enclosing one.
+var boltJoinedKey = ctxKey{"bolt_joined"}
+
+// ErrBoltWrongGoroutine is returned when a BoltDB transaction is accessed,
+// committed or rolled back from a goroutine other than the one that began it.
+var ErrBoltWrongGoroutine = errors.New("bolt transaction used from a goroutine other than the one that began it")
+
+// BoltTx implements the Runner interface for BoltDB read-write transactions.
+// BoltDB allows only one writer at a time and requires that a read-write
+// transaction is only used by the goroutine that created it. BoltTx enforces
+// both rules: writers are serialized through a single-slot queue that honors
+// context cancellation, and every access to the transaction is checked
+// against the goroutine that began it.
+//
+// BoltDB has no nested transactions, so when ctx already carries a
+// transaction, a nested unit of work joins it instead of waiting for the
+// writer slot the enclosing one holds: Commit and Rollback of the inner unit
+// of work are no-ops and the outermost unit of work decides the outcome.
+var _ Runner = &BoltTx{}
+
+// BoltTx struct holds the BoltDB handle and the writer slot.
+type BoltTx struct {
+	db     *bolt.DB
+	writer chan struct{}
+}
+
+// boltTx is stored in the context for the duration of a transaction and
+// remembers which goroutine owns it.
+type boltTx struct {
+	tx        *bolt.Tx
+	goroutine uint64
+	done      bool
+	readOnly  bool
+}
+
+// NewBoltTx creates a new BoltTx instance. It takes an opened BoltDB handle
+// as an argument. All transactions started by the returned runner share a
+// single writer slot, so only one of them is active at any time.
+func NewBoltTx(db *bolt.DB) *BoltTx {
+	return &BoltTx{
+		db:     db,
+		writer: make(chan struct{}, 1),
+	}
+}
+
+// Ctx waits for the writer slot and starts a new read-write transaction. The
+// wait is aborted when the provided context is done. The calling goroutine
+// becomes the owner of the transaction. A read-only unit of work begins a
+// read-only transaction instead, without waiting for the writer slot. When
+// ctx already carries a transaction, it is joined; joining it from a goroutine
+// other than its owner returns ErrBoltWrongGoroutine.
+func (b *BoltTx) Ctx(ctx context.Context) (context.Context, error) {
+	if outer, ok := ctx.Value(boltTxKey).(*boltTx); ok {
+		if outer.goroutine != goroutineID() {
+			return nil, ErrBoltWrongGoroutine
+		}
+		return context.WithValue(ctx, boltJoinedKey, outer), nil
+	}
+
+	if IsReadOnly(ctx) {
+		tx, err := b.db.Begin(false)
+		if err != nil {
+			return nil, fmt.Errorf("error in starting transaction: %w", err)
+		}
+		return context.WithValue(ctx, boltTxKey, &boltTx{tx: tx, goroutine: goroutineID(), readOnly: true}), nil
+	}
+
+	select {
+	case b.writer <- struct{}{}:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("error in waiting for writer: %w", ctx.Err())
+	}
+
+	tx, err := b.db.Begin(true)
+	if err != nil {
+		<-b.writer
+		return nil, fmt.Errorf("error in starting transaction: %w", err)
+	}
+	return context.WithValue(ctx, boltTxKey, &boltTx{tx: tx, goroutine: goroutineID()}), nil
+}
+
+// Get retrieves the BoltDB transaction. If a transaction exists in the
+// context and the caller is its owner, it returns the *bolt.Tx. When called
+// from another goroutine it returns nil; use Tx to get a descriptive error
+// instead. Outside a transaction it returns the *bolt.DB.
+func (b *BoltTx) Get(ctx context.Context) any {
+	if _, ok := ctx.Value(boltTxKey).(*boltTx); ok {
+		tx, err := b.Tx(ctx)
+		if err != nil {
+			return nil
+		}
+		return tx
+	}
+	return b.db
+}
+
+// Tx returns the BoltDB transaction stored in the context. It returns
+// ErrBoltWrongGoroutine when called from a goroutine other than the owner,
+// and an error when no transaction is present.
+func (b *BoltTx) Tx(ctx context.Context) (*bolt.Tx, error) {
+	state, ok := ctx.Value(boltTxKey).(*boltTx)
+	if !ok {
+		return nil, errors.New("no bolt transaction in context")
+	}
+	if state.goroutine != goroutineID() {
+		return nil, ErrBoltWrongGoroutine
+	}
+	return state.tx, nil
+}
+
+// Rollback aborts the current transaction and frees the writer slot. In a
+// unit of work that joined an enclosing transaction it does nothing. When
+// called from a goroutine other than the owner it returns
+// ErrBoltWrongGoroutine and leaves the transaction open so that the owner can
+// still finish it.
+func (b *BoltTx) Rollback(ctx context.Context) error {
+	state, ok := b.owned(ctx)
+	if !ok {
+		return nil
+	}
+	if state.goroutine != goroutineID() {
+		return ErrBoltWrongGoroutine
+	}
+	if state.done {
+		return bolt.ErrTxClosed
+	}
+	state.done = true
+	if !state.readOnly {
+		defer func() { <-b.writer }()
+	}
+	return state.tx.Rollback()
+}
+
+// Commit commits the current transaction and frees the writer slot. In a unit
+// of work that joined an enclosing transaction it does nothing. When
+// called from a goroutine other than the owner it returns
+// ErrBoltWrongGoroutine and leaves the transaction open so that the owner can
+// still finish it.
+func (b *BoltTx) Commit(ctx context.Context) error {
+	state, ok := b.owned(ctx)
+	if !ok {
+		return nil
+	}
+	if state.goroutine != goroutineID() {
+		return ErrBoltWrongGoroutine
+	}
+	if state.done {
+		return bolt.ErrTxClosed
+	}
+	state.done = true
+	if !state.readOnly {
+		defer func() { <-b.writer }()
+	}
+	return state.tx.Commit()
+}
+
+// owned returns the transaction in ctx when the unit of work ctx belongs to
+// started it.
+func (b *BoltTx) owned(ctx context.Context) (*boltTx, bool) {
+	state, ok := ctx.Value(boltTxKey).(*boltTx)
+	if !ok {
+		return nil, false
+	}
+	if outer, joined := ctx.Value(boltJoinedKey).(*boltTx); joined && outer == state {
+		return nil, false
+	}
+	return state, true
+}
+
+// goroutineID returns the identifier of the calling goroutine, parsed from
+// the header of its stack trace ("goroutine 42 [running]:").
+func goroutineID() uint64 {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	field := bytes.TrimPrefix(buf[:n], []byte("goroutine "))
+	if i := bytes.IndexByte(field, ' '); i >= 0 {
+		field = field[:i]
+	}
+	id, _ := strconv.ParseUint(string(field), 10, 64)
+	return id
+}
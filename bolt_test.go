@@ -0,0 +1,184 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// openBolt opens a BoltDB file in a temporary directory with a "test" bucket.
+func openBolt(t *testing.T) *bolt.DB {
+	t.Helper()
+	db, err := bolt.Open(filepath.Join(t.TempDir(), "test.db"), 0o600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucket([]byte("test"))
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// TestBoltTx_SingleWriter verifies that concurrent units of work never have
+// more than one writer active and that all their writes are committed.
+func TestBoltTx_SingleWriter(t *testing.T) {
+	db := openBolt(t)
+	txs := New(NewBoltTx(db))
+
+	var active, maxActive int32
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			err := txs.Run(context.Background(), func(ctx context.Context) error {
+				n := atomic.AddInt32(&active, 1)
+				defer atomic.AddInt32(&active, -1)
+				for {
+					m := atomic.LoadInt32(&maxActive)
+					if n <= m || atomic.CompareAndSwapInt32(&maxActive, m, n) {
+						break
+					}
+				}
+				time.Sleep(time.Millisecond)
+
+				tx := txs.Get(ctx).(*bolt.Tx)
+				return tx.Bucket([]byte("test")).Put([]byte(fmt.Sprintf("key-%d", i)), []byte("value"))
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if maxActive != 1 {
+		t.Errorf("expected at most 1 active writer, got %d", maxActive)
+	}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		if n := tx.Bucket([]byte("test")).Stats().KeyN; n != 8 {
+			t.Errorf("expected 8 keys, got %d", n)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestBoltTx_WrongGoroutine verifies that using the transaction from another
+// goroutine returns ErrBoltWrongGoroutine and leaves the transaction usable by
+// its owner.
+func TestBoltTx_WrongGoroutine(t *testing.T) {
+	db := openBolt(t)
+	boltTx := NewBoltTx(db)
+	txs := New(boltTx)
+
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		var txErr, commitErr error
+		var got any
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			got = boltTx.Get(ctx)
+			_, txErr = boltTx.Tx(ctx)
+			commitErr = boltTx.Commit(ctx)
+		}()
+		<-done
+
+		if got != nil {
+			t.Errorf("expected nil from Get on another goroutine, got %T", got)
+		}
+		if !errors.Is(txErr, ErrBoltWrongGoroutine) {
+			t.Errorf("expected ErrBoltWrongGoroutine from Tx, got %v", txErr)
+		}
+		if !errors.Is(commitErr, ErrBoltWrongGoroutine) {
+			t.Errorf("expected ErrBoltWrongGoroutine from Commit, got %v", commitErr)
+		}
+
+		tx, err := boltTx.Tx(ctx)
+		if err != nil {
+			return err
+		}
+		return tx.Bucket([]byte("test")).Put([]byte("key"), []byte("value"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.View(func(tx *bolt.Tx) error {
+		if v := tx.Bucket([]byte("test")).Get([]byte("key")); string(v) != "value" {
+			t.Errorf("expected committed value, got %q", v)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestBoltTx_CtxCancelledWhileWaiting verifies that a writer waiting for the
+// slot gives up when its context is done.
+func TestBoltTx_CtxCancelledWhileWaiting(t *testing.T) {
+	db := openBolt(t)
+	boltTx := NewBoltTx(db)
+
+	ctx, err := boltTx.Ctx(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = boltTx.Rollback(ctx) }()
+
+	waitCtx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = boltTx.Ctx(waitCtx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+}
+
+// TestBoltTx_NestedRun verifies that a nested unit of work joins the enclosing
+// transaction instead of waiting for the writer slot it holds.
+func TestBoltTx_NestedRun(t *testing.T) {
+	db := openBolt(t)
+	txs := New(NewBoltTx(db))
+	put := func(ctx context.Context, key string) error {
+		return txs.Get(ctx).(*bolt.Tx).Bucket([]byte("test")).Put([]byte(key), []byte("value"))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	err := txs.Run(ctx, func(ctx context.Context) error {
+		if err := put(ctx, "outer"); err != nil {
+			return err
+		}
+		return txs.Run(ctx, func(ctx context.Context) error { return put(ctx, "inner") })
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = db.View(func(tx *bolt.Tx) error {
+		if n := tx.Bucket([]byte("test")).Stats().KeyN; n != 2 {
+			t.Errorf("expected 2 keys, got %d", n)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
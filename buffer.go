@@ -0,0 +1,79 @@
+package uow
+
+import (
+	"context"
+	"fmt"
+)
+
+// WriteBuffer collects items enqueued during a unit of work and writes them in
+// a single batch right before commit, inside the transaction. This reduces
+// round-trips while preserving atomicity: the flushed items commit together
+// with the rest of the transaction, and items buffered by an attempt that
+// rolls back are discarded without being written.
+//
+// A WriteBuffer is typically created once, e.g. by a repository constructor,
+// and shared by all units of work; the buffered items are scoped to the run
+// that ctx belongs to.
+type WriteBuffer[T any] struct {
+	flush func(ctx context.Context, items []T) error
+}
+
+// bufferedItems holds the items of one WriteBuffer for one attempt.
+type bufferedItems[T any] struct {
+	items []T
+}
+
+// NewWriteBuffer creates a new WriteBuffer. flush receives the transactional
+// context and all items enqueued during the run; it is not called when no
+// items were enqueued. An error returned by flush rolls the transaction back.
+func NewWriteBuffer[T any](flush func(ctx context.Context, items []T) error) *WriteBuffer[T] {
+	return &WriteBuffer[T]{
+		flush: flush,
+	}
+}
+
+// Add enqueues items in the buffer of the unit of work that ctx belongs to. It
+// returns ErrNoTransaction when ctx does not belong to a unit of work.
+func (b *WriteBuffer[T]) Add(ctx context.Context, items ...T) error {
+	rs := runStateFrom(ctx)
+	if rs == nil {
+		return ErrNoTransaction
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.buffers == nil {
+		rs.buffers = make(map[any]any)
+	}
+	buf, ok := rs.buffers[b].(*bufferedItems[T])
+	if !ok {
+		buf = &bufferedItems[T]{}
+		rs.buffers[b] = buf
+		rs.preCommit = append(rs.preCommit, func(ctx context.Context) error {
+			rs.mu.Lock()
+			pending := buf.items
+			rs.mu.Unlock()
+			if err := b.flush(ctx, pending); err != nil {
+				return fmt.Errorf("failed to flush write buffer: %w", err)
+			}
+			return nil
+		})
+	}
+	buf.items = append(buf.items, items...)
+	return nil
+}
+
+// Len returns the number of items buffered by the unit of work that ctx
+// belongs to.
+func (b *WriteBuffer[T]) Len(ctx context.Context) int {
+	rs := runStateFrom(ctx)
+	if rs == nil {
+		return 0
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if buf, ok := rs.buffers[b].(*bufferedItems[T]); ok {
+		return len(buf.items)
+	}
+	return 0
+}
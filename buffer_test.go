@@ -0,0 +1,91 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestWriteBuffer_FlushBeforeCommit verifies that buffered items are flushed
+// in one batch inside the transaction, before commit.
+func TestWriteBuffer_FlushBeforeCommit(t *testing.T) {
+	mt := NewMockTx()
+	u := New(mt)
+
+	var flushed [][]int
+	buf := NewWriteBuffer(func(_ context.Context, items []int) error {
+		if got := mt.state.Value(); got != "" {
+			t.Errorf("expected flush before commit, got state %q", got)
+		}
+		flushed = append(flushed, items)
+		return nil
+	})
+
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		if err := buf.Add(ctx, 1, 2); err != nil {
+			return err
+		}
+		if err := buf.Add(ctx, 3); err != nil {
+			return err
+		}
+		if n := buf.Len(ctx); n != 3 {
+			t.Errorf("expected 3 buffered items, got %d", n)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := [][]int{{1, 2, 3}}; !reflect.DeepEqual(flushed, want) {
+		t.Errorf("expected flushes %v, got %v", want, flushed)
+	}
+	if got := mt.state.Value(); got != " committed!" {
+		t.Errorf("expected commit, got state %q", got)
+	}
+}
+
+// TestWriteBuffer_DiscardedOnRollback verifies that items buffered by a failed
+// unit of work are never flushed.
+func TestWriteBuffer_DiscardedOnRollback(t *testing.T) {
+	fnErr := errors.New("fn failed")
+	u := New(NewMockTx())
+	buf := NewWriteBuffer(func(_ context.Context, _ []string) error {
+		t.Error("expected no flush on rollback")
+		return nil
+	})
+
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		if err := buf.Add(ctx, "a"); err != nil {
+			return err
+		}
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) {
+		t.Errorf("expected fn error, got %v", err)
+	}
+}
+
+// TestWriteBuffer_FlushError verifies that a failing flush rolls back.
+func TestWriteBuffer_FlushError(t *testing.T) {
+	flushErr := errors.New("flush failed")
+	mt := NewMockTx()
+	u := New(mt)
+	buf := NewWriteBuffer(func(_ context.Context, _ []string) error {
+		return flushErr
+	})
+
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		return buf.Add(ctx, "a")
+	})
+	if !errors.Is(err, flushErr) {
+		t.Errorf("expected flush error, got %v", err)
+	}
+	if got := mt.state.Value(); got != " rolled back!" {
+		t.Errorf("expected rollback, got state %q", got)
+	}
+	if err := buf.Add(context.Background(), "a"); !errors.Is(err, ErrNoTransaction) {
+		t.Errorf("expected ErrNoTransaction outside a unit of work, got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/uptrace/bun"
+)
+
+// bunTxKey is the context key for storing the bun transaction.
+var bunTxKey = ctxKey{"bun_tx"}
+
+// BunTx implements the Runner interface for github.com/uptrace/bun. Get
+// returns a bun.Tx inside a transaction and the *bun.DB outside of one; both
+// implement bun.IDB, so repository code can accept either.
+//
+// bun's own DB.RunInTx also runs a function in a transaction, but only for
+// bun. BunTx lets bun take part in a UoW like any other store, so the same
+// service code, retries, hooks, logging and tracing apply whether it talks to
+// bun, MongoDB or another runner, and bun can be combined with other runners
+// in a MultiRunner.
+var _ Runner = &BunTx{}
+
+// BunTx struct holds the bun handle and the transaction options.
+type BunTx struct {
+	db        *bun.DB
+	txOptions *sql.TxOptions
+}
+
+// BunOption configures optional behavior of a BunTx. Options are passed to
+// NewBunTx.
+type BunOption func(*BunTx)
+
+// WithBunTxOptions sets the options every transaction is started with, such
+// as the isolation level and read-only mode.
+func WithBunTxOptions(opts *sql.TxOptions) BunOption {
+	return func(b *BunTx) {
+		b.txOptions = opts
+	}
+}
+
+// NewBunTx creates a new BunTx instance. It takes a bun handle and optional
+// settings as arguments.
+func NewBunTx(db *bun.DB, opts ...BunOption) *BunTx {
+	b := &BunTx{
+		db: db,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Ctx starts a new transaction with BeginTx and stores it in the returned
+// context. When ctx already carries a transaction, a nested unit of work joins
+// it through a bun savepoint, which Rollback rolls back to and Commit
+// releases.
+func (b *BunTx) Ctx(ctx context.Context) (context.Context, error) {
+	if outer, ok := ctx.Value(bunTxKey).(bun.Tx); ok {
+		tx, err := outer.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("error in creating savepoint: %w", err)
+		}
+		return context.WithValue(ctx, bunTxKey, tx), nil
+	}
+	tx, err := b.db.BeginTx(ctx, readOnlyTxOptions(ctx, b.txOptions))
+	if err != nil {
+		return nil, fmt.Errorf("error in starting transaction: %w", err)
+	}
+	return context.WithValue(ctx, bunTxKey, tx), nil
+}
+
+// Get retrieves the bun handle. If a transaction exists in the context, it
+// returns the bun.Tx. Otherwise, it returns the *bun.DB.
+func (b *BunTx) Get(ctx context.Context) any {
+	if tx, ok := ctx.Value(bunTxKey).(bun.Tx); ok {
+		return tx
+	}
+	return b.db
+}
+
+// Rollback aborts the current transaction.
+func (b *BunTx) Rollback(ctx context.Context) error {
+	if tx, ok := ctx.Value(bunTxKey).(bun.Tx); ok {
+		return tx.Rollback()
+	}
+	return nil
+}
+
+// Commit commits the current transaction.
+func (b *BunTx) Commit(ctx context.Context) error {
+	if tx, ok := ctx.Value(bunTxKey).(bun.Tx); ok {
+		return tx.Commit()
+	}
+	return nil
+}
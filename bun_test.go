@@ -0,0 +1,115 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/sqlitedialect"
+)
+
+// bunUser is the model used by the bun tests.
+type bunUser struct {
+	bun.BaseModel `bun:"table:users"`
+
+	ID   int64 `bun:",pk,autoincrement"`
+	Name string
+}
+
+// openBun opens an in-memory SQLite database through bun with the bunUser
+// table created.
+func openBun(t *testing.T) *bun.DB {
+	t.Helper()
+	sqlDB, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	db := bun.NewDB(sqlDB, sqlitedialect.New())
+	t.Cleanup(func() { _ = db.Close() })
+
+	if _, err := db.NewCreateTable().Model((*bunUser)(nil)).Exec(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// TestBunTx verifies the commit and rollback round trips through bun.
+func TestBunTx(t *testing.T) {
+	db := openBun(t)
+	txs := New(NewBunTx(db))
+
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		tx := txs.Get(ctx).(bun.IDB)
+		_, err := tx.NewInsert().Model(&bunUser{Name: "alice"}).Exec(ctx)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fnErr := errors.New("fn failed")
+	err = txs.Run(context.Background(), func(ctx context.Context) error {
+		tx := txs.Get(ctx).(bun.Tx)
+		if _, err := tx.NewInsert().Model(&bunUser{Name: "bob"}).Exec(ctx); err != nil {
+			return err
+		}
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) {
+		t.Fatalf("expected fn error, got %v", err)
+	}
+
+	var users []bunUser
+	if err := db.NewSelect().Model(&users).Order("id").Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].Name != "alice" {
+		t.Errorf("expected only the committed user, got %+v", users)
+	}
+	if _, ok := txs.Get(context.Background()).(*bun.DB); !ok {
+		t.Error("expected the *bun.DB outside a transaction")
+	}
+}
+
+// TestBunTx_NestedRun verifies that a nested unit of work joins the outer
+// transaction through a savepoint: an inner failure keeps the outer work.
+func TestBunTx_NestedRun(t *testing.T) {
+	db := openBun(t)
+	txs := New(NewBunTx(db))
+	insert := func(ctx context.Context, name string) error {
+		_, err := txs.Get(ctx).(bun.Tx).NewInsert().Model(&bunUser{Name: name}).Exec(ctx)
+		return err
+	}
+
+	innerErr := errors.New("inner failed")
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		if err := insert(ctx, "outer"); err != nil {
+			return err
+		}
+		err := txs.Run(ctx, func(ctx context.Context) error {
+			if err := insert(ctx, "inner"); err != nil {
+				return err
+			}
+			return innerErr
+		})
+		if !errors.Is(err, innerErr) {
+			t.Errorf("expected inner error, got %v", err)
+		}
+		return txs.Run(ctx, func(ctx context.Context) error { return insert(ctx, "kept") })
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var users []bunUser
+	if err := db.NewSelect().Model(&users).Order("id").Scan(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0].Name != "outer" || users[1].Name != "kept" {
+		t.Errorf("expected the outer and kept users, got %+v", users)
+	}
+}
@@ -0,0 +1,75 @@
+package uow
+
+import "context"
+
+// OnCommit registers callback to run once the transaction that ctx belongs to
+// has been committed, outside of it. Unlike WithAfterCommit it can be called
+// from deep inside fn, e.g. by a repository dispatching domain events.
+// Callbacks run in registration order and are dropped if the transaction
+// rolls back. Inside a nested Run they are deferred to the commit of the
+// outermost unit of work. OnCommit reports false, and does nothing, when ctx
+// does not belong to a unit of work.
+func OnCommit(ctx context.Context, callback func()) bool {
+	rs := runStateFrom(ctx)
+	if rs == nil {
+		return false
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.onCommit = append(rs.onCommit, callback)
+	return true
+}
+
+// OnRollback registers callback to run once the transaction that ctx belongs
+// to has been rolled back, e.g. to release a reservation made in an external
+// system. Callbacks run in registration order and are dropped if the
+// transaction commits. Inside a nested Run that succeeds they move to the
+// enclosing unit of work, so that they run if it rolls back. OnRollback
+// reports false, and does nothing, when ctx does not belong to a unit of work.
+func OnRollback(ctx context.Context, callback func()) bool {
+	rs := runStateFrom(ctx)
+	if rs == nil {
+		return false
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.onRollback = append(rs.onRollback, callback)
+	return true
+}
+
+// committed runs or hands over the callbacks of a successful attempt and
+// returns its buffered events for publishing. A nested attempt passes both
+// kinds of callbacks and its events to the enclosing run, which decides the
+// final outcome, and returns no events.
+func (rs *runState) committed() []any {
+	rs.mu.Lock()
+	onCommit, onRollback, events := rs.onCommit, rs.onRollback, rs.events
+	rs.onCommit, rs.onRollback, rs.events = nil, nil, nil
+	rs.mu.Unlock()
+
+	if rs.parent != nil {
+		rs.parent.mu.Lock()
+		rs.parent.onCommit = append(rs.parent.onCommit, onCommit...)
+		rs.parent.onRollback = append(rs.parent.onRollback, onRollback...)
+		rs.parent.events = append(rs.parent.events, events...)
+		rs.parent.mu.Unlock()
+		return nil
+	}
+	for _, callback := range onCommit {
+		callback()
+	}
+	return events
+}
+
+// rolledBack runs the rollback callbacks of a failed attempt and drops its
+// commit callbacks and events.
+func (rs *runState) rolledBack() {
+	rs.mu.Lock()
+	onRollback := rs.onRollback
+	rs.onCommit, rs.onRollback, rs.events = nil, nil, nil
+	rs.mu.Unlock()
+
+	for _, callback := range onRollback {
+		callback()
+	}
+}
@@ -0,0 +1,73 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestOnCommit verifies that commit callbacks registered inside fn run in
+// order after a commit and are dropped on rollback, and vice versa.
+func TestOnCommit(t *testing.T) {
+	u := New(NewMockTx())
+
+	var calls []string
+	register := func(ctx context.Context) {
+		OnCommit(ctx, func() { calls = append(calls, "commit 1") })
+		OnCommit(ctx, func() { calls = append(calls, "commit 2") })
+		OnRollback(ctx, func() { calls = append(calls, "rollback") })
+	}
+
+	if err := u.Run(context.Background(), func(ctx context.Context) error {
+		register(ctx)
+		if len(calls) != 0 {
+			t.Error("expected callbacks not to run before the commit")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"commit 1", "commit 2"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("expected %v after commit, got %v", want, calls)
+	}
+
+	calls = nil
+	_ = u.Run(context.Background(), func(ctx context.Context) error {
+		register(ctx)
+		return errors.New("fn failed")
+	})
+	if want := []string{"rollback"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("expected %v after rollback, got %v", want, calls)
+	}
+
+	if OnCommit(context.Background(), func() {}) {
+		t.Error("expected OnCommit to report false outside a unit of work")
+	}
+}
+
+// TestOnCommit_Nested verifies that callbacks of a successful nested run are
+// deferred to the outcome of the enclosing run.
+func TestOnCommit_Nested(t *testing.T) {
+	u := New(NewMockTx())
+
+	var calls []string
+	outerErr := errors.New("outer failed")
+	_ = u.Run(context.Background(), func(ctx context.Context) error {
+		err := u.Run(ctx, func(ctx context.Context) error {
+			OnCommit(ctx, func() { calls = append(calls, "inner commit") })
+			OnRollback(ctx, func() { calls = append(calls, "inner rollback") })
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if len(calls) != 0 {
+			t.Errorf("expected no callbacks before the outer run ends, got %v", calls)
+		}
+		return outerErr
+	})
+	if want := []string{"inner rollback"}; !reflect.DeepEqual(calls, want) {
+		t.Errorf("expected %v, got %v", want, calls)
+	}
+}
@@ -0,0 +1,81 @@
+package uow
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LeakError is returned by Run when resources registered with
+// RegisterResource were not released by the time the unit of work finished.
+type LeakError struct {
+	// Resources lists the names of the unreleased resources, sorted.
+	Resources []string
+}
+
+// Error implements the error interface.
+func (e *LeakError) Error() string {
+	return fmt.Sprintf("unreleased resources in unit of work: %s", strings.Join(e.Resources, ", "))
+}
+
+// checklist tracks the resources registered during a single attempt.
+type checklist struct {
+	mu      sync.Mutex
+	pending map[int]string
+	next    int
+}
+
+// WithCommitChecklist makes Run verify, before finishing the transaction,
+// that every resource registered with RegisterResource has been released.
+// Unreleased resources cause the transaction to roll back, and Run returns a
+// *LeakError naming them, joined with any error from fn.
+func WithCommitChecklist() Option {
+	return func(c *config) {
+		c.commitChecklist = true
+	}
+}
+
+// RegisterResource registers a resource that must be released before the unit
+// of work that ctx belongs to finishes, such as a handle opened on another
+// participant of the transaction. It returns the function releasing it;
+// calling the function more than once is harmless. Outside a unit of work the
+// registration is ignored.
+func RegisterResource(ctx context.Context, name string) (release func()) {
+	rs := runStateFrom(ctx)
+	if rs == nil {
+		return func() {}
+	}
+
+	cl := &rs.checklist
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if cl.pending == nil {
+		cl.pending = make(map[int]string)
+	}
+	id := cl.next
+	cl.next++
+	cl.pending[id] = name
+
+	return func() {
+		cl.mu.Lock()
+		defer cl.mu.Unlock()
+		delete(cl.pending, id)
+	}
+}
+
+// verify returns a *LeakError listing the unreleased resources, or nil.
+func (cl *checklist) verify() error {
+	cl.mu.Lock()
+	defer cl.mu.Unlock()
+	if len(cl.pending) == 0 {
+		return nil
+	}
+	names := make([]string, 0, len(cl.pending))
+	for _, name := range cl.pending {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return &LeakError{Resources: names}
+}
@@ -0,0 +1,69 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestWithCommitChecklist_Leak verifies that a resource that is not released
+// is reported and rolls the transaction back.
+func TestWithCommitChecklist_Leak(t *testing.T) {
+	mt := NewMockTx()
+	u := New(mt, WithCommitChecklist())
+
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		releaseCursor := RegisterResource(ctx, "cursor")
+		_ = RegisterResource(ctx, "lock")
+		releaseCursor()
+		return nil
+	})
+
+	var leak *LeakError
+	if !errors.As(err, &leak) {
+		t.Fatalf("expected *LeakError, got %v", err)
+	}
+	if !reflect.DeepEqual(leak.Resources, []string{"lock"}) {
+		t.Errorf("expected leaked resources [lock], got %v", leak.Resources)
+	}
+	if got := mt.state.Value(); got != " rolled back!" {
+		t.Errorf("expected rollback, got state %q", got)
+	}
+}
+
+// TestWithCommitChecklist_AllReleased verifies that the transaction commits
+// when every resource is released.
+func TestWithCommitChecklist_AllReleased(t *testing.T) {
+	mt := NewMockTx()
+	u := New(mt, WithCommitChecklist())
+
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		release := RegisterResource(ctx, "cursor")
+		defer release()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mt.state.Value(); got != " committed!" {
+		t.Errorf("expected commit, got state %q", got)
+	}
+}
+
+// TestWithCommitChecklist_JoinsFnError verifies that leaks are reported
+// together with the error returned by fn.
+func TestWithCommitChecklist_JoinsFnError(t *testing.T) {
+	fnErr := errors.New("fn failed")
+	u := New(NewMockTx(), WithCommitChecklist())
+
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		RegisterResource(ctx, "cursor")
+		return fnErr
+	})
+
+	var leak *LeakError
+	if !errors.Is(err, fnErr) || !errors.As(err, &leak) {
+		t.Errorf("expected fn error and *LeakError, got %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNoCheckpoint is returned by RollbackToCheckpoint when no checkpoint has
+// been taken in the unit of work.
+var ErrNoCheckpoint = errors.New("no checkpoint in unit of work")
+
+// Checkpoint marks a point in a long unit of work, such as an import, that
+// RollbackToCheckpoint can return to without ending the transaction. On
+// runners implementing Savepointer, such as SQLTx, it creates a rolling
+// savepoint: the savepoint of the previous checkpoint is released, so the
+// work done so far is kept and only the latest checkpoint can be returned to.
+// On other runners, such as MongoTx, whose transactions have no savepoints, and
+// on decorators such as TimeoutRunner wrapping them, it is a no-op and returns
+// nil. It returns ErrNoTransaction when ctx does not belong to a unit of work.
+func Checkpoint(ctx context.Context) error {
+	rs := runStateFrom(ctx)
+	if rs == nil {
+		return ErrNoTransaction
+	}
+	sp, ok := rs.runner.(Savepointer)
+	if !ok || !supports[Savepointer](rs.runner) {
+		return nil
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	name := rs.checkpointName()
+	if rs.checkpoint {
+		if err := sp.ReleaseSavepoint(ctx, name); err != nil {
+			return fmt.Errorf("failed to release checkpoint: %w", err)
+		}
+		rs.checkpoint = false
+	}
+	if err := sp.Savepoint(ctx, name); err != nil {
+		return fmt.Errorf("failed to create checkpoint: %w", err)
+	}
+	rs.checkpoint = true
+	return nil
+}
+
+// RollbackToCheckpoint undoes the changes made since the last call to
+// Checkpoint and keeps the transaction open, so that fn can recover from a
+// failure near the end of a long unit of work and still commit the work done
+// before the checkpoint. The checkpoint remains in place. It returns
+// ErrNoCheckpoint when no checkpoint has been taken, ErrSavepointsUnsupported
+// when the runner does not implement Savepointer, and ErrNoTransaction when
+// ctx does not belong to a unit of work.
+func RollbackToCheckpoint(ctx context.Context) error {
+	rs := runStateFrom(ctx)
+	if rs == nil {
+		return ErrNoTransaction
+	}
+	sp, ok := rs.runner.(Savepointer)
+	if !ok || !supports[Savepointer](rs.runner) {
+		return ErrSavepointsUnsupported
+	}
+
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if !rs.checkpoint {
+		return ErrNoCheckpoint
+	}
+	if err := sp.RollbackToSavepoint(ctx, rs.checkpointName()); err != nil {
+		return fmt.Errorf("failed to roll back to checkpoint: %w", err)
+	}
+	return nil
+}
+
+// checkpointName returns the name of the savepoint of the attempt's
+// checkpoint, numbered by nesting level so that the checkpoints of nested
+// units of work do not replace those of enclosing ones.
+func (rs *runState) checkpointName() string {
+	depth := 0
+	for p := rs.parent; p != nil; p = p.parent {
+		depth++
+	}
+	return fmt.Sprintf("uow_checkpoint_%d", depth)
+}
@@ -0,0 +1,72 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+)
+
+// TestCheckpoint verifies that RollbackToCheckpoint undoes only the work done
+// since the last checkpoint and that the transaction still commits.
+func TestCheckpoint(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec("CREATE TABLE items (id INTEGER PRIMARY KEY)"); err != nil {
+		t.Fatal(err)
+	}
+
+	txs := New(NewSQLTx(db))
+	err = txs.Run(context.Background(), func(ctx context.Context) error {
+		if err := RollbackToCheckpoint(ctx); !errors.Is(err, ErrNoCheckpoint) {
+			t.Errorf("expected ErrNoCheckpoint, got %v", err)
+		}
+		tx := txs.Get(ctx).(*sql.Tx)
+		for id := 1; id <= 4; id++ {
+			if _, err := tx.ExecContext(ctx, "INSERT INTO items (id) VALUES (?)", id); err != nil {
+				return err
+			}
+			if id%2 == 0 {
+				if err := Checkpoint(ctx); err != nil {
+					return err
+				}
+			}
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT INTO items (id) VALUES (5)"); err != nil {
+			return err
+		}
+		// Only the insert after the last checkpoint is undone.
+		return RollbackToCheckpoint(ctx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := countRows(t, db, "items"); got != 4 {
+		t.Errorf("expected 4 rows, got %d", got)
+	}
+}
+
+// TestCheckpoint_Unsupported verifies that Checkpoint is a no-op on runners
+// without savepoints.
+func TestCheckpoint_Unsupported(t *testing.T) {
+	txs := New(NewMockTx())
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		if err := Checkpoint(ctx); err != nil {
+			t.Errorf("expected Checkpoint to be a no-op, got %v", err)
+		}
+		if err := RollbackToCheckpoint(ctx); !errors.Is(err, ErrSavepointsUnsupported) {
+			t.Errorf("expected ErrSavepointsUnsupported, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Checkpoint(context.Background()); !errors.Is(err, ErrNoTransaction) {
+		t.Errorf("expected ErrNoTransaction outside a unit of work, got %v", err)
+	}
+}
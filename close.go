@@ -0,0 +1,20 @@
+package uow
+
+import "io"
+
+// Close releases the resources held by the runner, such as pooled sessions,
+// if the runner implements io.Closer, and returns its error. Runners without
+// long-lived resources do not implement it, in which case Close does nothing.
+// Call it during graceful shutdown, once no unit of work is running anymore;
+// u must not be used afterwards.
+func (u *UoW) Close() error {
+	return closeRunner(u.runner)
+}
+
+// closeRunner closes r if it implements io.Closer.
+func closeRunner(r Runner) error {
+	if c, ok := r.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
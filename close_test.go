@@ -0,0 +1,41 @@
+package uow
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// closingRunner is an orderRunner that also implements io.Closer.
+type closingRunner struct {
+	*orderRunner
+	closeErr error
+}
+
+func (r *closingRunner) Close() error {
+	*r.order = append(*r.order, r.name+" close")
+	return r.closeErr
+}
+
+// TestUoW_Close verifies that Close delegates to runners implementing
+// io.Closer, through a MultiRunner in reverse order, and ignores the others.
+func TestUoW_Close(t *testing.T) {
+	u := New(NewMockTx())
+	if err := u.Close(); err != nil {
+		t.Errorf("expected no error for a runner without Close, got %v", err)
+	}
+
+	var order []string
+	closeErr := errors.New("close failed")
+	first := &closingRunner{orderRunner: &orderRunner{name: "first", order: &order}}
+	third := &closingRunner{orderRunner: &orderRunner{name: "third", order: &order}, closeErr: closeErr}
+	u = New(NewMultiRunner(first, NewMockTx(), third))
+
+	err := u.Close()
+	if !errors.Is(err, closeErr) {
+		t.Errorf("expected close error, got %v", err)
+	}
+	if want := []string{"third close", "first close"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("expected %v, got %v", want, order)
+	}
+}
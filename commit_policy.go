@@ -0,0 +1,76 @@
+package uow
+
+import "context"
+
+// CommitPolicy decides how Run chooses between commit and rollback once fn has
+// returned.
+type CommitPolicy int
+
+const (
+	// CommitOnSuccess commits when fn returns nil and rolls back when it
+	// returns an error. It is the default policy.
+	CommitOnSuccess CommitPolicy = iota
+
+	// CommitExplicit commits only when fn called MarkCommit, after any call
+	// to MarkRollback, and returned nil. A unit of work that returns nil
+	// without being marked for commit is rolled back and Run returns nil, as
+	// if fn had returned ErrAbort. An error from fn always rolls back.
+	CommitExplicit
+)
+
+// decision is the outcome requested with MarkCommit or MarkRollback.
+type decision int
+
+const (
+	undecided decision = iota
+	decidedCommit
+	decidedRollback
+)
+
+// WithCommitPolicy sets the policy deciding between commit and rollback, e.g.
+// CommitExplicit for workflows that decide at the end of fn based on
+// accumulated state rather than on the error value.
+func WithCommitPolicy(policy CommitPolicy) Option {
+	return func(c *config) {
+		c.commitPolicy = policy
+	}
+}
+
+// MarkCommit requests that the unit of work ctx belongs to commits, under the
+// CommitExplicit policy. The last call to MarkCommit or MarkRollback wins.
+// Under the default policy it has no effect. It reports false, and does
+// nothing, when ctx does not belong to a unit of work.
+func MarkCommit(ctx context.Context) bool {
+	return mark(ctx, decidedCommit)
+}
+
+// MarkRollback requests that the unit of work ctx belongs to rolls back,
+// under the CommitExplicit policy. The last call to MarkCommit or
+// MarkRollback wins. Under the default policy it has no effect. It reports
+// false, and does nothing, when ctx does not belong to a unit of work.
+func MarkRollback(ctx context.Context) bool {
+	return mark(ctx, decidedRollback)
+}
+
+// mark records d as the decision of the unit of work ctx belongs to.
+func mark(ctx context.Context, d decision) bool {
+	rs := runStateFrom(ctx)
+	if rs == nil {
+		return false
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.decision = d
+	return true
+}
+
+// markedForCommit reports whether the attempt may commit under the configured
+// commit policy.
+func (u *UoW) markedForCommit(rs *runState) bool {
+	if u.config.commitPolicy != CommitExplicit {
+		return true
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return rs.decision == decidedCommit
+}
@@ -0,0 +1,91 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestCommitExplicit verifies that under the explicit policy only a unit of
+// work marked for commit commits, and that the last mark wins.
+func TestCommitExplicit(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func(ctx context.Context) error
+		want StateStatus
+	}{
+		{
+			name: "marked for commit",
+			fn: func(ctx context.Context) error {
+				MarkCommit(ctx)
+				return nil
+			},
+			want: StateCommitted,
+		},
+		{
+			name: "marked for rollback",
+			fn: func(ctx context.Context) error {
+				MarkCommit(ctx)
+				MarkRollback(ctx)
+				return nil
+			},
+			want: StateRolledBack,
+		},
+		{
+			name: "unmarked",
+			fn:   func(_ context.Context) error { return nil },
+			want: StateRolledBack,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt := NewMockTx()
+			u := New(mt, WithCommitPolicy(CommitExplicit))
+			if err := u.Run(context.Background(), tt.fn); err != nil {
+				t.Fatal(err)
+			}
+			if got := mt.State().Status(); got != tt.want {
+				t.Errorf("expected status %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestCommitExplicit_Error verifies that an error from fn rolls back even when
+// the unit of work was marked for commit.
+func TestCommitExplicit_Error(t *testing.T) {
+	mt := NewMockTx()
+	u := New(mt, WithCommitPolicy(CommitExplicit))
+
+	fnErr := errors.New("fn failed")
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		MarkCommit(ctx)
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) {
+		t.Errorf("expected fn error, got %v", err)
+	}
+	if got := mt.State().Status(); got != StateRolledBack {
+		t.Errorf("expected a rollback, got status %v", got)
+	}
+}
+
+// TestCommitOnSuccess verifies that marks are ignored under the default
+// policy.
+func TestCommitOnSuccess(t *testing.T) {
+	mt := NewMockTx()
+	u := New(mt)
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		MarkRollback(ctx)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mt.State().Status(); got != StateCommitted {
+		t.Errorf("expected a commit, got status %v", got)
+	}
+	if MarkCommit(context.Background()) {
+		t.Error("expected MarkCommit to report false outside a unit of work")
+	}
+}
@@ -0,0 +1,58 @@
+package uow
+
+import (
+	"context"
+	"fmt"
+)
+
+// conflictSavepoint is the name of the savepoint created before fn when a
+// conflict handler is configured.
+const conflictSavepoint = "uow_conflict"
+
+// conflictHandler holds the settings configured with WithConflictHandler.
+type conflictHandler struct {
+	classifier func(err error) bool
+	handler    func(ctx context.Context) error
+}
+
+// WithConflictHandler enables upsert-style recovery inside a single
+// transaction. A savepoint is created before fn runs; when fn fails with an
+// error accepted by classifier, typically a unique-constraint violation, the
+// transaction rolls back to the savepoint and handler runs instead, e.g. to
+// update the existing row. The outcome of handler decides whether the
+// transaction commits. The runner must implement Savepointer; otherwise Run
+// fails with ErrSavepointsUnsupported.
+func WithConflictHandler(classifier func(err error) bool, handler func(ctx context.Context) error) Option {
+	return func(c *config) {
+		c.conflict = &conflictHandler{
+			classifier: classifier,
+			handler:    handler,
+		}
+	}
+}
+
+// callFn runs fn in the transactional context, applying the conflict handler
+// when one is configured.
+func (u *UoW) callFn(ctx context.Context, fn func(ctx context.Context) error) error {
+	if u.config.conflict == nil {
+		return fn(ctx)
+	}
+
+	sp, ok := u.runner.(Savepointer)
+	if !ok || !supports[Savepointer](u.runner) {
+		return ErrSavepointsUnsupported
+	}
+	if err := sp.Savepoint(ctx, conflictSavepoint); err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	err := fn(ctx)
+	if err == nil || !u.config.conflict.classifier(err) {
+		return err
+	}
+
+	if spErr := sp.RollbackToSavepoint(ctx, conflictSavepoint); spErr != nil {
+		return fmt.Errorf("failed to roll back to savepoint after conflict (%w): %w", err, spErr)
+	}
+	return u.config.conflict.handler(ctx)
+}
@@ -0,0 +1,116 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/mattn/go-sqlite3"
+)
+
+// isUniqueViolation classifies SQLite unique and primary key violations.
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	if !errors.As(err, &sqliteErr) {
+		return false
+	}
+	return sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique ||
+		sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey
+}
+
+// TestWithConflictHandler verifies that an insert conflict rolls back to the
+// savepoint, runs the update handler and commits.
+func TestWithConflictHandler(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec("CREATE TABLE counters (name TEXT PRIMARY KEY, hits INTEGER)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec("INSERT INTO counters (name, hits) VALUES ('home', 1)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sqlTx := NewSQLTx(db)
+	handled := false
+	txs := New(sqlTx, WithConflictHandler(isUniqueViolation, func(ctx context.Context) error {
+		handled = true
+		tx := sqlTx.Get(ctx).(*sql.Tx)
+		_, err := tx.ExecContext(ctx, "UPDATE counters SET hits = hits + 1 WHERE name = 'home'")
+		return err
+	}))
+
+	err = txs.Run(context.Background(), func(ctx context.Context) error {
+		tx := txs.Get(ctx).(*sql.Tx)
+		_, err := tx.ExecContext(ctx, "INSERT INTO counters (name, hits) VALUES ('about', 1)")
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "INSERT INTO counters (name, hits) VALUES ('home', 1)")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !handled {
+		t.Error("expected conflict handler to run")
+	}
+
+	var hits int
+	if err := db.QueryRow("SELECT hits FROM counters WHERE name = 'home'").Scan(&hits); err != nil {
+		t.Fatal(err)
+	}
+	if hits != 2 {
+		t.Errorf("expected hits to be 2 after update, got %d", hits)
+	}
+	// The insert of 'about' happened after the savepoint and is undone.
+	if got := countRows(t, db, "counters"); got != 1 {
+		t.Errorf("expected 1 row, got %d", got)
+	}
+}
+
+// TestWithConflictHandler_OtherError verifies that errors not accepted by the
+// classifier roll back without running the handler.
+func TestWithConflictHandler_OtherError(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	fnErr := errors.New("fn failed")
+	txs := New(NewSQLTx(db), WithConflictHandler(isUniqueViolation, func(_ context.Context) error {
+		t.Error("expected handler not to run")
+		return nil
+	}))
+	err = txs.Run(context.Background(), func(_ context.Context) error {
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) {
+		t.Errorf("expected fn error, got %v", err)
+	}
+}
+
+// TestWithConflictHandler_Unsupported verifies that runners without savepoint
+// support are rejected.
+func TestWithConflictHandler_Unsupported(t *testing.T) {
+	mt := NewMockTx()
+	txs := New(mt, WithConflictHandler(isUniqueViolation, func(_ context.Context) error { return nil }))
+	err := txs.Run(context.Background(), func(_ context.Context) error {
+		t.Error("expected fn not to run")
+		return nil
+	})
+	if !errors.Is(err, ErrSavepointsUnsupported) {
+		t.Errorf("expected ErrSavepointsUnsupported, got %v", err)
+	}
+	if got := mt.state.Value(); got != " rolled back!" {
+		t.Errorf("expected rollback, got state %q", got)
+	}
+}
@@ -0,0 +1,96 @@
+package uow
+
+import (
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ConnectionLostError wraps an error that was classified as a lost connection
+// to the backend. Callers can detect it with errors.As to react specifically,
+// e.g. by tripping a circuit breaker. The wrapped error remains reachable via
+// errors.Is and errors.As.
+type ConnectionLostError struct {
+	Err error
+}
+
+// Error implements the error interface.
+func (e *ConnectionLostError) Error() string {
+	return "connection lost: " + e.Err.Error()
+}
+
+// Unwrap returns the original error.
+func (e *ConnectionLostError) Unwrap() error {
+	return e.Err
+}
+
+// ConnLostClassifier reports whether err indicates that the connection to the
+// backend was lost.
+type ConnLostClassifier func(err error) bool
+
+// IsSQLConnLost reports whether err carries driver.ErrBadConn, which
+// database/sql drivers return when a connection is no longer usable.
+func IsSQLConnLost(err error) bool {
+	return errors.Is(err, driver.ErrBadConn)
+}
+
+// IsMongoConnLost reports whether err carries the MongoDB "NetworkError"
+// label.
+func IsMongoConnLost(err error) bool {
+	return mongo.IsNetworkError(err)
+}
+
+// IsNetConnLost reports whether err is caused by a broken network connection,
+// such as a reset or unexpectedly closed socket. Drivers that talk to the
+// network directly, like pgconn, surface disconnects this way.
+func IsNetConnLost(err error) bool {
+	if errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNABORTED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+	var opErr *net.OpError
+	return errors.As(err, &opErr) && !opErr.Timeout()
+}
+
+// defaultConnLostClassifiers are used by WithConnLostDetection when no
+// classifier is given.
+var defaultConnLostClassifiers = []ConnLostClassifier{
+	IsSQLConnLost,
+	IsMongoConnLost,
+	IsNetConnLost,
+}
+
+// WithConnLostDetection makes Run wrap errors that indicate a lost connection
+// in a *ConnectionLostError. The given classifiers decide which errors qualify;
+// without classifiers IsSQLConnLost, IsMongoConnLost and IsNetConnLost are
+// used. Classifiers accumulate over several uses of the option, and an error
+// qualifies when any of them matches it.
+func WithConnLostDetection(classifiers ...ConnLostClassifier) Option {
+	return func(c *config) {
+		if len(classifiers) == 0 {
+			classifiers = defaultConnLostClassifiers
+		}
+		c.connLost = append(c.connLost, classifiers...)
+	}
+}
+
+// classifyConnLost wraps err in a *ConnectionLostError when one of the
+// configured classifiers matches it.
+func (u *UoW) classifyConnLost(err error) error {
+	if err == nil {
+		return nil
+	}
+	for _, isLost := range u.config.connLost {
+		if isLost(err) {
+			return &ConnectionLostError{Err: err}
+		}
+	}
+	return err
+}
@@ -0,0 +1,108 @@
+package uow
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// TestWithConnLostDetection verifies that connection-loss errors from the
+// supported backends are wrapped in a *ConnectionLostError while other errors
+// are left untouched.
+func TestWithConnLostDetection(t *testing.T) {
+	tests := []struct {
+		name     string
+		runner   *errorRunner
+		fnErr    error
+		wantLost bool
+	}{
+		{
+			name:     "sql_bad_conn",
+			runner:   &errorRunner{},
+			fnErr:    fmt.Errorf("exec failed: %w", driver.ErrBadConn),
+			wantLost: true,
+		},
+		{
+			name:     "sql_bad_conn_on_begin",
+			runner:   &errorRunner{ctxErr: driver.ErrBadConn},
+			wantLost: true,
+		},
+		{
+			name:     "mongo_network_error",
+			runner:   &errorRunner{},
+			fnErr:    mongo.CommandError{Code: 6, Message: "host unreachable", Labels: []string{"NetworkError"}},
+			wantLost: true,
+		},
+		{
+			name:     "mongo_other_error",
+			runner:   &errorRunner{},
+			fnErr:    mongo.CommandError{Code: 11000, Message: "duplicate key"},
+			wantLost: false,
+		},
+		{
+			name:     "plain_error",
+			runner:   &errorRunner{},
+			fnErr:    errors.New("fn failed"),
+			wantLost: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := New(tt.runner, WithConnLostDetection())
+			err := u.Run(context.Background(), func(_ context.Context) error {
+				return tt.fnErr
+			})
+			if err == nil {
+				t.Fatal("expected error, got nil")
+			}
+
+			var lost *ConnectionLostError
+			if got := errors.As(err, &lost); got != tt.wantLost {
+				t.Errorf("expected errors.As(err, *ConnectionLostError) to be %v, got %v (%v)", tt.wantLost, got, err)
+			}
+			if tt.fnErr != nil && !strings.Contains(err.Error(), tt.fnErr.Error()) {
+				t.Errorf("expected original error to be reported, got %v", err)
+			}
+		})
+	}
+}
+
+// TestWithConnLostDetection_Disabled verifies that errors are not classified
+// unless the option is set.
+func TestWithConnLostDetection_Disabled(t *testing.T) {
+	u := New(&errorRunner{})
+	err := u.Run(context.Background(), func(_ context.Context) error {
+		return driver.ErrBadConn
+	})
+
+	var lost *ConnectionLostError
+	if errors.As(err, &lost) {
+		t.Errorf("expected no classification without WithConnLostDetection, got %v", err)
+	}
+}
+
+// TestWithConnLostDetection_Accumulates verifies that classifiers given in
+// several uses of the option are all applied.
+func TestWithConnLostDetection_Accumulates(t *testing.T) {
+	errA, errB := errors.New("lost a"), errors.New("lost b")
+	u := New(&errorRunner{},
+		WithConnLostDetection(func(err error) bool { return errors.Is(err, errA) }),
+		WithConnLostDetection(func(err error) bool { return errors.Is(err, errB) }),
+	)
+
+	for _, fnErr := range []error{errA, errB} {
+		err := u.Run(context.Background(), func(_ context.Context) error {
+			return fnErr
+		})
+		var lost *ConnectionLostError
+		if !errors.As(err, &lost) {
+			t.Errorf("expected %v to be classified as a lost connection, got %v", fnErr, err)
+		}
+	}
+}
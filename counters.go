@@ -0,0 +1,88 @@
+package uow
+
+import "sync/atomic"
+
+// Counters is a snapshot of the transactions of a UoW, returned by Stats.
+type Counters struct {
+	// Queued is the number of transactions currently waiting to begin, e.g.
+	// for a connection or a SemaphoreRunner permit.
+	Queued int64
+
+	// Active is the number of transactions that have begun and not yet
+	// committed or rolled back. A value that keeps growing points to
+	// transactions that never finish.
+	Active int64
+
+	// Started is the total number of transactions that have begun.
+	Started int64
+
+	// Committed is the total number of transactions that committed.
+	Committed int64
+
+	// RolledBack is the total number of transactions that rolled back or
+	// failed to commit.
+	RolledBack int64
+}
+
+// counters holds the live counts behind Stats. It is shared by all copies of
+// a UoW.
+type counters struct {
+	queued     atomic.Int64
+	active     atomic.Int64
+	started    atomic.Int64
+	committed  atomic.Int64
+	rolledBack atomic.Int64
+}
+
+// Stats returns the number of transactions of u currently queued and active,
+// and the totals started, committed and rolled back since New, for health
+// endpoints and leak detection. Every attempt of a retried run counts as a
+// transaction. The counts are read one by one with atomic loads, so a
+// snapshot taken while transactions finish may be off by the transactions in
+// flight.
+func (u *UoW) Stats() Counters {
+	c := u.counters
+	if c == nil {
+		return Counters{}
+	}
+	return Counters{
+		Queued:     c.queued.Load(),
+		Active:     c.active.Load(),
+		Started:    c.started.Load(),
+		Committed:  c.committed.Load(),
+		RolledBack: c.rolledBack.Load(),
+	}
+}
+
+// beginning counts a transaction waiting to begin.
+func (c *counters) beginning() {
+	if c != nil {
+		c.queued.Add(1)
+	}
+}
+
+// began moves a transaction from queued to active, or drops it from the
+// queue when ok is false because it failed to begin.
+func (c *counters) began(ok bool) {
+	if c == nil {
+		return
+	}
+	c.queued.Add(-1)
+	if ok {
+		c.started.Add(1)
+		c.active.Add(1)
+	}
+}
+
+// finished counts the end of an active transaction.
+func (c *counters) finished(committed bool) {
+	if c == nil {
+		return
+	}
+	c.active.Add(-1)
+	if committed {
+		c.committed.Add(1)
+	} else {
+		c.rolledBack.Add(1)
+	}
+}
@@ -0,0 +1,115 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+)
+
+// TestUoW_Stats verifies the counts of queued, active, committed and rolled
+// back transactions, and that they are shared by copies of the UoW.
+func TestUoW_Stats(t *testing.T) {
+	mt := NewMockTx()
+	txs := New(mt)
+	copied := txs
+
+	var during Counters
+	err := txs.Run(context.Background(), func(_ context.Context) error {
+		during = copied.Stats()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Counters{Active: 1, Started: 1}); during != want {
+		t.Errorf("expected %+v while running, got %+v", want, during)
+	}
+
+	_ = txs.Run(context.Background(), func(_ context.Context) error { return errors.New("fn failed") })
+	_ = txs.Run(context.Background(), func(_ context.Context) error { return ErrAbort })
+	mt.WithCtxError(errors.New("begin failed"))
+	_ = txs.Run(context.Background(), func(_ context.Context) error { return nil })
+
+	if got, want := txs.Stats(), (Counters{Started: 3, Committed: 1, RolledBack: 2}); got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+// TestUoW_Stats_Queued verifies that a transaction waiting to begin is counted
+// as queued rather than active.
+func TestUoW_Stats_Queued(t *testing.T) {
+	runner := NewSemaphoreRunner(NewMockTx(), 1)
+	txs := New(runner)
+
+	hold := make(chan struct{})
+	started := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = txs.Run(context.Background(), func(_ context.Context) error {
+			close(started)
+			<-hold
+			return nil
+		})
+	}()
+	<-started
+
+	ctx, cancel := context.WithCancel(context.Background())
+	waiting := make(chan struct{})
+	go func() {
+		defer close(waiting)
+		_ = txs.Run(ctx, func(_ context.Context) error { return nil })
+	}()
+	for txs.Stats().Queued == 0 {
+		runtime.Gosched()
+	}
+	if got, want := txs.Stats(), (Counters{Queued: 1, Active: 1, Started: 1}); got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+
+	cancel()
+	<-waiting
+	close(hold)
+	<-done
+	if got, want := txs.Stats(), (Counters{Started: 1, Committed: 1}); got != want {
+		t.Errorf("expected %+v, got %+v", want, got)
+	}
+}
+
+// TestUoW_Stats_Panic verifies that a transaction is no longer counted as
+// active when the runner's commit or an after-commit hook panics.
+func TestUoW_Stats_Panic(t *testing.T) {
+	tests := []struct {
+		name   string
+		runner Runner
+		opts   []Option
+		want   Counters
+	}{
+		{name: "commit", runner: &panickingCommitRunner{MockTx: NewMockTx()}, want: Counters{Started: 1, RolledBack: 1}},
+		{name: "after commit", runner: NewMockTx(), opts: []Option{WithAfterCommit(func(_ context.Context) { panic("hook") })}, want: Counters{Started: 1, Committed: 1}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			txs := New(tt.runner, tt.opts...)
+			func() {
+				defer func() { _ = recover() }()
+				_ = txs.Run(context.Background(), func(_ context.Context) error { return nil })
+				t.Error("expected Run to panic")
+			}()
+			if got := txs.Stats(); got != tt.want {
+				t.Errorf("expected %+v, got %+v", tt.want, got)
+			}
+		})
+	}
+}
+
+// panickingCommitRunner is a MockTx whose Commit panics.
+type panickingCommitRunner struct {
+	*MockTx
+}
+
+func (r *panickingCommitRunner) Commit(_ context.Context) error {
+	panic("commit")
+}
@@ -0,0 +1,22 @@
+package uow
+
+import "context"
+
+// DryRun is a per-run option that always rolls the transaction back, even
+// when fn succeeds, so that fn runs against the real data store without
+// persisting anything, e.g. to preview a data migration. Before-commit hooks
+// and pre-commit callbacks still run inside the transaction, while the
+// after-rollback hooks and OnRollback callbacks run instead of the
+// after-commit ones.
+func DryRun() RunOption {
+	return func(rc *runConfig) {
+		rc.dryRun = true
+	}
+}
+
+// RunDryRun runs fn in a transaction that is always rolled back and returns
+// the error of fn unchanged, or nil when it succeeded. It is shorthand for
+// u.Run(ctx, fn, append(opts, DryRun())...).
+func (u *UoW) RunDryRun(ctx context.Context, fn func(ctx context.Context) error, opts ...RunOption) error {
+	return u.Run(ctx, fn, append(opts, DryRun())...)
+}
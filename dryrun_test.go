@@ -0,0 +1,32 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestRunDryRun verifies that a dry run always rolls back and reports the
+// error of fn unchanged.
+func TestRunDryRun(t *testing.T) {
+	for _, fnErr := range []error{nil, errors.New("fn failed")} {
+		mt := NewMockTx()
+		u := New(mt)
+		err := u.RunDryRun(context.Background(), func(ctx context.Context) error {
+			u.Get(ctx).(*State).SetValue("migrated")
+			return fnErr
+		})
+		if err != fnErr {
+			t.Errorf("expected %v, got %v", fnErr, err)
+		}
+		if got := mt.State().Value(); got != "migrated rolled back!" {
+			t.Errorf("expected the dry run to roll back, got %q", got)
+		}
+		if n := mt.CallCount("commit"); n != 0 {
+			t.Errorf("expected no commit, got %d", n)
+		}
+		if d := mt.Depth(); d != 0 {
+			t.Errorf("expected the transaction to be released, got depth %d", d)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package uow
+
+import (
+	"context"
+	"fmt"
+)
+
+// entTxKey is the context key for storing the ent transaction.
+var entTxKey = ctxKey{"ent_tx"}
+
+// entJoinedKey is the context key marking a unit of work that joined the ent
+// transaction of an enclosing one.
+var entJoinedKey = ctxKey{"ent_joined"}
+
+// EntTransaction is the part of a generated ent transaction, *ent.Tx, used by
+// EntTx. C is the generated client type, *ent.Client.
+type EntTransaction[C any] interface {
+	Commit() error
+	Rollback() error
+	Client() C
+}
+
+// EntTx implements the Runner interface for ent (entgo.io/ent). Since ent
+// generates its client and transaction types per schema, EntTx is generic
+// over them and does not depend on ent itself: C is the generated *ent.Client
+// and T the generated *ent.Tx. Get returns the transactional client,
+// tx.Client(), inside a transaction and the base client outside of one, so
+// repository code always works with a C:
+//
+//	runner := uow.NewEntTx(client, client.Tx)
+//	txs := uow.New(runner)
+//	err := txs.Run(ctx, func(ctx context.Context) error {
+//		c := txs.Get(ctx).(*ent.Client)
+//		return c.User.Create().SetName("alice").Exec(ctx)
+//	})
+//
+// Hooks registered on the base client with client.Use, and the schema hooks,
+// also apply to the transactional client, as ent copies them into every
+// transaction. The context passed to a hook belongs to the unit of work, so a
+// hook can call OnCommit to defer side effects, such as publishing events,
+// until the transaction has committed.
+//
+// ent offers no savepoints, so when ctx already carries a transaction, e.g.
+// because a service method running in a unit of work calls another one, the
+// existing transaction is joined instead: Commit and Rollback of the inner
+// unit of work are no-ops and the outermost unit of work decides the outcome.
+var _ Runner = &EntTx[any, EntTransaction[any]]{}
+
+// EntTx struct holds the base client and the function starting transactions.
+type EntTx[C any, T EntTransaction[C]] struct {
+	client C
+	begin  func(ctx context.Context) (T, error)
+}
+
+// NewEntTx creates a new EntTx instance. It takes the base client and the
+// function starting a transaction, usually client.Tx, or a closure calling
+// client.BeginTx with *sql.TxOptions.
+func NewEntTx[C any, T EntTransaction[C]](client C, begin func(ctx context.Context) (T, error)) *EntTx[C, T] {
+	return &EntTx[C, T]{
+		client: client,
+		begin:  begin,
+	}
+}
+
+// Ctx starts a new transaction and stores it in the returned context. When
+// ctx already carries a transaction, it is joined instead.
+func (e *EntTx[C, T]) Ctx(ctx context.Context) (context.Context, error) {
+	if outer, ok := ctx.Value(entTxKey).(T); ok {
+		return context.WithValue(ctx, entJoinedKey, outer), nil
+	}
+	tx, err := e.begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("error in starting transaction: %w", err)
+	}
+	return context.WithValue(ctx, entTxKey, tx), nil
+}
+
+// Get retrieves the ent client. If a transaction exists in the context, it
+// returns its transactional client. Otherwise, it returns the base client.
+func (e *EntTx[C, T]) Get(ctx context.Context) any {
+	if tx, ok := ctx.Value(entTxKey).(T); ok {
+		return tx.Client()
+	}
+	return e.client
+}
+
+// Rollback aborts the current transaction. In a unit of work that joined an
+// enclosing transaction it does nothing.
+func (e *EntTx[C, T]) Rollback(ctx context.Context) error {
+	if tx, ok := e.owned(ctx); ok {
+		return tx.Rollback()
+	}
+	return nil
+}
+
+// Commit commits the current transaction. In a unit of work that joined an
+// enclosing transaction it does nothing.
+func (e *EntTx[C, T]) Commit(ctx context.Context) error {
+	if tx, ok := e.owned(ctx); ok {
+		return tx.Commit()
+	}
+	return nil
+}
+
+// owned returns the transaction in ctx when the unit of work ctx belongs to
+// started it.
+func (e *EntTx[C, T]) owned(ctx context.Context) (T, bool) {
+	tx, ok := ctx.Value(entTxKey).(T)
+	if !ok {
+		return tx, false
+	}
+	if outer, joined := ctx.Value(entJoinedKey).(T); joined && any(outer) == any(tx) {
+		return tx, false
+	}
+	return tx, true
+}
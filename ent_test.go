@@ -0,0 +1,105 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// fakeEntClient stands in for a generated *ent.Client.
+type fakeEntClient struct {
+	tx  *fakeEntTx
+	log *[]string
+}
+
+// Tx starts a fake transaction, like the generated client.Tx.
+func (c *fakeEntClient) Tx(_ context.Context) (*fakeEntTx, error) {
+	*c.log = append(*c.log, "begin")
+	tx := &fakeEntTx{log: c.log}
+	tx.client = &fakeEntClient{tx: tx, log: c.log}
+	return tx, nil
+}
+
+// fakeEntTx stands in for a generated *ent.Tx.
+type fakeEntTx struct {
+	client *fakeEntClient
+	log    *[]string
+}
+
+func (tx *fakeEntTx) Commit() error {
+	*tx.log = append(*tx.log, "commit")
+	return nil
+}
+
+func (tx *fakeEntTx) Rollback() error {
+	*tx.log = append(*tx.log, "rollback")
+	return nil
+}
+
+func (tx *fakeEntTx) Client() *fakeEntClient {
+	return tx.client
+}
+
+// TestEntTx verifies that EntTx hands out the transactional client and
+// forwards commit and rollback to the transaction.
+func TestEntTx(t *testing.T) {
+	var log []string
+	client := &fakeEntClient{log: &log}
+	runner := NewEntTx(client, client.Tx)
+	txs := New(runner)
+
+	if got := txs.Get(context.Background()); got != client {
+		t.Errorf("expected the base client outside a transaction, got %v", got)
+	}
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		c := txs.Get(ctx).(*fakeEntClient)
+		if c == client || c.tx == nil {
+			t.Error("expected the transactional client")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fnErr := errors.New("fn failed")
+	if err := txs.Run(context.Background(), func(context.Context) error { return fnErr }); !errors.Is(err, fnErr) {
+		t.Errorf("expected fn error, got %v", err)
+	}
+	if want := []string{"begin", "commit", "begin", "rollback"}; !reflect.DeepEqual(log, want) {
+		t.Errorf("expected %v, got %v", want, log)
+	}
+}
+
+// TestEntTx_NestedRun verifies that a nested unit of work joins the enclosing
+// transaction and leaves its outcome to the outermost unit of work.
+func TestEntTx_NestedRun(t *testing.T) {
+	var log []string
+	client := &fakeEntClient{log: &log}
+	txs := New(NewEntTx(client, client.Tx))
+
+	innerErr := errors.New("inner failed")
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		outer := txs.Get(ctx)
+		err := txs.Run(ctx, func(ctx context.Context) error {
+			if got := txs.Get(ctx); got != outer {
+				t.Errorf("expected the outer transactional client, got %v", got)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if err := txs.Run(ctx, func(context.Context) error { return innerErr }); !errors.Is(err, innerErr) {
+			t.Errorf("expected inner error, got %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"begin", "commit"}; !reflect.DeepEqual(log, want) {
+		t.Errorf("expected %v, got %v", want, log)
+	}
+}
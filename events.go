@@ -0,0 +1,75 @@
+package uow
+
+import (
+	"context"
+	"fmt"
+)
+
+// EventSink receives the domain events buffered during a unit of work once
+// its transaction has committed, e.g. to publish them to Kafka or NATS.
+type EventSink interface {
+	// Publish delivers events in the order they were appended. It is called
+	// outside the transaction and only when at least one event was appended.
+	Publish(ctx context.Context, events []any) error
+}
+
+// EventSinkFunc adapts a function to the EventSink interface.
+type EventSinkFunc func(ctx context.Context, events []any) error
+
+// Publish calls f(ctx, events).
+func (f EventSinkFunc) Publish(ctx context.Context, events []any) error {
+	return f(ctx, events)
+}
+
+// WithEventSink sets the sink that receives the events appended with
+// AppendEvent once the transaction has committed. The events of a unit of work
+// that rolls back are discarded without reaching the sink. The transaction
+// cannot be undone once committed, so when Publish fails Run returns an error
+// saying that the transaction committed but its events were not published;
+// to deliver events atomically with the transaction, write them to an outbox
+// table with a WriteBuffer instead.
+func WithEventSink(sink EventSink) Option {
+	return func(c *config) {
+		c.eventSink = sink
+	}
+}
+
+// AppendEvent buffers event in the unit of work that ctx belongs to, to be
+// published by the sink set with WithEventSink once its transaction has
+// committed. Inside a nested Run the events move to the enclosing unit of work
+// and are published by its sink when the outermost one commits. It returns
+// ErrNoTransaction when ctx does not belong to a unit of work.
+func AppendEvent(ctx context.Context, event any) error {
+	rs := runStateFrom(ctx)
+	if rs == nil {
+		return ErrNoTransaction
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.events = append(rs.events, event)
+	return nil
+}
+
+// BufferedEvents returns the events appended so far in the unit of work that
+// ctx belongs to, in the order they were appended.
+func BufferedEvents(ctx context.Context) []any {
+	rs := runStateFrom(ctx)
+	if rs == nil {
+		return nil
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return append([]any(nil), rs.events...)
+}
+
+// publishEvents hands the buffered events of a committed attempt to the event
+// sink. ctx is the context outside the transaction.
+func (u *UoW) publishEvents(ctx context.Context, events []any) error {
+	if u.config.eventSink == nil || len(events) == 0 {
+		return nil
+	}
+	if err := u.config.eventSink.Publish(ctx, events); err != nil {
+		return fmt.Errorf("transaction committed but publishing events failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestAppendEvent verifies that buffered events reach the sink after commit,
+// in order and including those of a nested unit of work, and are discarded on
+// rollback.
+func TestAppendEvent(t *testing.T) {
+	mt := NewMockTx()
+	var published [][]any
+	u := New(mt, WithEventSink(EventSinkFunc(func(_ context.Context, events []any) error {
+		if got := mt.State().Status(); got != StateCommitted {
+			t.Errorf("expected events to be published after commit, got status %v", got)
+		}
+		published = append(published, events)
+		return nil
+	})))
+
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		if err := AppendEvent(ctx, "created"); err != nil {
+			return err
+		}
+		return u.Run(ctx, func(ctx context.Context) error {
+			return AppendEvent(ctx, "updated")
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][]any{{"created", "updated"}}
+	if !reflect.DeepEqual(published, want) {
+		t.Errorf("expected published events %v, got %v", want, published)
+	}
+
+	published = nil
+	fnErr := errors.New("fn failed")
+	err = u.Run(context.Background(), func(ctx context.Context) error {
+		_ = AppendEvent(ctx, "lost")
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) {
+		t.Errorf("expected fn error, got %v", err)
+	}
+	if published != nil {
+		t.Errorf("expected no events after a rollback, got %v", published)
+	}
+
+	if err := AppendEvent(context.Background(), "outside"); !errors.Is(err, ErrNoTransaction) {
+		t.Errorf("expected ErrNoTransaction, got %v", err)
+	}
+}
+
+// TestAppendEvent_PublishFails verifies that a failing sink is reported after
+// the transaction has committed.
+func TestAppendEvent_PublishFails(t *testing.T) {
+	mt := NewMockTx()
+	sinkErr := errors.New("broker unavailable")
+	u := New(mt, WithEventSink(EventSinkFunc(func(context.Context, []any) error {
+		return sinkErr
+	})))
+
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		return AppendEvent(ctx, "created")
+	})
+	if !errors.Is(err, sinkErr) {
+		t.Errorf("expected sink error, got %v", err)
+	}
+	if got := mt.State().Status(); got != StateCommitted {
+		t.Errorf("expected the transaction to stay committed, got status %v", got)
+	}
+}
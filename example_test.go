@@ -2,9 +2,13 @@ package uow_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
 	"github.com/agtabesh/uow"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 func ExampleUoW_Run() {
@@ -24,3 +28,48 @@ func ExampleUoW_Run() {
 	}
 	// Output: Transaction successful!
 }
+
+func ExampleNew() {
+	attempts := 0
+	txs := uow.New(uow.NewMockTx(),
+		uow.WithMaxRetries(2),
+		uow.WithRetryIf(func(err error) bool { return err.Error() == "conflict" }),
+		uow.WithBeforeCommit(func(_ context.Context) error {
+			fmt.Println("committing")
+			return nil
+		}),
+	)
+
+	err := txs.Run(context.Background(), func(_ context.Context) error {
+		attempts++
+		if attempts == 1 {
+			return errors.New("conflict")
+		}
+		return nil
+	})
+	fmt.Println(attempts, err)
+	// Output:
+	// committing
+	// 2 <nil>
+}
+
+func ExampleRepository() {
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://localhost:27017"))
+	if err != nil {
+		panic(err)
+	}
+	defer func() { _ = client.Disconnect(context.Background()) }()
+
+	txs := uow.New(uow.NewMongoTx(client, "app"))
+	users := uow.NewRepository[*mongo.Database](&txs, nil)
+
+	// Outside a unit of work the insert commits on its own; inside one, it
+	// joins the running transaction.
+	err = users.WithTx(context.Background(), func(ctx context.Context, db *mongo.Database) error {
+		_, err := db.Collection("users").InsertOne(ctx, bson.M{"name": "alice"})
+		return err
+	})
+	if err != nil {
+		fmt.Printf("Error: %v\n", err)
+	}
+}
@@ -0,0 +1,184 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// firestoreTxKey is the context key for storing the Firestore transaction.
+var firestoreTxKey = ctxKey{"firestore_tx"}
+
+// firestoreJoinedKey is the context key marking a unit of work that joined the
+// Firestore transaction of an enclosing one.
+var firestoreJoinedKey = ctxKey{"firestore_joined"}
+
+// errFirestoreRollback is returned from the RunTransaction callback to make
+// Firestore roll the transaction back.
+var errFirestoreRollback = errors.New("firestore transaction rolled back")
+
+// FirestoreTx implements the Runner interface for Google Cloud Firestore
+// transactions. Firestore only offers transactions through the callback of
+// firestore.Client.RunTransaction, so FirestoreTx bridges that model: Ctx
+// calls RunTransaction on a separate goroutine and returns once the callback
+// has received the transaction, and the callback then waits for Commit or
+// Rollback to tell it how to return. Commit and Rollback wait for
+// RunTransaction to finish and return its outcome.
+//
+// The bridge differs from using RunTransaction directly:
+//
+//   - Firestore retries a contended transaction by calling the callback
+//     again, which the bridge cannot do since fn has already returned. The
+//     transaction is therefore attempted once, and a contention error is
+//     returned by Commit; use WithRetryIf(IsFirestoreAborted) with
+//     WithMaxRetries to run the whole unit of work again.
+//   - As with RunTransaction, all reads must happen before the first write,
+//     and writes are buffered and only applied by Commit.
+//   - Every transaction holds a goroutine until Commit or Rollback is called,
+//     which UoW.Run always does.
+//
+// When ctx already carries a transaction, e.g. because a service method
+// running in a unit of work calls another one, the existing transaction is
+// joined instead: Commit and Rollback of the inner unit of work are no-ops
+// and the outermost unit of work decides the outcome.
+var _ Runner = &FirestoreTx{}
+
+// FirestoreTx struct holds the Firestore client.
+type FirestoreTx struct {
+	client *firestore.Client
+}
+
+// firestoreTx is stored in the context for the duration of a transaction.
+type firestoreTx struct {
+	tx *firestore.Transaction
+
+	// decide receives nil to commit or errFirestoreRollback to roll back.
+	decide chan error
+
+	// done receives the result of RunTransaction.
+	done chan error
+
+	// end makes sure that the decision is sent and the result of
+	// RunTransaction received once; err keeps that result for later calls.
+	end sync.Once
+	err error
+}
+
+// finish sends decision to the RunTransaction callback, once, and returns the
+// result of RunTransaction. Later calls return the same result instead of
+// waiting for a decision that is never picked up.
+func (state *firestoreTx) finish(decision error) error {
+	state.end.Do(func() {
+		state.decide <- decision
+		state.err = <-state.done
+	})
+	return state.err
+}
+
+// NewFirestoreTx creates a new FirestoreTx instance. It takes a Firestore
+// client as an argument.
+func NewFirestoreTx(client *firestore.Client) *FirestoreTx {
+	return &FirestoreTx{
+		client: client,
+	}
+}
+
+// Ctx starts a new Firestore transaction and returns a context carrying it. A
+// read-only unit of work starts a read-only transaction. ctx stays in use by
+// the transaction until Commit or Rollback has returned.
+func (f *FirestoreTx) Ctx(ctx context.Context) (context.Context, error) {
+	if outer, ok := ctx.Value(firestoreTxKey).(*firestoreTx); ok {
+		// Firestore does not support nested transactions: join the enclosing
+		// one.
+		return context.WithValue(ctx, firestoreJoinedKey, outer), nil
+	}
+
+	opts := []firestore.TransactionOption{firestore.MaxAttempts(1)}
+	if IsReadOnly(ctx) {
+		opts = append(opts, firestore.ReadOnly)
+	}
+	state := &firestoreTx{
+		decide: make(chan error, 1),
+		done:   make(chan error, 1),
+	}
+	started := make(chan struct{})
+	go func() {
+		state.done <- f.client.RunTransaction(ctx, func(_ context.Context, tx *firestore.Transaction) error {
+			state.tx = tx
+			close(started)
+			return <-state.decide
+		}, opts...)
+	}()
+
+	select {
+	case <-started:
+		return context.WithValue(ctx, firestoreTxKey, state), nil
+	case err := <-state.done:
+		return nil, fmt.Errorf("error in starting transaction: %w", err)
+	}
+}
+
+// Get retrieves the Firestore transaction. If a transaction exists in the
+// context, it returns the *firestore.Transaction. Otherwise, it returns the
+// *firestore.Client.
+func (f *FirestoreTx) Get(ctx context.Context) any {
+	if state, ok := ctx.Value(firestoreTxKey).(*firestoreTx); ok {
+		return state.tx
+	}
+	return f.client
+}
+
+// Rollback rolls back the current transaction and waits for Firestore to
+// finish it. In a unit of work that joined an enclosing transaction it does
+// nothing. Once the transaction has ended, it returns the outcome of the
+// first Commit or Rollback again.
+func (f *FirestoreTx) Rollback(ctx context.Context) error {
+	state, ok := f.owned(ctx)
+	if !ok {
+		return nil
+	}
+	if err := state.finish(errFirestoreRollback); err != nil && !errors.Is(err, errFirestoreRollback) {
+		return err
+	}
+	return nil
+}
+
+// Commit commits the current transaction, applying its buffered writes, and
+// waits for Firestore to finish it. In a unit of work that joined an
+// enclosing transaction it does nothing. Once the transaction has ended, it
+// returns the outcome of the first Commit or Rollback again.
+func (f *FirestoreTx) Commit(ctx context.Context) error {
+	state, ok := f.owned(ctx)
+	if !ok {
+		return nil
+	}
+	return state.finish(nil)
+}
+
+// owned returns the transaction in ctx when the unit of work ctx belongs to
+// started it.
+func (f *FirestoreTx) owned(ctx context.Context) (*firestoreTx, bool) {
+	state, ok := ctx.Value(firestoreTxKey).(*firestoreTx)
+	if !ok {
+		return nil, false
+	}
+	if outer, joined := ctx.Value(firestoreJoinedKey).(*firestoreTx); joined && outer == state {
+		return nil, false
+	}
+	return state, true
+}
+
+// IsFirestoreAborted reports whether err carries the gRPC code Aborted, which
+// Firestore returns when a transaction lost a conflict with a concurrent one
+// and can be run again. Use it with WithRetryIf:
+//
+//	uow.New(uow.NewFirestoreTx(client), uow.WithMaxRetries(3), uow.WithRetryIf(uow.IsFirestoreAborted))
+func IsFirestoreAborted(err error) bool {
+	var se interface{ GRPCStatus() *status.Status }
+	return errors.As(err, &se) && se.GRPCStatus().Code() == codes.Aborted
+}
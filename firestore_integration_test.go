@@ -0,0 +1,75 @@
+//go:build integration
+
+package uow
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+)
+
+// openFirestore connects to the Firestore emulator given by
+// FIRESTORE_EMULATOR_HOST. The test is skipped when the variable is not set.
+func openFirestore(t *testing.T) *firestore.Client {
+	t.Helper()
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		t.Skip("FIRESTORE_EMULATOR_HOST not set; skipping integration test")
+	}
+	client, err := firestore.NewClient(context.Background(), "uow-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+// TestFirestoreTx_Integration verifies that writes made through the bridged
+// transaction are applied on commit and discarded on rollback.
+func TestFirestoreTx_Integration(t *testing.T) {
+	client := openFirestore(t)
+	ctx := context.Background()
+	doc := client.Collection("uow_test").Doc(t.Name())
+	if _, err := doc.Set(ctx, map[string]any{"balance": 10}); err != nil {
+		t.Fatal(err)
+	}
+	txs := New(NewFirestoreTx(client))
+
+	err := txs.Run(ctx, func(ctx context.Context) error {
+		tx := txs.Get(ctx).(*firestore.Transaction)
+		snap, err := tx.Get(doc)
+		if err != nil {
+			return err
+		}
+		balance, err := snap.DataAt("balance")
+		if err != nil {
+			return err
+		}
+		return tx.Set(doc, map[string]any{"balance": balance.(int64) + 5})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fnErr := errors.New("fn failed")
+	err = txs.Run(ctx, func(ctx context.Context) error {
+		tx := txs.Get(ctx).(*firestore.Transaction)
+		if err := tx.Set(doc, map[string]any{"balance": 0}); err != nil {
+			return err
+		}
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) {
+		t.Fatalf("expected fn error, got %v", err)
+	}
+
+	snap, err := doc.Get(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := snap.DataAt("balance"); got != int64(15) {
+		t.Errorf("expected the committed balance of 15, got %v", got)
+	}
+}
@@ -0,0 +1,94 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newLazyFirestoreClient returns a client for an emulator that is never
+// reached, which is enough for tests that start no transaction.
+func newLazyFirestoreClient(t *testing.T) *firestore.Client {
+	t.Helper()
+	t.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:1")
+	client, err := firestore.NewClient(context.Background(), "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Close() })
+	return client
+}
+
+// TestFirestoreTx_NoTransaction verifies that Get falls back to the client and
+// that Commit and Rollback do nothing outside a transaction.
+func TestFirestoreTx_NoTransaction(t *testing.T) {
+	client := newLazyFirestoreClient(t)
+	f := NewFirestoreTx(client)
+
+	if got := f.Get(context.Background()); got != client {
+		t.Errorf("expected the client outside a transaction, got %v", got)
+	}
+	if err := f.Rollback(context.Background()); err != nil {
+		t.Errorf("expected Rollback to do nothing, got %v", err)
+	}
+	if err := f.Commit(context.Background()); err != nil {
+		t.Errorf("expected Commit to do nothing, got %v", err)
+	}
+}
+
+// TestFirestoreTx_EndTwice verifies that ending a transaction again returns
+// the outcome of the first Commit or Rollback instead of blocking.
+func TestFirestoreTx_EndTwice(t *testing.T) {
+	f := NewFirestoreTx(newLazyFirestoreClient(t))
+	commitErr := errors.New("commit failed")
+	tests := []struct {
+		name    string
+		first   func(context.Context) error
+		result  error
+		wantErr error
+	}{
+		{name: "commit", first: f.Commit, result: commitErr, wantErr: commitErr},
+		{name: "rollback", first: f.Rollback, result: errFirestoreRollback},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Stand in for the RunTransaction goroutine, which picks up a
+			// single decision.
+			state := &firestoreTx{decide: make(chan error, 1), done: make(chan error, 1)}
+			go func() { <-state.decide; state.done <- tt.result }()
+			ctx := context.WithValue(context.Background(), firestoreTxKey, state)
+
+			if err := tt.first(ctx); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			for _, end := range []func(context.Context) error{f.Commit, f.Rollback} {
+				done := make(chan error, 1)
+				go func() { done <- end(ctx) }()
+				select {
+				case <-done:
+				case <-time.After(time.Second):
+					t.Fatal("expected ending the transaction again not to block")
+				}
+			}
+		})
+	}
+}
+
+// TestIsFirestoreAborted verifies that only the Aborted code is classified as
+// retryable, also when wrapped.
+func TestIsFirestoreAborted(t *testing.T) {
+	aborted := status.Error(codes.Aborted, "too much contention")
+	if !IsFirestoreAborted(fmt.Errorf("failed to commit transaction: %w", aborted)) {
+		t.Error("expected a wrapped Aborted error to be retryable")
+	}
+	if IsFirestoreAborted(status.Error(codes.NotFound, "missing")) {
+		t.Error("expected NotFound not to be retryable")
+	}
+}
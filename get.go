@@ -0,0 +1,34 @@
+package uow
+
+import (
+	"context"
+	"fmt"
+)
+
+// GetTyped returns the value u.Get(ctx) retrieves from the runner as a T. Unlike
+// a type assertion on Get it does not panic: when the value is not a T, it
+// returns the zero value and an error naming the type that was actually
+// returned.
+func GetTyped[T any](ctx context.Context, u *UoW) (T, error) {
+	v := u.Get(ctx)
+	t, ok := v.(T)
+	if !ok {
+		var zero T
+		return zero, fmt.Errorf("runner %s returned %T, not %T", u.runnerName, v, zero)
+	}
+	return t, nil
+}
+
+// RunWith runs fn through u and passes it the value Get retrieves from the
+// runner as a T, such as *sql.Tx or *mongo.Database, sparing fn the call to
+// Get and the type assertion. When the value is not a T, fn is not called and
+// the transaction rolls back with the error GetTyped returns.
+func RunWith[T any](ctx context.Context, u *UoW, fn func(ctx context.Context, tx T) error, opts ...RunOption) error {
+	return u.Run(ctx, func(ctx context.Context) error {
+		tx, err := GetTyped[T](ctx, u)
+		if err != nil {
+			return err
+		}
+		return fn(ctx, tx)
+	}, opts...)
+}
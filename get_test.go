@@ -0,0 +1,68 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+)
+
+// TestGetTyped verifies that GetTyped returns the runner's value when the type
+// matches and a descriptive error instead of panicking when it does not.
+func TestGetTyped(t *testing.T) {
+	u := New(NewMockTx())
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		state, err := GetTyped[*State](ctx, &u)
+		if err != nil {
+			return err
+		}
+		state.SetValue("typed")
+
+		tx, err := GetTyped[*sql.Tx](ctx, &u)
+		if err == nil {
+			t.Fatal("expected an error for a mismatched type")
+		}
+		if tx != nil {
+			t.Errorf("expected a nil *sql.Tx, got %v", tx)
+		}
+		if !strings.Contains(err.Error(), "*uow.State") || !strings.Contains(err.Error(), "*sql.Tx") {
+			t.Errorf("expected the error to name both types, got %q", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestRunWith verifies that RunWith passes the typed runner value to fn and
+// rolls back without calling fn when the type does not match.
+func TestRunWith(t *testing.T) {
+	mt := NewMockTx()
+	u := New(mt)
+	err := RunWith(context.Background(), &u, func(_ context.Context, state *State) error {
+		state.SetValue("typed")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mt.State().Value(); got != "typed committed!" {
+		t.Errorf("expected the value to be committed, got %q", got)
+	}
+
+	called := false
+	err = RunWith(context.Background(), &u, func(_ context.Context, _ *sql.Tx) error {
+		called = true
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "*sql.Tx") {
+		t.Errorf("expected a type mismatch error, got %v", err)
+	}
+	if called {
+		t.Error("expected fn not to be called")
+	}
+	if got := mt.State().Status(); got != StateRolledBack {
+		t.Errorf("expected a rollback, got status %v", got)
+	}
+}
@@ -0,0 +1,157 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// gormTxKey is the context key for storing the GORM transaction handle.
+var gormTxKey = ctxKey{"gorm_tx"}
+
+// gormSavepointKey is the context key for storing the savepoint of a nested
+// unit of work.
+var gormSavepointKey = ctxKey{"gorm_savepoint"}
+
+// gormSavepoint is the savepoint created for a nested unit of work.
+type gormSavepoint struct {
+	tx    *gorm.DB
+	name  string
+	depth int
+}
+
+// GormTx implements the Runner interface for GORM (gorm.io/gorm). The
+// transactional *gorm.DB returned by Get is used with the regular GORM API.
+var _ Runner = &GormTx{}
+
+// GormTx struct holds the GORM handle and the transaction options.
+type GormTx struct {
+	db        *gorm.DB
+	txOptions *sql.TxOptions
+}
+
+// GormOption configures optional behavior of a GormTx. Options are passed to
+// NewGormTx.
+type GormOption func(*GormTx)
+
+// WithGormTxOptions sets the options every transaction is started with, such
+// as the isolation level and read-only mode.
+func WithGormTxOptions(opts *sql.TxOptions) GormOption {
+	return func(g *GormTx) {
+		g.txOptions = opts
+	}
+}
+
+// NewGormTx creates a new GormTx instance. It takes a GORM handle and
+// optional settings as arguments.
+func NewGormTx(db *gorm.DB, opts ...GormOption) *GormTx {
+	g := &GormTx{
+		db: db,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	return g
+}
+
+// Ctx starts a new transaction with db.Begin, bound to the provided context,
+// and stores the transactional *gorm.DB in the returned context. When ctx
+// already carries a transaction, a nested unit of work joins it through a
+// savepoint named after the nesting level instead: Rollback rolls back to it,
+// and Commit keeps the changes, as GORM's own nested transactions do.
+func (g *GormTx) Ctx(ctx context.Context) (context.Context, error) {
+	if tx, ok := ctx.Value(gormTxKey).(*gorm.DB); ok {
+		return g.nest(ctx, tx)
+	}
+	var tx *gorm.DB
+	if opts := readOnlyTxOptions(ctx, g.txOptions); opts != nil {
+		tx = g.db.WithContext(ctx).Begin(opts)
+	} else {
+		tx = g.db.WithContext(ctx).Begin()
+	}
+	if tx.Error != nil {
+		return nil, fmt.Errorf("error in starting transaction: %w", tx.Error)
+	}
+	return context.WithValue(ctx, gormTxKey, tx), nil
+}
+
+// nest creates the savepoint of a unit of work nested in tx.
+func (g *GormTx) nest(ctx context.Context, tx *gorm.DB) (context.Context, error) {
+	depth := 1
+	if outer := gormSavepointFrom(ctx, tx); outer != nil {
+		depth = outer.depth + 1
+	}
+	sp := &gormSavepoint{tx: tx, name: fmt.Sprintf("uow_sp_%d", depth), depth: depth}
+	if err := gormSession(ctx, tx).SavePoint(sp.name).Error; err != nil {
+		return nil, fmt.Errorf("error in creating savepoint: %w", err)
+	}
+	return context.WithValue(ctx, gormSavepointKey, sp), nil
+}
+
+// gormSavepointFrom returns the savepoint stored in ctx for tx, or nil when
+// ctx does not belong to a nested unit of work.
+func gormSavepointFrom(ctx context.Context, tx *gorm.DB) *gormSavepoint {
+	sp, ok := ctx.Value(gormSavepointKey).(*gormSavepoint)
+	if !ok || sp.tx != tx {
+		return nil
+	}
+	return sp
+}
+
+// gormSession returns a session of tx bound to ctx that does not carry the
+// errors previously recorded on tx, so that savepoint statements run
+// regardless of them.
+func gormSession(ctx context.Context, tx *gorm.DB) *gorm.DB {
+	sess := tx.Session(&gorm.Session{Context: ctx})
+	sess.Error = nil
+	return sess
+}
+
+// Get retrieves the GORM handle. If a transaction exists in the context, it
+// returns the transactional *gorm.DB. Otherwise, it returns the *gorm.DB the
+// runner was created with.
+func (g *GormTx) Get(ctx context.Context) any {
+	if tx, ok := ctx.Value(gormTxKey).(*gorm.DB); ok {
+		return tx
+	}
+	return g.db
+}
+
+// Rollback aborts the current transaction. It is attempted even when the
+// transactional handle already carries an error from a failed statement, and
+// only the error of the rollback itself is returned. A nested unit of work
+// rolls back to its savepoint instead.
+func (g *GormTx) Rollback(ctx context.Context) error {
+	tx, ok := ctx.Value(gormTxKey).(*gorm.DB)
+	if !ok {
+		return nil
+	}
+	if sp := gormSavepointFrom(ctx, tx); sp != nil {
+		return gormSession(ctx, tx).RollbackTo(sp.name).Error
+	}
+	committer, ok := tx.Statement.ConnPool.(gorm.TxCommitter)
+	if !ok || committer == nil {
+		return gorm.ErrInvalidTransaction
+	}
+	return committer.Rollback()
+}
+
+// Commit commits the current transaction. Only the error of the commit itself
+// is returned, not errors previously recorded on the handle. A nested unit of
+// work leaves the outcome to the enclosing transaction.
+func (g *GormTx) Commit(ctx context.Context) error {
+	tx, ok := ctx.Value(gormTxKey).(*gorm.DB)
+	if !ok {
+		return nil
+	}
+	if gormSavepointFrom(ctx, tx) != nil {
+		return nil
+	}
+	committer, ok := tx.Statement.ConnPool.(gorm.TxCommitter)
+	if !ok || committer == nil {
+		return gorm.ErrInvalidTransaction
+	}
+	return committer.Commit()
+}
@@ -0,0 +1,142 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// gormUser is the model used by the GORM tests.
+type gormUser struct {
+	ID   uint
+	Name string
+}
+
+// openGorm opens an in-memory SQLite database through GORM with the gormUser
+// table migrated.
+func openGorm(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+
+	if err := db.AutoMigrate(&gormUser{}); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// TestGormTx verifies the commit and rollback round trips through GORM.
+func TestGormTx(t *testing.T) {
+	db := openGorm(t)
+	txs := New(NewGormTx(db))
+
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		return txs.Get(ctx).(*gorm.DB).Create(&gormUser{Name: "committed"}).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fnErr := errors.New("fn failed")
+	err = txs.Run(context.Background(), func(ctx context.Context) error {
+		if err := txs.Get(ctx).(*gorm.DB).Create(&gormUser{Name: "rolled back"}).Error; err != nil {
+			return err
+		}
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) {
+		t.Fatalf("expected fn error, got %v", err)
+	}
+
+	var names []string
+	if err := db.Model(&gormUser{}).Pluck("name", &names).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "committed" {
+		t.Errorf("expected only the committed user, got %v", names)
+	}
+}
+
+// TestGormTx_RollbackWithSessionError verifies that the rollback is attempted
+// and succeeds when the transactional handle already carries an error.
+func TestGormTx_RollbackWithSessionError(t *testing.T) {
+	db := openGorm(t)
+	txs := New(NewGormTx(db))
+
+	stmtErr := errors.New("statement failed")
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		tx := txs.Get(ctx).(*gorm.DB)
+		if err := tx.Create(&gormUser{Name: "rolled back"}).Error; err != nil {
+			return err
+		}
+		return tx.AddError(stmtErr)
+	})
+	if !errors.Is(err, stmtErr) {
+		t.Fatalf("expected statement error, got %v", err)
+	}
+	if strings.Contains(err.Error(), "rollback also failed") {
+		t.Errorf("expected the rollback to succeed, got %v", err)
+	}
+
+	var count int64
+	if err := db.Model(&gormUser{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("expected the insert to be rolled back, got %d rows", count)
+	}
+}
+
+// TestGormTx_NestedRun verifies that a nested unit of work joins the outer
+// transaction through a savepoint: an inner failure keeps the outer work.
+func TestGormTx_NestedRun(t *testing.T) {
+	db := openGorm(t)
+	txs := New(NewGormTx(db))
+	insert := func(ctx context.Context, name string) error {
+		return txs.Get(ctx).(*gorm.DB).Create(&gormUser{Name: name}).Error
+	}
+
+	innerErr := errors.New("inner failed")
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		if err := insert(ctx, "outer"); err != nil {
+			return err
+		}
+		err := txs.Run(ctx, func(ctx context.Context) error {
+			if err := insert(ctx, "inner"); err != nil {
+				return err
+			}
+			// A second level of nesting gets its own savepoint.
+			if err := txs.Run(ctx, func(ctx context.Context) error { return insert(ctx, "innermost") }); err != nil {
+				return err
+			}
+			return innerErr
+		})
+		if !errors.Is(err, innerErr) {
+			t.Errorf("expected inner error, got %v", err)
+		}
+		return txs.Run(ctx, func(ctx context.Context) error { return insert(ctx, "kept") })
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	if err := db.Model(&gormUser{}).Order("id").Pluck("name", &names).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "outer" || names[1] != "kept" {
+		t.Errorf("expected the outer and kept users, got %v", names)
+	}
+}
@@ -0,0 +1,83 @@
+package uow
+
+import (
+	"context"
+	"fmt"
+)
+
+// WithAfterBegin registers a hook that runs inside the transaction right after
+// it has started, before preconditions and fn, e.g. to issue SET LOCAL
+// search_path or to set the row-level-security user on a SQL transaction.
+// When the hook fails, the transaction rolls back and Run returns its error
+// without running fn. Hooks are cumulative and run in registration order.
+func WithAfterBegin(hook func(ctx context.Context) error) Option {
+	return func(c *config) {
+		c.afterBegin = append(c.afterBegin, hook)
+	}
+}
+
+// WithBeforeCommit registers a hook that runs inside the transaction after fn
+// has succeeded, right before commit, e.g. to validate the outcome of the unit
+// of work. When the hook fails, the transaction rolls back and Run returns
+// its error. Hooks are cumulative and run in registration order.
+func WithBeforeCommit(hook func(ctx context.Context) error) Option {
+	return func(c *config) {
+		c.beforeCommit = append(c.beforeCommit, hook)
+	}
+}
+
+// WithAfterCommit registers a hook that runs once the transaction has been
+// committed, outside of it, e.g. to publish domain events. It never runs for a
+// transaction that rolls back. Hooks are cumulative and run in registration
+// order.
+func WithAfterCommit(hook func(ctx context.Context)) Option {
+	return func(c *config) {
+		c.afterCommit = append(c.afterCommit, hook)
+	}
+}
+
+// WithAfterRollback registers a hook that runs after the transaction has been
+// rolled back, outside of it, e.g. to release a reserved external resource.
+// cause is the error that made the unit of work roll back; when fn panicked it
+// describes the panic, which propagates once the hooks have run. The hook runs
+// even if the rollback itself failed, and never after a successful commit.
+// Hooks are cumulative and run in registration order.
+func WithAfterRollback(hook func(ctx context.Context, cause error)) Option {
+	return func(c *config) {
+		c.afterRollback = append(c.afterRollback, hook)
+	}
+}
+
+// runAfterBegin runs the after-begin hooks in the transactional context.
+func (u *UoW) runAfterBegin(ctx context.Context) error {
+	for _, hook := range u.config.afterBegin {
+		if err := hook(ctx); err != nil {
+			return fmt.Errorf("after-begin hook failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// runBeforeCommit runs the before-commit hooks in the transactional context.
+func (u *UoW) runBeforeCommit(ctx context.Context) error {
+	for _, hook := range u.config.beforeCommit {
+		if err := hook(ctx); err != nil {
+			return fmt.Errorf("before-commit hook failed: %w", err)
+		}
+	}
+	return nil
+}
+
+// runAfterCommit runs the after-commit hooks.
+func (u *UoW) runAfterCommit(ctx context.Context) {
+	for _, hook := range u.config.afterCommit {
+		hook(ctx)
+	}
+}
+
+// runAfterRollback runs the after-rollback hooks.
+func (u *UoW) runAfterRollback(ctx context.Context, cause error) {
+	for _, hook := range u.config.afterRollback {
+		hook(ctx, cause)
+	}
+}
@@ -0,0 +1,140 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestCommitHooks verifies the order of the commit hooks around a successful
+// commit.
+func TestCommitHooks(t *testing.T) {
+	mt := NewMockTx()
+	var calls []string
+	u := New(mt,
+		WithBeforeCommit(func(_ context.Context) error {
+			calls = append(calls, "before:"+mt.state.Value())
+			return nil
+		}),
+		WithAfterCommit(func(_ context.Context) {
+			calls = append(calls, "after:"+mt.state.Value())
+		}),
+	)
+
+	err := u.Run(context.Background(), func(_ context.Context) error {
+		calls = append(calls, "fn")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"fn", "before:", "after: committed!"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("expected calls %v, got %v", want, calls)
+	}
+}
+
+// TestCommitHooks_BeforeCommitFails verifies that a failing before-commit hook
+// rolls back and that the after-commit hook does not run.
+func TestCommitHooks_BeforeCommitFails(t *testing.T) {
+	mt := NewMockTx()
+	hookErr := errors.New("invalid outcome")
+	afterCalled := false
+	u := New(mt,
+		WithBeforeCommit(func(_ context.Context) error { return hookErr }),
+		WithAfterCommit(func(_ context.Context) { afterCalled = true }),
+	)
+
+	err := u.Run(context.Background(), func(_ context.Context) error { return nil })
+	if !errors.Is(err, hookErr) {
+		t.Errorf("expected hook error, got %v", err)
+	}
+	if got := mt.state.Value(); got != " rolled back!" {
+		t.Errorf("expected rollback, got %q", got)
+	}
+	if afterCalled {
+		t.Error("expected the after-commit hook not to run after a rollback")
+	}
+}
+
+// TestAfterRollback verifies that the after-rollback hook receives the cause
+// of the rollback, also when fn panics, and does not run after a commit.
+func TestAfterRollback(t *testing.T) {
+	var causes []error
+	u := New(NewMockTx(), WithAfterRollback(func(_ context.Context, cause error) {
+		causes = append(causes, cause)
+	}))
+
+	if err := u.Run(context.Background(), func(_ context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if len(causes) != 0 {
+		t.Fatalf("expected no after-rollback call on commit, got %v", causes)
+	}
+
+	fnErr := errors.New("reservation failed")
+	_ = u.Run(context.Background(), func(_ context.Context) error { return fnErr })
+	if len(causes) != 1 || !errors.Is(causes[0], fnErr) {
+		t.Fatalf("expected the fn error as cause, got %v", causes)
+	}
+
+	func() {
+		defer func() { _ = recover() }()
+		_ = u.Run(context.Background(), func(_ context.Context) error { panic("boom") })
+	}()
+	if len(causes) != 2 || causes[1] == nil || causes[1].Error() != "panic: boom" {
+		t.Errorf("expected the panic as cause, got %v", causes)
+	}
+}
+
+// TestAfterBegin verifies that after-begin hooks run in the transaction
+// before preconditions and fn, and that a failing hook rolls back without
+// running fn.
+func TestAfterBegin(t *testing.T) {
+	mt := NewMockTx()
+	var calls []string
+	u := New(mt,
+		WithAfterBegin(func(ctx context.Context) error {
+			if !InTransaction(ctx) {
+				t.Error("expected the hook to run inside the transaction")
+			}
+			calls = append(calls, "after-begin")
+			return nil
+		}),
+		WithPrecondition(func(_ context.Context) error {
+			calls = append(calls, "precondition")
+			return nil
+		}),
+	)
+
+	err := u.Run(context.Background(), func(_ context.Context) error {
+		calls = append(calls, "fn")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"after-begin", "precondition", "fn"}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("expected calls %v, got %v", want, calls)
+	}
+
+	hookErr := errors.New("set local failed")
+	mt = NewMockTx()
+	u = New(mt, WithAfterBegin(func(_ context.Context) error { return hookErr }))
+	fnCalled := false
+	err = u.Run(context.Background(), func(_ context.Context) error {
+		fnCalled = true
+		return nil
+	})
+	if !errors.Is(err, hookErr) {
+		t.Errorf("expected hook error, got %v", err)
+	}
+	if fnCalled {
+		t.Error("expected fn not to run after a failing hook")
+	}
+	if got := mt.state.Value(); got != " rolled back!" {
+		t.Errorf("expected rollback, got %q", got)
+	}
+}
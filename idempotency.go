@@ -0,0 +1,101 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrNoIdempotencyStore is returned by RunIdempotent when the UoW was created
+// without WithIdempotencyStore.
+var ErrNoIdempotencyStore = errors.New("no idempotency store configured")
+
+// IdempotencyStore records the idempotency keys of units of work that have
+// completed. Both methods are called inside the transaction with its context,
+// so a store backed by the same data source, e.g. a table written through the
+// transaction found in ctx, marks the key atomically with the business change.
+type IdempotencyStore interface {
+	// Seen reports whether key has been marked.
+	Seen(ctx context.Context, key string) (bool, error)
+	// Mark records key.
+	Mark(ctx context.Context, key string) error
+}
+
+// WithIdempotencyStore sets the store used by RunIdempotent.
+func WithIdempotencyStore(store IdempotencyStore) Option {
+	return func(c *config) {
+		c.idempotency = store
+	}
+}
+
+// RunIdempotent runs fn as a unit of work at most once per key, which suits
+// consumers of at-least-once message deliveries. Inside the transaction it
+// asks the configured IdempotencyStore whether key has been seen: if so, fn is
+// skipped, the transaction rolls back and nil is returned. Otherwise fn runs
+// and key is marked right after it, in the same transaction. Retries of a
+// failed attempt check the key again.
+func (u *UoW) RunIdempotent(ctx context.Context, key string, fn func(ctx context.Context) error, opts ...RunOption) error {
+	store := u.config.idempotency
+	if store == nil {
+		return ErrNoIdempotencyStore
+	}
+	return u.Run(ctx, func(ctx context.Context) error {
+		seen, err := store.Seen(ctx, key)
+		if err != nil {
+			return fmt.Errorf("failed to check idempotency key %q: %w", key, err)
+		}
+		if seen {
+			return ErrAbort
+		}
+		if err := fn(ctx); err != nil {
+			return err
+		}
+		if err := store.Mark(ctx, key); err != nil {
+			return fmt.Errorf("failed to mark idempotency key %q: %w", key, err)
+		}
+		return nil
+	}, opts...)
+}
+
+// MemoryIdempotencyStore is an in-memory IdempotencyStore for tests. A key
+// marked inside a unit of work is only recorded once the transaction commits,
+// so a rolled back unit of work can be processed again. Concurrent units of
+// work with the same key may both run, since neither has committed when the
+// other checks the key.
+var _ IdempotencyStore = &MemoryIdempotencyStore{}
+
+// MemoryIdempotencyStore struct holds the recorded keys.
+type MemoryIdempotencyStore struct {
+	mu   sync.Mutex
+	keys map[string]struct{}
+}
+
+// NewMemoryIdempotencyStore creates a new, empty MemoryIdempotencyStore.
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		keys: make(map[string]struct{}),
+	}
+}
+
+// Seen reports whether key has been recorded.
+func (s *MemoryIdempotencyStore) Seen(_ context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.keys[key]
+	return ok, nil
+}
+
+// Mark records key once the transaction that ctx belongs to commits, or right
+// away when ctx does not belong to a unit of work.
+func (s *MemoryIdempotencyStore) Mark(ctx context.Context, key string) error {
+	record := func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.keys[key] = struct{}{}
+	}
+	if !OnCommit(ctx, record) {
+		record()
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestRunIdempotent verifies that fn runs once per key and that a key is only
+// marked when the unit of work commits.
+func TestRunIdempotent(t *testing.T) {
+	store := NewMemoryIdempotencyStore()
+	mt := NewMockTx()
+	u := New(mt, WithIdempotencyStore(store))
+
+	calls := 0
+	fn := func(_ context.Context) error {
+		calls++
+		return nil
+	}
+	for i := 0; i < 2; i++ {
+		if err := u.RunIdempotent(context.Background(), "msg-1", fn); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected fn to run once, got %d calls", calls)
+	}
+	if n := mt.CallCount("commit"); n != 1 {
+		t.Errorf("expected the duplicate to commit nothing, got %d commits", n)
+	}
+
+	fnErr := errors.New("fn failed")
+	err := u.RunIdempotent(context.Background(), "msg-2", func(_ context.Context) error { return fnErr })
+	if !errors.Is(err, fnErr) {
+		t.Fatalf("expected fn error, got %v", err)
+	}
+	if seen, _ := store.Seen(context.Background(), "msg-2"); seen {
+		t.Error("expected a rolled back key not to be marked")
+	}
+	if err := u.RunIdempotent(context.Background(), "msg-2", fn); err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Errorf("expected the rolled back key to be processed again, got %d calls", calls)
+	}
+}
+
+// TestRunIdempotent_NoStore verifies that RunIdempotent refuses to run without
+// a store.
+func TestRunIdempotent_NoStore(t *testing.T) {
+	u := New(NewMockTx())
+	err := u.RunIdempotent(context.Background(), "msg", func(_ context.Context) error {
+		t.Error("expected fn not to run")
+		return nil
+	})
+	if !errors.Is(err, ErrNoIdempotencyStore) {
+		t.Errorf("expected ErrNoIdempotencyStore, got %v", err)
+	}
+}
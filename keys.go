@@ -0,0 +1,9 @@
+package uow
+
+// ctxKey is an unexported struct type used for context value keys. Values of
+// it can only be created in this package, so the keys cannot collide with
+// those of user code or other packages, even ones using the same names. The
+// name only identifies the key when debugging.
+type ctxKey struct {
+	name string
+}
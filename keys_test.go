@@ -0,0 +1,45 @@
+package uow_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/agtabesh/uow"
+)
+
+// ctxKey mirrors the name of the package's key type, as user code might.
+type ctxKey string
+
+// TestContextKeys_NoCollision verifies that values stored by user code under
+// the names the package uses for its keys are neither visible to the package
+// nor overwritten by it.
+func TestContextKeys_NoCollision(t *testing.T) {
+	ctx := context.Background()
+	for _, name := range []string{"tx", "tx_id", "run_state", "mongo_database"} {
+		ctx = context.WithValue(ctx, ctxKey(name), "user key")
+	}
+
+	if id := uow.TxID(ctx); id != "" {
+		t.Errorf("expected no transaction ID from user keys, got %q", id)
+	}
+	if err := uow.CheckActive(ctx); !errors.Is(err, uow.ErrNoTransaction) {
+		t.Errorf("expected ErrNoTransaction with user keys only, got %v", err)
+	}
+
+	txs := uow.New(uow.NewMockTx())
+	err := txs.Run(uow.ContextWithTxID(ctx, "correlation"), func(ctx context.Context) error {
+		if id := uow.TxID(ctx); id != "correlation" {
+			t.Errorf("expected the package's transaction ID, got %q", id)
+		}
+		for _, name := range []string{"tx", "tx_id", "run_state", "mongo_database"} {
+			if v := ctx.Value(ctxKey(name)); v != "user key" {
+				t.Errorf("key %q: expected the user value, got %v", name, v)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
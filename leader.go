@@ -0,0 +1,38 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNotLeader is returned by Run when the leader check configured with
+// WithLeaderCheck reports that this instance is not the leader. No transaction
+// is started in that case.
+var ErrNotLeader = errors.New("not the leader")
+
+// WithLeaderCheck restricts the unit of work to the elected leader of a
+// cluster. isLeader is consulted before the transaction starts, typically by
+// asking an external leader-election mechanism; when it returns false Run
+// returns ErrNotLeader without calling the runner, and when it fails Run
+// returns its error.
+func WithLeaderCheck(isLeader func(ctx context.Context) (bool, error)) Option {
+	return func(c *config) {
+		c.leaderCheck = isLeader
+	}
+}
+
+// checkLeader consults the configured leader check, if any.
+func (u *UoW) checkLeader(ctx context.Context) error {
+	if u.config.leaderCheck == nil {
+		return nil
+	}
+	leader, err := u.config.leaderCheck(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to check leadership: %w", err)
+	}
+	if !leader {
+		return ErrNotLeader
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// spyRunner wraps a Runner and counts how often Ctx is called.
+type spyRunner struct {
+	Runner
+	ctxCalls int
+}
+
+func (r *spyRunner) Ctx(ctx context.Context) (context.Context, error) {
+	r.ctxCalls++
+	return r.Runner.Ctx(ctx)
+}
+
+// TestWithLeaderCheck verifies that a non-leader skips the transaction while a
+// leader proceeds.
+func TestWithLeaderCheck(t *testing.T) {
+	checkErr := errors.New("election backend unavailable")
+
+	tests := []struct {
+		name     string
+		leader   bool
+		checkErr error
+		wantErr  error
+		wantRuns int
+	}{
+		{name: "leader", leader: true, wantRuns: 1},
+		{name: "not_leader", leader: false, wantErr: ErrNotLeader},
+		{name: "check_error", checkErr: checkErr, wantErr: checkErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			spy := &spyRunner{Runner: NewMockTx()}
+			u := New(spy, WithLeaderCheck(func(_ context.Context) (bool, error) {
+				return tt.leader, tt.checkErr
+			}))
+
+			runs := 0
+			err := u.Run(context.Background(), func(_ context.Context) error {
+				runs++
+				return nil
+			})
+			if tt.wantErr == nil && err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+			if runs != tt.wantRuns || spy.ctxCalls != tt.wantRuns {
+				t.Errorf("expected %d runs and Ctx calls, got %d runs and %d Ctx calls", tt.wantRuns, runs, spy.ctxCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package uow
+
+// Logger receives structured log records about transactions. Messages are
+// accompanied by alternating key/value pairs, as in log/slog, so that
+// *slog.Logger satisfies it and adapters for other logging libraries are
+// short.
+type Logger interface {
+	// Debug logs routine transaction events: begin, commit and discard.
+	Debug(msg string, keysAndValues ...any)
+
+	// Error logs failures and rollbacks, with the error under the "error"
+	// key.
+	Error(msg string, keysAndValues ...any)
+}
+
+// WithLogger sets the logger that transaction events are written to. Without
+// it nothing is logged and no log records are built.
+func WithLogger(l Logger) Option {
+	return func(c *config) {
+		c.logger = l
+	}
+}
+
+// logDebug writes a debug record about the attempt described by rs.
+func (u *UoW) logDebug(msg string, rs *runState, keysAndValues ...any) {
+	if u.config.logger != nil {
+		u.config.logger.Debug(msg, u.logFields(rs, keysAndValues)...)
+	}
+}
+
+// logError writes an error record about the attempt described by rs.
+func (u *UoW) logError(msg string, rs *runState, keysAndValues ...any) {
+	if u.config.logger != nil {
+		u.config.logger.Error(msg, u.logFields(rs, keysAndValues)...)
+	}
+}
+
+// logFields returns the key/value pairs identifying the attempt described by
+// rs, followed by extra.
+func (u *UoW) logFields(rs *runState, extra []any) []any {
+	fields := []any{"tx_id", rs.txID, "attempt", rs.attempt, "runner", u.runnerName}
+	if u.config.name != "" {
+		fields = append(fields, "name", u.config.name)
+	}
+	return append(fields, extra...)
+}
@@ -0,0 +1,102 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// logRecord is a log record captured by recordingLogger.
+type logRecord struct {
+	level string
+	msg   string
+	kv    map[any]any
+}
+
+// recordingLogger is a Logger that keeps every record.
+type recordingLogger struct {
+	mu      sync.Mutex
+	records []logRecord
+}
+
+func (l *recordingLogger) Debug(msg string, keysAndValues ...any) {
+	l.record("debug", msg, keysAndValues)
+}
+
+func (l *recordingLogger) Error(msg string, keysAndValues ...any) {
+	l.record("error", msg, keysAndValues)
+}
+
+func (l *recordingLogger) record(level, msg string, keysAndValues []any) {
+	kv := make(map[any]any)
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		kv[keysAndValues[i]] = keysAndValues[i+1]
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.records = append(l.records, logRecord{level: level, msg: msg, kv: kv})
+}
+
+// messages returns the level and message of every record.
+func (l *recordingLogger) messages() []string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	var msgs []string
+	for _, r := range l.records {
+		msgs = append(msgs, r.level+": "+r.msg)
+	}
+	return msgs
+}
+
+// TestWithLogger verifies the records written for a commit and a rollback and
+// the fields identifying the transaction.
+func TestWithLogger(t *testing.T) {
+	logger := &recordingLogger{}
+	u := New(NewMockTx(), WithLogger(logger), WithName("transfer"))
+
+	if err := u.Run(context.Background(), func(_ context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	fnErr := errors.New("insufficient funds")
+	_ = u.Run(context.Background(), func(_ context.Context) error { return fnErr })
+
+	want := []string{
+		"debug: transaction started",
+		"debug: transaction committed",
+		"debug: transaction started",
+		"error: transaction rolled back",
+	}
+	if got := logger.messages(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected records %v, got %v", want, got)
+	}
+
+	rolledBack := logger.records[3]
+	if rolledBack.kv["error"] != fnErr {
+		t.Errorf("expected the fn error in the rollback record, got %v", rolledBack.kv["error"])
+	}
+	if rolledBack.kv["runner"] != "*uow.MockTx" || rolledBack.kv["name"] != "transfer" || rolledBack.kv["tx_id"] == "" {
+		t.Errorf("expected identifying fields, got %v", rolledBack.kv)
+	}
+	if logger.records[2].kv["tx_id"] == logger.records[0].kv["tx_id"] {
+		t.Error("expected distinct transaction IDs for distinct runs")
+	}
+}
+
+// TestWithLogger_BeginError verifies that a failed begin is logged as an
+// error.
+func TestWithLogger_BeginError(t *testing.T) {
+	logger := &recordingLogger{}
+	ctxErr := errors.New("pool exhausted")
+	u := New(&errorRunner{ctxErr: ctxErr}, WithLogger(logger))
+
+	_ = u.Run(context.Background(), func(_ context.Context) error { return nil })
+	want := []string{"error: failed to start transaction"}
+	if got := logger.messages(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected records %v, got %v", want, got)
+	}
+	if logger.records[0].kv["error"] != ctxErr {
+		t.Errorf("expected the begin error, got %v", logger.records[0].kv["error"])
+	}
+}
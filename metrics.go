@@ -0,0 +1,73 @@
+package uow
+
+import (
+	"fmt"
+	"reflect"
+	"time"
+)
+
+// MetricsCollector receives the outcome of every transaction attempt made by
+// Run. Implementations typically export counters and latency histograms; the
+// uowotel package provides one for OpenTelemetry. runner is the concrete type
+// name of the runner, as returned by RunnerName, and is meant to be used as a
+// label.
+type MetricsCollector interface {
+	// ObserveCommit is called after a transaction committed. d is the time
+	// from the start of the transaction to the end of the commit.
+	ObserveCommit(runner string, d time.Duration)
+
+	// ObserveRollback is called after a transaction was rolled back or failed
+	// to commit. err is the error that caused it, or nil when the unit of
+	// work was discarded intentionally.
+	ObserveRollback(runner string, d time.Duration, err error)
+
+	// ObserveBeginError is called when a transaction failed to start.
+	ObserveBeginError(runner string, err error)
+}
+
+// WithMetrics registers a collector that receives the outcome of every
+// transaction attempt.
+func WithMetrics(collector MetricsCollector) Option {
+	return func(c *config) {
+		c.metrics = collector
+	}
+}
+
+// RunnerName returns the concrete type name of runner, e.g. "*uow.MongoTx".
+func RunnerName(runner Runner) string {
+	if runner == nil {
+		return "<nil>"
+	}
+	return reflect.TypeOf(runner).String()
+}
+
+// describeRunner identifies runner in error messages. Runners implementing
+// fmt.Stringer describe themselves, e.g. to include the database they work
+// on; others are identified by RunnerName.
+func describeRunner(runner Runner) string {
+	if s, ok := runner.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return RunnerName(runner)
+}
+
+// observeCommit reports a commit to the metrics collector, if any.
+func (u *UoW) observeCommit(start time.Time) {
+	if u.config.metrics != nil {
+		u.config.metrics.ObserveCommit(u.runnerName, time.Since(start))
+	}
+}
+
+// observeRollback reports a rollback to the metrics collector, if any.
+func (u *UoW) observeRollback(start time.Time, err error) {
+	if u.config.metrics != nil {
+		u.config.metrics.ObserveRollback(u.runnerName, time.Since(start), err)
+	}
+}
+
+// observeBeginError reports a failed begin to the metrics collector, if any.
+func (u *UoW) observeBeginError(err error) {
+	if u.config.metrics != nil {
+		u.config.metrics.ObserveBeginError(u.runnerName, err)
+	}
+}
@@ -0,0 +1,60 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingCollector is a MetricsCollector that records the observed events.
+type recordingCollector struct {
+	mu          sync.Mutex
+	commits     []string
+	rollbacks   []error
+	beginErrors []error
+}
+
+func (c *recordingCollector) ObserveCommit(runner string, _ time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.commits = append(c.commits, runner)
+}
+
+func (c *recordingCollector) ObserveRollback(_ string, _ time.Duration, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.rollbacks = append(c.rollbacks, err)
+}
+
+func (c *recordingCollector) ObserveBeginError(_ string, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.beginErrors = append(c.beginErrors, err)
+}
+
+// TestWithMetrics verifies that every transaction outcome reaches the
+// collector, labeled with the runner type.
+func TestWithMetrics(t *testing.T) {
+	ctx := context.Background()
+	fnErr := errors.New("fn failed")
+	ctxErr := errors.New("begin failed")
+	c := &recordingCollector{}
+
+	u := New(NewMockTx(), WithMetrics(c))
+	_ = u.Run(ctx, func(_ context.Context) error { return nil })
+	_ = u.Run(ctx, func(_ context.Context) error { return fnErr })
+	failing := New(&errorRunner{ctxErr: ctxErr}, WithMetrics(c))
+	_ = failing.Run(ctx, func(_ context.Context) error { return nil })
+
+	if len(c.commits) != 1 || c.commits[0] != "*uow.MockTx" {
+		t.Errorf("expected one commit for *uow.MockTx, got %v", c.commits)
+	}
+	if len(c.rollbacks) != 1 || !errors.Is(c.rollbacks[0], fnErr) {
+		t.Errorf("expected one rollback caused by fn error, got %v", c.rollbacks)
+	}
+	if len(c.beginErrors) != 1 || !errors.Is(c.beginErrors[0], ctxErr) {
+		t.Errorf("expected one begin error, got %v", c.beginErrors)
+	}
+}
@@ -4,15 +4,49 @@ package uow
 
 import (
 	"context"
+	"fmt"
+	"slices"
 	"sync"
+	"time"
 )
 
 // State struct simulates application state and provides methods for setting,
-// getting, committing, and rolling back the state. It uses a mutex to ensure
-// thread safety.
+// getting, committing, and rolling back the state. Besides a single string
+// value, it holds structured data by key: entries written with Put are staged
+// until Commit applies them and are dropped by Rollback, so tests can assert
+// which changes survived. It uses a mutex to ensure thread safety.
 type State struct {
-	value string
-	mu    sync.Mutex
+	value  string
+	data   map[string]any
+	staged map[string]any
+	status StateStatus
+	mu     sync.Mutex
+}
+
+// StateStatus is the outcome of the last transaction on a State.
+type StateStatus int
+
+const (
+	// StatePending means the state has not been committed or rolled back yet.
+	StatePending StateStatus = iota
+
+	// StateCommitted means the last transaction was committed.
+	StateCommitted
+
+	// StateRolledBack means the last transaction was rolled back.
+	StateRolledBack
+)
+
+// String returns the name of the status.
+func (s StateStatus) String() string {
+	switch s {
+	case StateCommitted:
+		return "committed"
+	case StateRolledBack:
+		return "rolled back"
+	default:
+		return "pending"
+	}
 }
 
 // SetValue sets the value of the state. It uses a mutex to ensure thread safety.
@@ -29,20 +63,97 @@ func (s *State) Value() string {
 	return s.value
 }
 
-// Commit appends " committed!" to the state value. It uses a mutex to ensure
-// thread safety. This simulates a successful commit operation.
+// Put stages v under key. It becomes part of Data once the transaction
+// commits, and is dropped if it rolls back.
+func (s *State) Put(key string, v any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.staged == nil {
+		s.staged = make(map[string]any)
+	}
+	s.staged[key] = v
+}
+
+// Lookup returns the value stored under key as seen inside the transaction:
+// a staged value if there is one, otherwise the committed value.
+func (s *State) Lookup(key string) (any, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := s.staged[key]; ok {
+		return v, true
+	}
+	v, ok := s.data[key]
+	return v, ok
+}
+
+// Data returns a copy of the committed data.
+func (s *State) Data() map[string]any {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data := make(map[string]any, len(s.data))
+	for k, v := range s.data {
+		data[k] = v
+	}
+	return data
+}
+
+// Status returns the outcome of the last transaction.
+func (s *State) Status() StateStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.status
+}
+
+// StateValue returns the value stored under key in s as a T, as seen inside
+// the transaction. It returns false when key is missing or holds another
+// type.
+func StateValue[T any](s *State, key string) (T, bool) {
+	v, ok := s.Lookup(key)
+	if !ok {
+		var zero T
+		return zero, false
+	}
+	t, ok := v.(T)
+	return t, ok
+}
+
+// Commit appends " committed!" to the state value and applies the staged
+// data. It uses a mutex to ensure thread safety. This simulates a successful
+// commit operation.
 func (s *State) Commit() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.value += " committed!"
+	if len(s.staged) > 0 && s.data == nil {
+		s.data = make(map[string]any, len(s.staged))
+	}
+	for k, v := range s.staged {
+		s.data[k] = v
+	}
+	s.staged = nil
+	s.status = StateCommitted
 }
 
-// Rollback appends " rolled back!" to the state value. It uses a mutex to ensure
-// thread safety. This simulates a rollback operation.
+// Rollback appends " rolled back!" to the state value and drops the staged
+// data. It uses a mutex to ensure thread safety. This simulates a rollback
+// operation.
 func (s *State) Rollback() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.value += " rolled back!"
+	s.staged = nil
+	s.status = StateRolledBack
+}
+
+// Reset clears the value and the data and makes the status StatePending again,
+// as for a new State. It uses a mutex to ensure thread safety.
+func (s *State) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.value = ""
+	s.staged = nil
+	s.data = nil
+	s.status = StatePending
 }
 
 // MockTx implements the Runner interface for testing purposes. It simulates a
@@ -53,6 +164,27 @@ var _ Runner = &MockTx{}
 // a transaction.
 type MockTx struct {
 	state *State
+
+	ctxErr      error
+	commitErr   error
+	rollbackErr error
+
+	mu               sync.Mutex
+	calls            []MockCall
+	depth            int
+	transientCommits int
+}
+
+// MockCall records a single call to a MockTx method.
+type MockCall struct {
+	// Seq is the 1-based position of the call among all calls on the mock.
+	Seq int
+
+	// Op is the method called: "ctx", "get", "commit" or "rollback".
+	Op string
+
+	// At is the time of the call.
+	At time.Time
 }
 
 // NewMockTx creates a new MockTx instance with a new State object. This function
@@ -63,28 +195,238 @@ func NewMockTx() *MockTx {
 	}
 }
 
-// Ctx returns the context without any modification. This is a placeholder
-// function for the mock transaction.
+// State returns the internal State object, so that tests can assert its
+// contents after Run has returned.
+func (t *MockTx) State() *State {
+	return t.state
+}
+
+// WithCtxError makes Ctx fail with err, simulating a transaction that cannot
+// be started. It returns t for chaining.
+func (t *MockTx) WithCtxError(err error) *MockTx {
+	t.ctxErr = err
+	return t
+}
+
+// WithCommitError makes Commit fail with err without committing the state. It
+// returns t for chaining.
+func (t *MockTx) WithCommitError(err error) *MockTx {
+	t.commitErr = err
+	return t
+}
+
+// WithRollbackError makes Rollback fail with err without rolling back the
+// state. It returns t for chaining.
+func (t *MockTx) WithRollbackError(err error) *MockTx {
+	t.rollbackErr = err
+	return t
+}
+
+// WithTransientCommitErrors makes the first n commits of an outermost
+// transaction fail with a *MockLabeledError carrying the
+// "UnknownTransactionCommitResult" label, which IsMongoTransient accepts, and
+// roll the state back as a server aborting the transaction would. Together
+// with WithMaxRetries and WithRetryIf(IsMongoTransient), it lets the retry
+// layer be tested end-to-end with MongoDB semantics but without a server. It
+// returns t for chaining.
+func (t *MockTx) WithTransientCommitErrors(n int) *MockTx {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.transientCommits = n
+	return t
+}
+
+// MockLabeledError is returned by a MockTx configured with
+// WithTransientCommitErrors. Like the errors of the MongoDB driver, it
+// implements mongo.LabeledError, the interface IsMongoTransient classifies
+// errors by.
+type MockLabeledError struct {
+	// Labels are the error labels reported by HasErrorLabel.
+	Labels []string
+}
+
+// Error implements the error interface.
+func (e *MockLabeledError) Error() string {
+	return fmt.Sprintf("mock error with labels %v", e.Labels)
+}
+
+// HasErrorLabel reports whether the error carries label.
+func (e *MockLabeledError) HasErrorLabel(label string) bool {
+	return slices.Contains(e.Labels, label)
+}
+
+// mockDepthKey is the context key for storing the nesting level of a MockTx
+// transaction. It includes the mock so that several mocks, e.g. in a
+// MultiRunner, keep separate depths.
+type mockDepthKey struct {
+	tx *MockTx
+}
+
+// mockLevel is a transaction of a MockTx, stored in its context.
+type mockLevel struct {
+	depth int
+
+	// ended reports whether the transaction has been committed or rolled
+	// back, so that it leaves the depth count once, e.g. when a failed
+	// Commit is followed by a Rollback. It is guarded by the mutex of the
+	// mock.
+	ended bool
+}
+
+// Ctx returns the context marked with the nesting depth of the new
+// transaction, or the error set with WithCtxError. When ctx already carries a
+// transaction of the mock, the new one is nested in it: its Commit and
+// Rollback are recorded but leave the State to the outermost transaction.
 func (t *MockTx) Ctx(ctx context.Context) (context.Context, error) {
-	return ctx, nil
+	t.record("ctx")
+	if t.ctxErr != nil {
+		return nil, t.ctxErr
+	}
+	level := &mockLevel{depth: 1}
+	if outer, ok := ctx.Value(mockDepthKey{t}).(*mockLevel); ok {
+		level.depth = outer.depth + 1
+	}
+	t.mu.Lock()
+	t.depth++
+	t.mu.Unlock()
+	return context.WithValue(ctx, mockDepthKey{t}, level), nil
+}
+
+// Depth returns the number of transactions of the mock that have been started
+// and not yet committed or rolled back, counting the nested ones.
+func (t *MockTx) Depth() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.depth
+}
+
+// nested reports whether ctx carries a transaction nested in another one, and
+// ends it in the depth count unless it has already ended. begun reports
+// whether ctx carries a transaction of the mock at all.
+func (t *MockTx) nested(ctx context.Context) (nested, begun bool) {
+	level, ok := ctx.Value(mockDepthKey{t}).(*mockLevel)
+	if !ok {
+		return false, false
+	}
+	t.mu.Lock()
+	if !level.ended {
+		level.ended = true
+		t.depth--
+	}
+	t.mu.Unlock()
+	return level.depth > 1, true
 }
 
 // Get returns the internal State object. This allows access to the simulated
 // transaction state.
 func (t *MockTx) Get(_ context.Context) any {
+	t.record("get")
 	return t.state
 }
 
 // Rollback calls the Rollback method on the internal State object. This simulates
-// a rollback operation in the mock transaction.
-func (t *MockTx) Rollback(_ context.Context) error {
-	t.state.Rollback()
+// a rollback operation in the mock transaction. It fails with the error set
+// with WithRollbackError, if any. A nested transaction leaves the State
+// unchanged, and so does a context without a transaction of the mock.
+func (t *MockTx) Rollback(ctx context.Context) error {
+	t.record("rollback")
+	nested, begun := t.nested(ctx)
+	if !begun {
+		return nil
+	}
+	if t.rollbackErr != nil {
+		return t.rollbackErr
+	}
+	if !nested {
+		t.state.Rollback()
+	}
 	return nil
 }
 
 // Commit calls the Commit method on the internal State object. This simulates a
-// commit operation in the mock transaction.
-func (t *MockTx) Commit(_ context.Context) error {
-	t.state.Commit()
+// commit operation in the mock transaction. It fails with the error set with
+// WithCommitError or WithTransientCommitErrors, if any. A nested transaction
+// leaves the State unchanged, and so does a context without a transaction of
+// the mock.
+func (t *MockTx) Commit(ctx context.Context) error {
+	t.record("commit")
+	nested, begun := t.nested(ctx)
+	if !begun {
+		return nil
+	}
+	if t.commitErr != nil {
+		return t.commitErr
+	}
+	if !nested {
+		if t.takeTransientCommit() {
+			t.state.Rollback()
+			return &MockLabeledError{Labels: []string{"UnknownTransactionCommitResult"}}
+		}
+		t.state.Commit()
+	}
 	return nil
 }
+
+// takeTransientCommit reports whether the current commit should fail with a
+// transient error, using up one of those set with WithTransientCommitErrors.
+func (t *MockTx) takeTransientCommit() bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.transientCommits <= 0 {
+		return false
+	}
+	t.transientCommits--
+	return true
+}
+
+// Calls returns the calls made on the mock, in order. Failed calls are
+// recorded too.
+func (t *MockTx) Calls() []MockCall {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]MockCall(nil), t.calls...)
+}
+
+// Ops returns the operations of the calls made on the mock, in order, e.g.
+// []string{"ctx", "get", "rollback"}.
+func (t *MockTx) Ops() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	ops := make([]string, len(t.calls))
+	for i, c := range t.calls {
+		ops[i] = c.Op
+	}
+	return ops
+}
+
+// CallCount returns how many times op was called on the mock.
+func (t *MockTx) CallCount(op string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	n := 0
+	for _, c := range t.calls {
+		if c.Op == op {
+			n++
+		}
+	}
+	return n
+}
+
+// Reset resets the State and clears the call log, so that a mock can be
+// reused across sub-tests. The errors injected with WithCtxError,
+// WithCommitError and WithRollbackError are kept. Reset must not be called
+// while a transaction of the mock is running.
+func (t *MockTx) Reset() {
+	t.state.Reset()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.calls = nil
+	t.depth = 0
+}
+
+// record appends a call to the call log.
+func (t *MockTx) record(op string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.calls = append(t.calls, MockCall{Seq: len(t.calls) + 1, Op: op, At: time.Now()})
+}
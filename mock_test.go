@@ -0,0 +1,310 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestMockTx_Errors verifies that the configured errors are returned by the
+// respective methods and surface from Run as they would for a real runner.
+func TestMockTx_Errors(t *testing.T) {
+	ctxErr := errors.New("ctx failed")
+	commitErr := errors.New("commit failed")
+	rollbackErr := errors.New("rollback failed")
+	fnErr := errors.New("fn failed")
+
+	tests := []struct {
+		name      string
+		mock      *MockTx
+		fnErr     error
+		wantErrs  []error
+		wantMsg   string
+		wantState string
+	}{
+		{name: "ctx", mock: NewMockTx().WithCtxError(ctxErr), wantErrs: []error{ctxErr}, wantMsg: "failed to start transaction on *uow.MockTx"},
+		{name: "commit", mock: NewMockTx().WithCommitError(commitErr), wantErrs: []error{commitErr}},
+		{name: "rollback", mock: NewMockTx().WithRollbackError(rollbackErr), fnErr: fnErr, wantErrs: []error{fnErr, rollbackErr}, wantMsg: "rollback also failed"},
+		{name: "rollback_unused", mock: NewMockTx().WithRollbackError(rollbackErr), wantState: " committed!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := New(tt.mock)
+			err := u.Run(context.Background(), func(_ context.Context) error { return tt.fnErr })
+			for _, want := range tt.wantErrs {
+				if !errors.Is(err, want) {
+					t.Errorf("expected errors.Is(err, %v), got %v", want, err)
+				}
+			}
+			if len(tt.wantErrs) == 0 && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if tt.wantMsg != "" && (err == nil || !strings.Contains(err.Error(), tt.wantMsg)) {
+				t.Errorf("expected %q in error, got %v", tt.wantMsg, err)
+			}
+			if got := tt.mock.state.Value(); got != tt.wantState {
+				t.Errorf("expected state %q, got %q", tt.wantState, got)
+			}
+		})
+	}
+}
+
+// TestMockTx_Calls verifies that lifecycle calls are logged in order, so that
+// tests can assert how often each was made.
+func TestMockTx_Calls(t *testing.T) {
+	mt := NewMockTx()
+	u := New(mt, WithMaxRetries(1), WithRetryIf(isErrRetryable))
+
+	attempts := 0
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		_ = u.Get(ctx)
+		attempts++
+		if attempts == 1 {
+			return errRetryable
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"ctx", "get", "rollback", "ctx", "get", "commit"}
+	if got := mt.Ops(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected ops %v, got %v", want, got)
+	}
+	if n := mt.CallCount("rollback"); n != 1 {
+		t.Errorf("expected 1 rollback, got %d", n)
+	}
+	calls := mt.Calls()
+	for i, c := range calls {
+		if c.Seq != i+1 {
+			t.Errorf("expected sequence number %d, got %d", i+1, c.Seq)
+		}
+		if i > 0 && c.At.Before(calls[i-1].At) {
+			t.Errorf("expected non-decreasing timestamps at call %d", c.Seq)
+		}
+	}
+}
+
+// TestMockTx_CallsErrorPath verifies that the error path never commits.
+func TestMockTx_CallsErrorPath(t *testing.T) {
+	mt := NewMockTx()
+	u := New(mt)
+	_ = u.Run(context.Background(), func(_ context.Context) error { return errors.New("fn failed") })
+
+	if mt.CallCount("rollback") != 1 || mt.CallCount("commit") != 0 {
+		t.Errorf("expected exactly one rollback and no commit, got %v", mt.Ops())
+	}
+}
+
+// TestMockTx_CallsCommitError verifies that a failed commit is reported as
+// such and is not followed by a rollback.
+func TestMockTx_CallsCommitError(t *testing.T) {
+	commitErr := errors.New("commit failed")
+	mt := NewMockTx().WithCommitError(commitErr)
+	u := New(mt)
+	err := u.Run(context.Background(), func(_ context.Context) error { return nil })
+
+	if !errors.Is(err, commitErr) {
+		t.Fatalf("expected commit error, got %v", err)
+	}
+	if !strings.HasPrefix(err.Error(), "failed to commit transaction: ") {
+		t.Errorf("expected the commit error to be wrapped, got %q", err)
+	}
+	if want := []string{"ctx", "commit"}; !reflect.DeepEqual(mt.Ops(), want) {
+		t.Errorf("expected calls %v, got %v", want, mt.Ops())
+	}
+}
+
+// TestState_Data verifies that structured data is applied on commit and
+// dropped on rollback, and that the status reports the outcome.
+func TestState_Data(t *testing.T) {
+	type user struct{ Name string }
+	mt := NewMockTx()
+	u := New(mt)
+
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		state := u.Get(ctx).(*State)
+		state.Put("user:1", user{Name: "alice"})
+		if got, ok := StateValue[user](state, "user:1"); !ok || got.Name != "alice" {
+			t.Errorf("expected the staged user inside the transaction, got %v", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mt.State().Status() != StateCommitted {
+		t.Errorf("expected %v, got %v", StateCommitted, mt.State().Status())
+	}
+
+	_ = u.Run(context.Background(), func(ctx context.Context) error {
+		state := u.Get(ctx).(*State)
+		state.Put("user:1", user{Name: "bob"})
+		state.Put("user:2", user{Name: "carol"})
+		return errors.New("fn failed")
+	})
+	if mt.State().Status() != StateRolledBack {
+		t.Errorf("expected %v, got %v", StateRolledBack, mt.State().Status())
+	}
+	want := map[string]any{"user:1": user{Name: "alice"}}
+	if got := mt.State().Data(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v after rollback, got %v", want, got)
+	}
+	if _, ok := StateValue[string](mt.State(), "user:1"); ok {
+		t.Error("expected StateValue to report a type mismatch")
+	}
+}
+
+// TestMockTx_Nested verifies that only the outermost transaction of a nested
+// unit of work changes the State.
+func TestMockTx_Nested(t *testing.T) {
+	t.Run("inner_fails_outer_commits", func(t *testing.T) {
+		mt := NewMockTx()
+		u := New(mt)
+		innerErr := errors.New("inner failed")
+		err := u.Run(context.Background(), func(ctx context.Context) error {
+			u.Get(ctx).(*State).SetValue("outer")
+			err := u.Run(ctx, func(ctx context.Context) error {
+				if d := mt.Depth(); d != 2 {
+					t.Errorf("expected depth 2 inside the nested unit of work, got %d", d)
+				}
+				return innerErr
+			})
+			if !errors.Is(err, innerErr) {
+				t.Errorf("expected inner error, got %v", err)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := mt.State().Value(); got != "outer committed!" {
+			t.Errorf("expected only the outer commit to change the state, got %q", got)
+		}
+		if want := []string{"ctx", "get", "ctx", "rollback", "commit"}; !reflect.DeepEqual(mt.Ops(), want) {
+			t.Errorf("expected calls %v, got %v", want, mt.Ops())
+		}
+		if d := mt.Depth(); d != 0 {
+			t.Errorf("expected depth 0 after Run, got %d", d)
+		}
+	})
+
+	t.Run("nested_success", func(t *testing.T) {
+		mt := NewMockTx()
+		u := New(mt)
+		err := u.Run(context.Background(), func(ctx context.Context) error {
+			return u.Run(ctx, func(ctx context.Context) error {
+				return u.Run(ctx, func(ctx context.Context) error {
+					u.Get(ctx).(*State).SetValue("inner")
+					if d := mt.Depth(); d != 3 {
+						t.Errorf("expected depth 3, got %d", d)
+					}
+					return nil
+				})
+			})
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := mt.State().Value(); got != "inner committed!" {
+			t.Errorf("expected a single commit of the state, got %q", got)
+		}
+		if n := mt.CallCount("commit"); n != 3 {
+			t.Errorf("expected 3 recorded commits, got %d", n)
+		}
+		if d := mt.Depth(); d != 0 {
+			t.Errorf("expected depth 0 after Run, got %d", d)
+		}
+	})
+}
+
+// TestMockTx_RollbackAfterFailedCommit verifies that a transaction leaves the
+// depth count once, even when a failed Commit is followed by a Rollback.
+func TestMockTx_RollbackAfterFailedCommit(t *testing.T) {
+	commitErr := errors.New("commit failed")
+	mt := NewMockTx().WithCommitError(commitErr)
+	ctx, err := mt.Ctx(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := mt.Commit(ctx); !errors.Is(err, commitErr) {
+		t.Fatalf("expected commit error, got %v", err)
+	}
+	if err := mt.Rollback(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if d := mt.Depth(); d != 0 {
+		t.Errorf("expected depth 0, got %d", d)
+	}
+}
+
+// TestMockTx_Reset verifies that Reset clears the state and the call log so
+// that the mock can be reused.
+func TestMockTx_Reset(t *testing.T) {
+	mt := NewMockTx()
+	u := New(mt)
+	for _, want := range []string{"first committed!", "second committed!"} {
+		mt.Reset()
+		err := u.Run(context.Background(), func(ctx context.Context) error {
+			state := u.Get(ctx).(*State)
+			state.SetValue(strings.Fields(want)[0])
+			state.Put("key", want)
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := mt.State().Value(); got != want {
+			t.Errorf("expected %q, got %q", want, got)
+		}
+		if got := mt.Ops(); !reflect.DeepEqual(got, []string{"ctx", "get", "commit"}) {
+			t.Errorf("expected only the calls of this run, got %v", got)
+		}
+	}
+
+	mt.Reset()
+	if mt.State().Status() != StatePending || len(mt.State().Data()) != 0 || len(mt.Calls()) != 0 {
+		t.Errorf("expected a pristine mock after Reset, got status %v, data %v and calls %v",
+			mt.State().Status(), mt.State().Data(), mt.Calls())
+	}
+}
+
+// TestMockTx_TransientCommitErrors verifies that Run retries commits failing
+// with a MongoDB transient label and eventually commits.
+func TestMockTx_TransientCommitErrors(t *testing.T) {
+	mt := NewMockTx().WithTransientCommitErrors(2)
+	txs := New(mt, WithMaxRetries(3), WithRetryIf(IsMongoTransient))
+
+	attempts := 0
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		attempts++
+		state := txs.Get(ctx).(*State)
+		state.SetValue("attempt")
+		if attempts == 1 {
+			state.Put("stale", true)
+		}
+		state.Put("key", attempts)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 || mt.CallCount("commit") != 3 {
+		t.Errorf("expected 3 attempts and commits, got %d and %d", attempts, mt.CallCount("commit"))
+	}
+	if want := map[string]any{"key": 3}; !reflect.DeepEqual(mt.State().Data(), want) {
+		t.Errorf("expected only the final attempt committed, got %v", mt.State().Data())
+	}
+
+	mt = NewMockTx().WithTransientCommitErrors(1)
+	txs = New(mt)
+	err = txs.Run(context.Background(), func(_ context.Context) error { return nil })
+	var le *MockLabeledError
+	if !IsMongoTransient(err) || !errors.As(err, &le) {
+		t.Errorf("expected a transient labeled error without retries, got %v", err)
+	}
+}
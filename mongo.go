@@ -2,11 +2,30 @@ package uow
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
+	"time"
 
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
 )
 
+// mongoJoinedKey is the context key marking a unit of work that joined the
+// transaction of an enclosing one.
+var mongoJoinedKey = ctxKey{"mongo_joined"}
+
+// mongoWriteConcernKey is the context key for storing the write concern set
+// with RunWithWriteConcern.
+var mongoWriteConcernKey = ctxKey{"mongo_write_concern"}
+
+// mongoDatabaseKey is the context key for storing the database of the active
+// MongoDB transaction.
+var mongoDatabaseKey = ctxKey{"mongo_database"}
+
 // MongoTx implements the Runner interface for MongoDB transactions. It manages
 // the lifecycle of MongoDB sessions and transactions.
 var _ Runner = &MongoTx{}
@@ -15,34 +34,241 @@ var _ Runner = &MongoTx{}
 type MongoTx struct {
 	client *mongo.Client
 	dbName string
+
+	// sizeLimit is the write size limit enforced by TrackMongoWrite; zero
+	// disables tracking.
+	sizeLimit int64
+
+	// txOptions are passed to StartTransaction; nil uses the session
+	// defaults.
+	txOptions *options.TransactionOptions
+
+	// sessionOptions are passed to StartSession; nil uses the client
+	// defaults.
+	sessionOptions *options.SessionOptions
+
+	// sessionOnly starts sessions without transactions.
+	sessionOnly bool
+
+	// pool provides the sessions transactions run on; nil starts a new
+	// session for every transaction.
+	pool MongoSessionPool
+
+	// ender ends sessions in the background; nil ends them synchronously.
+	ender *MongoSessionEnder
+}
+
+// MongoOption configures optional behavior of a MongoTx. Options are passed to
+// NewMongoTx.
+type MongoOption func(*MongoTx)
+
+// WithWriteConcern sets the write concern of every transaction, e.g.
+// writeconcern.Majority().
+func WithWriteConcern(wc *writeconcern.WriteConcern) MongoOption {
+	return func(m *MongoTx) {
+		m.transactionOptions().SetWriteConcern(wc)
+	}
+}
+
+// WithReadConcern sets the read concern of every transaction, e.g.
+// readconcern.Snapshot().
+func WithReadConcern(rc *readconcern.ReadConcern) MongoOption {
+	return func(m *MongoTx) {
+		m.transactionOptions().SetReadConcern(rc)
+	}
+}
+
+// WithReadPreference sets the read preference of every transaction.
+// Transactions that read must use readpref.Primary().
+func WithReadPreference(rp *readpref.ReadPref) MongoOption {
+	return func(m *MongoTx) {
+		m.transactionOptions().SetReadPreference(rp)
+	}
+}
+
+// WithMaxCommitTime bounds the time the server may spend committing every
+// transaction, which maps to maxTimeMS of the commitTransaction command, so a
+// commit waiting e.g. for write concern acknowledgement does not hang. A commit
+// exceeding it fails with a MaxTimeMSExpired error, whose outcome is unknown:
+// Commit retries it like other unknown commit results, and once those retries
+// are exhausted returns it labeled "UnknownTransactionCommitResult", so that
+// WithRetryIf(IsMongoTransient) retries the unit of work. A timeout set on the
+// client takes precedence.
+func WithMaxCommitTime(d time.Duration) MongoOption {
+	return func(m *MongoTx) {
+		m.transactionOptions().SetMaxCommitTime(&d)
+	}
+}
+
+// WithSessionOptions sets the options every session is started with.
+// Sessions taken from a pool set by WithSessionPool are started by the pool
+// and are not affected.
+func WithSessionOptions(opts *options.SessionOptions) MongoOption {
+	return func(m *MongoTx) {
+		m.sessionOptions = opts
+	}
 }
 
-// NewMongoTx creates a new MongoTx instance. It takes a MongoDB client and
-// database name as arguments. This function should be called to initialize
-// a new transaction with MongoDB.
-func NewMongoTx(client *mongo.Client, dbName string) *MongoTx {
-	return &MongoTx{
+// WithCausalConsistency sets whether sessions are causally consistent, so that
+// reads within a transaction observe the writes made before them in the same
+// session. MongoDB enables causal consistency by default.
+func WithCausalConsistency(enabled bool) MongoOption {
+	return func(m *MongoTx) {
+		if m.sessionOptions == nil {
+			m.sessionOptions = options.Session()
+		}
+		m.sessionOptions.SetCausalConsistency(enabled)
+	}
+}
+
+// WithSessionOnly makes Ctx start only a session, without a transaction, and
+// Commit and Rollback only end it. Reads remain causally consistent within the
+// unit of work, which suits read workloads on deployments that do not support
+// multi-document transactions, such as standalone servers. Writes are applied
+// immediately and are not undone by Rollback. The transaction options set by
+// WithWriteConcern, WithReadConcern and WithReadPreference are ignored.
+func WithSessionOnly() MongoOption {
+	return func(m *MongoTx) {
+		m.sessionOnly = true
+	}
+}
+
+// RunWithWriteConcern is Run with wc as the write concern of the MongoDB
+// transaction, overriding the one set with WithWriteConcern for this unit of
+// work only, e.g. to commit with writeconcern.Majority() where durability
+// matters most. MongoDB applies the write concern of a transaction when it
+// commits. Runners other than MongoTx ignore it.
+func (u *UoW) RunWithWriteConcern(ctx context.Context, wc *writeconcern.WriteConcern, fn func(ctx context.Context) error, opts ...RunOption) error {
+	return u.Run(context.WithValue(ctx, mongoWriteConcernKey, wc), fn, opts...)
+}
+
+// transactionOptions returns the transaction options, creating them on first
+// use.
+func (m *MongoTx) transactionOptions() *options.TransactionOptions {
+	if m.txOptions == nil {
+		m.txOptions = options.Transaction()
+	}
+	return m.txOptions
+}
+
+// NewMongoTx creates a new MongoTx instance. It takes a MongoDB client,
+// database name and optional settings as arguments. This function should be
+// called to initialize a new transaction with MongoDB.
+func NewMongoTx(client *mongo.Client, dbName string, opts ...MongoOption) *MongoTx {
+	m := &MongoTx{
 		client: client,
 		dbName: dbName,
 	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
 }
 
 // Ctx starts a new MongoDB transaction. It uses the provided context and
-// starts a new session and transaction within that session. If any errors
-// occur during this process, they are wrapped and returned. This function
-// is crucial for initiating transactions in the context.
+// starts a new session, or takes one from the pool set by WithSessionPool,
+// and starts a transaction within that session, with the options set by
+// WithWriteConcern, WithReadConcern and WithReadPreference. With
+// WithSessionOnly no transaction is started on the session. If any errors
+// occur during this process, they are wrapped and returned; when ctx is done
+// before the transaction has started, no session is left open and the error
+// says so. This function is crucial for initiating transactions in the
+// context.
+//
+// When ctx already carries a session, e.g. because a service method running
+// in a unit of work calls another one, the existing transaction is joined
+// instead: Commit and Rollback of the inner unit of work are no-ops and the
+// outermost unit of work decides the outcome. An inner failure is returned to
+// the outer fn, which must propagate it for the transaction to roll back.
 func (m *MongoTx) Ctx(ctx context.Context) (context.Context, error) {
-	sess, err := m.client.StartSession()
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("context cancelled before transaction start: %w", err)
+	}
+	if sess := mongo.SessionFromContext(ctx); sess != nil {
+		// A unit of work is already running: join its transaction, since
+		// MongoDB does not support nested transactions.
+		ctx = context.WithValue(ctx, mongoDatabaseKey, sess.Client().Database(m.dbName))
+		return context.WithValue(ctx, mongoJoinedKey, sess), nil
+	}
+
+	sess, err := m.startSession(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	err = sess.StartTransaction()
+	if m.sessionOnly {
+		if err := ctx.Err(); err != nil {
+			m.endSession(context.WithoutCancel(ctx), sess)
+			return nil, fmt.Errorf("context cancelled before transaction start: %w", err)
+		}
+		return m.sessionContext(ctx, sess), nil
+	}
+
+	var txOptions []*options.TransactionOptions
+	if IsReadOnly(ctx) {
+		txOptions = append(txOptions, options.Transaction().SetReadConcern(readconcern.Snapshot()))
+	}
+	if m.txOptions != nil {
+		txOptions = append(txOptions, m.txOptions)
+	}
+	if wc, ok := ctx.Value(mongoWriteConcernKey).(*writeconcern.WriteConcern); ok {
+		// Later options take precedence, so the override wins over
+		// WithWriteConcern.
+		txOptions = append(txOptions, options.Transaction().SetWriteConcern(wc))
+	}
+	err = sess.StartTransaction(txOptions...)
 	if err != nil {
-		sess.EndSession(ctx)
+		m.endSession(ctx, sess)
 		return nil, fmt.Errorf("error in starting transaction: %w", err)
 	}
-	return mongo.NewSessionContext(ctx, sess), nil
+	if err := ctx.Err(); err != nil {
+		// The context ended while the transaction was being started; fn would
+		// fail on it, so release the session right away.
+		_ = sess.AbortTransaction(context.WithoutCancel(ctx))
+		m.endSession(context.WithoutCancel(ctx), sess)
+		return nil, fmt.Errorf("context cancelled before transaction start: %w", err)
+	}
+	return m.sessionContext(ctx, sess), nil
+}
+
+// sessionContext returns a context carrying sess and the values the unit of
+// work running on it needs.
+func (m *MongoTx) sessionContext(ctx context.Context, sess mongo.Session) context.Context {
+	if m.sizeLimit > 0 {
+		ctx = context.WithValue(ctx, mongoSizeKey, &sizeTracker{limit: m.sizeLimit})
+	}
+	ctx = context.WithValue(ctx, mongoDatabaseKey, sess.Client().Database(m.dbName))
+	return mongo.NewSessionContext(ctx, sess)
+}
+
+// MongoDatabase returns the database of the MongoDB transaction active in ctx.
+// Unlike MongoTx.Get it needs no UoW or runner, which suits repository code
+// that only receives the context. It returns false when ctx carries no active
+// Mongo session.
+func MongoDatabase(ctx context.Context) (*mongo.Database, bool) {
+	if mongo.SessionFromContext(ctx) == nil {
+		return nil, false
+	}
+	db, ok := ctx.Value(mongoDatabaseKey).(*mongo.Database)
+	return db, ok
+}
+
+// MongoCollection returns the named collection of the database of the MongoDB
+// transaction active in ctx, configured with opts, sparing repositories the
+// lookup of the database with MongoDatabase. It returns false when ctx
+// carries no active Mongo session.
+func MongoCollection(ctx context.Context, name string, opts ...*options.CollectionOptions) (*mongo.Collection, bool) {
+	db, ok := MongoDatabase(ctx)
+	if !ok {
+		return nil, false
+	}
+	return db.Collection(name, opts...), true
+}
+
+// String identifies the runner and its database, e.g. in the error returned
+// when a transaction cannot be started.
+func (m *MongoTx) String() string {
+	return fmt.Sprintf("%s(database=%s)", RunnerName(m), m.dbName)
 }
 
 // Get retrieves the MongoDB database. It checks if a session is present in the
@@ -57,27 +283,185 @@ func (m *MongoTx) Get(ctx context.Context) any {
 	return m.client.Database(m.dbName)
 }
 
+// MongoSession returns the MongoDB session of the unit of work ctx belongs to,
+// e.g. to inspect its cluster or operation time. It returns false when ctx
+// carries no session. The transaction on the session is managed by the
+// runner: do not commit, abort or end it.
+func MongoSession(ctx context.Context) (mongo.Session, bool) {
+	sess := mongo.SessionFromContext(ctx)
+	return sess, sess != nil
+}
+
+// RunTransaction runs fn in a transaction using the driver's
+// mongo.Session.WithTransaction instead of the manual start and commit done
+// by Ctx and Commit. The driver retries the whole transaction, including fn,
+// on errors labeled "TransientTransactionError" and retries the commit on
+// errors labeled "UnknownTransactionCommitResult", until they succeed or 120
+// seconds have passed. fn must therefore be safe to run several times.
+//
+// Unlike UoW.Run, RunTransaction does not apply the options of a UoW, such
+// as hooks, retries, logging or tracing. fn receives a context carrying the
+// session, so Get, MongoDatabase and MongoSession work inside it. As with
+// Run, returning ErrAbort from fn aborts the transaction and RunTransaction
+// returns nil.
+func (m *MongoTx) RunTransaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	sess, err := m.startSession(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start session: %w", err)
+	}
+	defer m.endSession(context.WithoutCancel(ctx), sess)
+
+	_, err = sess.WithTransaction(ctx, func(sc mongo.SessionContext) (any, error) {
+		txCtx := context.WithValue(sc, mongoDatabaseKey, sess.Client().Database(m.dbName))
+		if m.sizeLimit > 0 {
+			txCtx = context.WithValue(txCtx, mongoSizeKey, &sizeTracker{limit: m.sizeLimit})
+		}
+		return nil, fn(txCtx)
+	}, m.txOptions)
+	if errors.Is(err, ErrAbort) {
+		return nil
+	}
+	return err
+}
+
+// GetStrict retrieves the MongoDB database of the active transaction, like
+// Get, but returns ErrNoTransaction instead of falling back to the
+// non-transactional database when ctx carries no session, and ErrFinished
+// when the unit of work ctx belongs to has already ended. Repository code can
+// use it to catch calls made outside UoW.Run.
+func (m *MongoTx) GetStrict(ctx context.Context) (any, error) {
+	sess := mongo.SessionFromContext(ctx)
+	if sess == nil {
+		return nil, fmt.Errorf("database %s: %w", m.dbName, ErrNoTransaction)
+	}
+	if runFinished(ctx) {
+		return nil, fmt.Errorf("database %s: %w", m.dbName, ErrFinished)
+	}
+	return sess.Client().Database(m.dbName), nil
+}
+
 // Rollback aborts the current transaction. It checks for the presence of a
 // session in the context and aborts the transaction if one exists. The session
-// is then ended. This function is essential for handling transaction failures.
+// is then ended, or given back to the session pool. This function is
+// essential for handling transaction failures. In a unit of work that joined
+// an enclosing transaction it does nothing. With WithSessionOnly it only ends
+// the session.
 func (m *MongoTx) Rollback(ctx context.Context) error {
 	sess := mongo.SessionFromContext(ctx)
-	if sess != nil {
-		defer sess.EndSession(ctx)
+	if sess != nil && !joined(ctx, sess) {
+		defer m.endSession(ctx, sess)
+		if m.sessionOnly {
+			return nil
+		}
 		return sess.AbortTransaction(ctx)
 	}
 	return nil
 }
 
 // Commit commits the current transaction. It checks for the presence of a
-// session in the context and commits the transaction if one exists. The session
-// is then ended. This function is crucial for saving changes made within a
-// transaction.
+// session in the context and commits the transaction if one exists. A commit
+// that fails with the "UnknownTransactionCommitResult" label may or may not
+// have been applied and is retried, up to mongoCommitRetries times; commit is
+// idempotent, so this never applies the transaction twice. The session is then
+// ended, or given back to the session pool, exactly once, whatever the
+// outcome. A commit exceeding the limit set with WithMaxCommitTime is treated
+// as an unknown result as well. In a unit of work that joined an enclosing
+// transaction it does nothing. With WithSessionOnly it only ends the session.
 func (m *MongoTx) Commit(ctx context.Context) error {
 	sess := mongo.SessionFromContext(ctx)
-	if sess != nil {
-		defer sess.EndSession(ctx)
-		return sess.CommitTransaction(ctx)
+	if sess != nil && !joined(ctx, sess) {
+		defer m.endSession(context.WithoutCancel(ctx), sess)
+		if m.sessionOnly {
+			return nil
+		}
+		err := commitWithRetry(ctx, sess.CommitTransaction)
+		if isMaxTimeExpired(err) {
+			return &unknownCommitResultError{err: err}
+		}
+		return err
 	}
 	return nil
 }
+
+// mongoCommitRetries is the number of times a commit with an unknown result
+// is retried.
+const mongoCommitRetries = 3
+
+// commitWithRetry calls commit and calls it again while it fails with the
+// "UnknownTransactionCommitResult" label, up to mongoCommitRetries times or
+// until ctx is done.
+func commitWithRetry(ctx context.Context, commit func(context.Context) error) error {
+	err := commit(ctx)
+	for retry := 0; retry < mongoCommitRetries && isUnknownCommitResult(err) && ctx.Err() == nil; retry++ {
+		err = commit(ctx)
+	}
+	return err
+}
+
+// isUnknownCommitResult reports whether err carries the MongoDB
+// "UnknownTransactionCommitResult" label or is a commit that exceeded the
+// limit set with WithMaxCommitTime, whose outcome is unknown as well.
+func isUnknownCommitResult(err error) bool {
+	var le mongo.LabeledError
+	return errors.As(err, &le) && le.HasErrorLabel("UnknownTransactionCommitResult") || isMaxTimeExpired(err)
+}
+
+// unknownCommitResultError labels a commit that exceeded the limit set with
+// WithMaxCommitTime with "UnknownTransactionCommitResult", so that
+// IsMongoTransient accepts it.
+type unknownCommitResultError struct {
+	err error
+}
+
+// Error implements the error interface.
+func (e *unknownCommitResultError) Error() string {
+	return e.err.Error()
+}
+
+// Unwrap returns the commit error.
+func (e *unknownCommitResultError) Unwrap() error {
+	return e.err
+}
+
+// HasErrorLabel implements mongo.LabeledError.
+func (e *unknownCommitResultError) HasErrorLabel(label string) bool {
+	if label == "UnknownTransactionCommitResult" {
+		return true
+	}
+	var le mongo.LabeledError
+	return errors.As(e.err, &le) && le.HasErrorLabel(label)
+}
+
+// isMaxTimeExpired reports whether err is a MongoDB MaxTimeMSExpired error,
+// returned by the server itself or as a write concern error.
+func isMaxTimeExpired(err error) bool {
+	var ce mongo.CommandError
+	if errors.As(err, &ce) && ce.IsMaxTimeMSExpiredError() {
+		return true
+	}
+	var we mongo.WriteException
+	return errors.As(err, &we) && we.WriteConcernError != nil && we.WriteConcernError.IsMaxTimeMSExpiredError()
+}
+
+// Close closes the session pool set by WithSessionPool if the pool implements
+// io.Closer, ending its idle sessions, and the ender set by
+// WithAsyncSessionEnd, waiting for its queued sessions to be ended. The client
+// is left connected, and a session passed to WithSession is left open. Close
+// should be called once no unit of work uses the runner anymore.
+func (m *MongoTx) Close() error {
+	var errs []error
+	if c, ok := m.pool.(io.Closer); ok {
+		errs = append(errs, c.Close())
+	}
+	if m.ender != nil {
+		errs = append(errs, m.ender.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// joined reports whether ctx belongs to a unit of work that joined the
+// transaction of sess instead of starting it.
+func joined(ctx context.Context, sess mongo.Session) bool {
+	outer, ok := ctx.Value(mongoJoinedKey).(mongo.Session)
+	return ok && outer == sess
+}
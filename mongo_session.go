@@ -0,0 +1,234 @@
+package uow
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// MongoSessionPool hands out MongoDB sessions to MongoTx, which starts one
+// transaction at a time on each of them. A pool must never hand out a session
+// that has been acquired and not yet released, so that concurrent units of
+// work never share a session.
+type MongoSessionPool interface {
+	// Acquire returns a session that is not in use, waiting for one if
+	// necessary until ctx is done.
+	Acquire(ctx context.Context) (mongo.Session, error)
+	// Release gives back a session obtained from Acquire once its transaction
+	// has been committed or aborted. The pool decides whether to keep it or
+	// end it.
+	Release(sess mongo.Session)
+}
+
+// WithSessionPool makes MongoTx take sessions from pool instead of starting a
+// new session for every transaction, and give them back instead of ending
+// them.
+func WithSessionPool(pool MongoSessionPool) MongoOption {
+	return func(m *MongoTx) {
+		m.pool = pool
+	}
+}
+
+// WithSession makes MongoTx run every transaction on sess. Transactions
+// started while sess is in use wait for it to be released, so concurrent
+// units of work are serialized rather than sharing the session. The caller
+// remains responsible for ending sess.
+func WithSession(sess mongo.Session) MongoOption {
+	// A cache holding sess and no free slot never starts a session.
+	cache := &MongoSessionCache{idle: make(chan mongo.Session, 1), slots: make(chan struct{})}
+	cache.idle <- sess
+	return WithSessionPool(borrowedSession{cache: cache})
+}
+
+// borrowedSession is the pool set by WithSession. It does not implement
+// io.Closer, so closing the runner leaves the caller's session open.
+type borrowedSession struct {
+	cache *MongoSessionCache
+}
+
+// Acquire waits for the session to be released.
+func (b borrowedSession) Acquire(ctx context.Context) (mongo.Session, error) {
+	return b.cache.Acquire(ctx)
+}
+
+// Release makes the session available again.
+func (b borrowedSession) Release(sess mongo.Session) {
+	b.cache.Release(sess)
+}
+
+// MongoSessionCache is a MongoSessionPool that keeps up to a fixed number of
+// sessions of a client for reuse. Sessions are started on demand, and
+// Acquire waits while all of them are in use.
+var _ MongoSessionPool = &MongoSessionCache{}
+
+// MongoSessionCache struct holds the client, the idle sessions and the free
+// slots for new sessions.
+type MongoSessionCache struct {
+	client *mongo.Client
+	idle   chan mongo.Session
+	slots  chan struct{}
+}
+
+// NewMongoSessionCache creates a MongoSessionCache holding at most size
+// sessions started on client. A size below 1 is treated as 1. Call Close to
+// end the idle sessions once the pool is no longer used; closing a MongoTx
+// using the pool does so as well.
+func NewMongoSessionCache(client *mongo.Client, size int) *MongoSessionCache {
+	if size < 1 {
+		size = 1
+	}
+	p := &MongoSessionCache{
+		client: client,
+		idle:   make(chan mongo.Session, size),
+		slots:  make(chan struct{}, size),
+	}
+	for i := 0; i < size; i++ {
+		p.slots <- struct{}{}
+	}
+	return p
+}
+
+// Acquire returns an idle session, or starts a new one while the pool holds
+// fewer than its size. Otherwise it waits for a session to be released or
+// for ctx to be done.
+func (p *MongoSessionCache) Acquire(ctx context.Context) (mongo.Session, error) {
+	select {
+	case sess := <-p.idle:
+		return sess, nil
+	default:
+	}
+	select {
+	case sess := <-p.idle:
+		return sess, nil
+	case <-p.slots:
+		sess, err := p.client.StartSession()
+		if err != nil {
+			p.slots <- struct{}{}
+			return nil, err
+		}
+		return sess, nil
+	case <-ctx.Done():
+		return nil, fmt.Errorf("error in waiting for session: %w", ctx.Err())
+	}
+}
+
+// Release makes sess available to the next Acquire.
+func (p *MongoSessionCache) Release(sess mongo.Session) {
+	p.idle <- sess
+}
+
+// Close ends the idle sessions. Sessions still in use are not affected.
+func (p *MongoSessionCache) Close() error {
+	for {
+		select {
+		case sess := <-p.idle:
+			sess.EndSession(context.Background())
+		default:
+			return nil
+		}
+	}
+}
+
+// startSession returns a session for a new transaction, from the pool if one
+// is configured.
+func (m *MongoTx) startSession(ctx context.Context) (mongo.Session, error) {
+	if m.pool != nil {
+		return m.pool.Acquire(ctx)
+	}
+	if m.sessionOptions != nil {
+		return m.client.StartSession(m.sessionOptions)
+	}
+	return m.client.StartSession()
+}
+
+// endSession ends sess, or gives it back to the pool if one is configured. With
+// WithAsyncSessionEnd the session is handed to the ender instead.
+func (m *MongoTx) endSession(ctx context.Context, sess mongo.Session) {
+	if m.pool != nil {
+		m.pool.Release(sess)
+		return
+	}
+	if m.ender != nil {
+		m.ender.end(sess)
+		return
+	}
+	sess.EndSession(ctx)
+}
+
+// WithAsyncSessionEnd makes MongoTx hand sessions to ender once their
+// transaction has been committed or aborted, instead of ending them on the
+// request path, so that Commit returns as soon as the transaction is
+// committed. Sessions taken from a pool set by WithSessionPool are given back
+// to the pool instead. Closing the MongoTx closes the ender.
+func WithAsyncSessionEnd(ender *MongoSessionEnder) MongoOption {
+	return func(m *MongoTx) {
+		m.ender = ender
+	}
+}
+
+// MongoSessionEnder ends MongoDB sessions on background workers. Its queue is
+// bounded: when it is full, handing over a session blocks until a worker
+// frees a place, so a slow server slows down commits instead of letting
+// sessions pile up. An ender may be shared by several MongoTx.
+type MongoSessionEnder struct {
+	queue chan mongo.Session
+	wg    sync.WaitGroup
+
+	// mu guards closed; it is held for reading while a session is queued so
+	// that Close never closes the queue under a sender.
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewMongoSessionEnder creates a MongoSessionEnder with the given number of
+// workers and queue size and starts the workers. Values below 1 are treated
+// as 1. Call Close to end the queued sessions and stop the workers.
+func NewMongoSessionEnder(workers, queueSize int) *MongoSessionEnder {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < 1 {
+		queueSize = 1
+	}
+	e := &MongoSessionEnder{
+		queue: make(chan mongo.Session, queueSize),
+	}
+	e.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer e.wg.Done()
+			for sess := range e.queue {
+				sess.EndSession(context.Background())
+			}
+		}()
+	}
+	return e
+}
+
+// end queues sess to be ended, waiting while the queue is full. Once the
+// ender is closed, sess is ended right away.
+func (e *MongoSessionEnder) end(sess mongo.Session) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.closed {
+		sess.EndSession(context.Background())
+		return
+	}
+	e.queue <- sess
+}
+
+// Close stops accepting sessions, waits until the queued ones have been ended
+// and stops the workers. Sessions handed over afterwards are ended
+// synchronously. Close is safe to call more than once.
+func (e *MongoSessionEnder) Close() error {
+	e.mu.Lock()
+	if !e.closed {
+		e.closed = true
+		close(e.queue)
+	}
+	e.mu.Unlock()
+	e.wg.Wait()
+	return nil
+}
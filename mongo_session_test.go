@@ -0,0 +1,255 @@
+package uow
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/session"
+)
+
+// TestMongoTx_SessionPool verifies that sessions are reused across units of
+// work and that concurrent units of work never share one.
+func TestMongoTx_SessionPool(t *testing.T) {
+	client := newLazyMongoClient(t)
+	pool := NewMongoSessionCache(client, 2)
+	t.Cleanup(func() { _ = pool.Close() })
+	txs := New(NewMongoTx(client, "test", WithSessionPool(pool)))
+
+	var first mongo.Session
+	for i := 0; i < 2; i++ {
+		err := txs.Run(context.Background(), func(ctx context.Context) error {
+			sess := mongo.SessionFromContext(ctx)
+			if first == nil {
+				first = sess
+			} else if sess != first {
+				t.Error("expected the session to be reused")
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if n := client.NumberSessionsInProgress(); n != 1 {
+		t.Errorf("expected the pooled session to stay open, got %d sessions", n)
+	}
+
+	var mu sync.Mutex
+	inUse := make(map[mongo.Session]bool)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := txs.Run(context.Background(), func(ctx context.Context) error {
+				sess := mongo.SessionFromContext(ctx)
+				mu.Lock()
+				if inUse[sess] {
+					t.Error("expected concurrent units of work not to share a session")
+				}
+				inUse[sess] = true
+				mu.Unlock()
+				time.Sleep(time.Millisecond)
+				mu.Lock()
+				inUse[sess] = false
+				mu.Unlock()
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if n := client.NumberSessionsInProgress(); n > 2 {
+		t.Errorf("expected at most 2 sessions, got %d", n)
+	}
+
+	if err := pool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := client.NumberSessionsInProgress(); n != 0 {
+		t.Errorf("expected Close to end the idle sessions, got %d sessions", n)
+	}
+}
+
+// TestMongoTx_WithSession verifies that a single injected session serializes
+// units of work and is left open.
+func TestMongoTx_WithSession(t *testing.T) {
+	client := newLazyMongoClient(t)
+	sess, err := client.StartSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sess.EndSession(context.Background()) })
+	txs := New(NewMongoTx(client, "test", WithSession(sess)))
+
+	var active, maxActive int32
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := txs.Run(context.Background(), func(ctx context.Context) error {
+				if mongo.SessionFromContext(ctx) != sess {
+					t.Error("expected the injected session")
+				}
+				n := atomic.AddInt32(&active, 1)
+				defer atomic.AddInt32(&active, -1)
+				if n > atomic.LoadInt32(&maxActive) {
+					atomic.StoreInt32(&maxActive, n)
+				}
+				time.Sleep(time.Millisecond)
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if maxActive != 1 {
+		t.Errorf("expected units of work to be serialized, got %d at once", maxActive)
+	}
+	if n := client.NumberSessionsInProgress(); n != 1 {
+		t.Errorf("expected the injected session to stay open, got %d sessions", n)
+	}
+}
+
+// TestMongoTx_SessionOptions verifies that sessions are started with the
+// configured causal consistency.
+func TestMongoTx_SessionOptions(t *testing.T) {
+	client := newLazyMongoClient(t)
+	for _, enabled := range []bool{true, false} {
+		txs := New(NewMongoTx(client, "test", WithCausalConsistency(enabled)))
+		err := txs.Run(context.Background(), func(ctx context.Context) error {
+			sess, ok := mongo.SessionFromContext(ctx).(interface{ ClientSession() *session.Client })
+			if !ok {
+				t.Fatal("expected the driver session to expose its client session")
+			}
+			if got := sess.ClientSession().Consistent; got != enabled {
+				t.Errorf("expected causal consistency %v, got %v", enabled, got)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestMongoTx_Close verifies that closing the runner ends the sessions of its
+// pool but leaves an injected session open.
+func TestMongoTx_Close(t *testing.T) {
+	client := newLazyMongoClient(t)
+	u := New(NewMongoTx(client, "test", WithSessionPool(NewMongoSessionCache(client, 1))))
+	if err := u.Run(context.Background(), func(_ context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := client.NumberSessionsInProgress(); n != 0 {
+		t.Errorf("expected Close to end the pooled sessions, got %d sessions", n)
+	}
+
+	sess, err := client.StartSession()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { sess.EndSession(context.Background()) })
+	u = New(NewMongoTx(client, "test", WithSession(sess)))
+	if err := u.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := client.NumberSessionsInProgress(); n != 1 {
+		t.Errorf("expected the injected session to stay open, got %d sessions", n)
+	}
+}
+
+// TestMongoTx_SessionOnly verifies that WithSessionOnly provides a session
+// without a transaction and ends it on commit and rollback.
+func TestMongoTx_SessionOnly(t *testing.T) {
+	client := newLazyMongoClient(t)
+	txs := New(NewMongoTx(client, "test", WithSessionOnly()))
+
+	for _, fnErr := range []error{nil, ErrAbort} {
+		err := txs.Run(context.Background(), func(ctx context.Context) error {
+			sess, ok := mongo.SessionFromContext(ctx).(interface{ ClientSession() *session.Client })
+			if !ok {
+				t.Fatal("expected the driver session to expose its client session")
+			}
+			if sess.ClientSession().TransactionRunning() {
+				t.Error("expected no transaction on the session")
+			}
+			if _, ok := MongoDatabase(ctx); !ok {
+				t.Error("expected the database in the context")
+			}
+			return fnErr
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n := client.NumberSessionsInProgress(); n != 0 {
+			t.Errorf("expected the session to be ended, got %d sessions", n)
+		}
+	}
+}
+
+// TestMongoTx_AsyncSessionEnd verifies that sessions are ended by the ender
+// and that closing the runner drains its queue.
+func TestMongoTx_AsyncSessionEnd(t *testing.T) {
+	client := newLazyMongoClient(t)
+	m := NewMongoTx(client, "test", WithAsyncSessionEnd(NewMongoSessionEnder(2, 4)))
+	txs := New(m)
+
+	for i := 0; i < 16; i++ {
+		if err := txs.Run(context.Background(), func(_ context.Context) error { return nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n := client.NumberSessionsInProgress(); n != 0 {
+		t.Errorf("expected Close to end the queued sessions, got %d sessions", n)
+	}
+
+	// Sessions handed over after Close are ended right away.
+	if err := txs.Run(context.Background(), func(_ context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if n := client.NumberSessionsInProgress(); n != 0 {
+		t.Errorf("expected the session to be ended, got %d sessions", n)
+	}
+}
+
+// BenchmarkMongoTx_EndSession compares ending sessions on the request path
+// with handing them to a MongoSessionEnder. Without a server ending a session
+// sends no command, so this measures the overhead of the handover; against a
+// real deployment the async variant also saves the endSessions round-trip.
+func BenchmarkMongoTx_EndSession(b *testing.B) {
+	client := newLazyMongoClient(b)
+	for _, bm := range []struct {
+		name string
+		opts []MongoOption
+	}{
+		{name: "sync"},
+		{name: "async", opts: []MongoOption{WithAsyncSessionEnd(NewMongoSessionEnder(4, 64))}},
+	} {
+		b.Run(bm.name, func(b *testing.B) {
+			m := NewMongoTx(client, "test", bm.opts...)
+			b.Cleanup(func() { _ = m.Close() })
+			txs := New(m)
+			for i := 0; i < b.N; i++ {
+				if err := txs.Run(context.Background(), func(_ context.Context) error { return nil }); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
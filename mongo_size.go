@@ -0,0 +1,80 @@
+package uow
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// mongoSizeKey is the context key for storing the write size tracker.
+var mongoSizeKey = ctxKey{"mongo_size"}
+
+// MongoMaxTxSize is the 16MB limit MongoDB imposes on the oplog entry of a
+// transaction, which bounds the total size of the writes it contains.
+const MongoMaxTxSize int64 = 16 * 1024 * 1024
+
+// TxSizeError is returned by TrackMongoWrite when the estimated size of the
+// writes in a transaction would exceed the configured limit.
+type TxSizeError struct {
+	// Size is the estimated size in bytes the writes would reach.
+	Size int64
+
+	// Limit is the configured limit in bytes.
+	Limit int64
+}
+
+// Error implements the error interface.
+func (e *TxSizeError) Error() string {
+	return fmt.Sprintf("transaction writes would reach about %d bytes, exceeding the limit of %d bytes; "+
+		"split the documents into smaller chunks written in separate transactions", e.Size, e.Limit)
+}
+
+// sizeTracker accumulates the estimated size of the writes in a transaction.
+type sizeTracker struct {
+	mu    sync.Mutex
+	size  int64
+	limit int64
+}
+
+// WithTxSizeLimit enables monitoring of the accumulated size of the writes in
+// each transaction. Repositories report their writes with TrackMongoWrite,
+// which fails with a *TxSizeError before the limit is crossed, so that the
+// transaction rolls back with a descriptive error instead of failing hard at
+// commit. The limit should stay below MongoMaxTxSize to leave room for
+// operation overhead.
+func WithTxSizeLimit(bytes int64) MongoOption {
+	return func(m *MongoTx) {
+		m.sizeLimit = bytes
+	}
+}
+
+// TrackMongoWrite estimates the BSON size of docs about to be written in the
+// transaction that ctx belongs to and adds it to the running total. It returns
+// a *TxSizeError, without counting the documents, when the total would exceed
+// the limit configured with WithTxSizeLimit. It is a no-op when size tracking
+// is not enabled.
+func TrackMongoWrite(ctx context.Context, docs ...any) error {
+	tracker, ok := ctx.Value(mongoSizeKey).(*sizeTracker)
+	if !ok {
+		return nil
+	}
+
+	var size int64
+	for _, doc := range docs {
+		raw, err := bson.Marshal(doc)
+		if err != nil {
+			return fmt.Errorf("error in estimating document size: %w", err)
+		}
+		size += int64(len(raw))
+	}
+
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	if tracker.size+size > tracker.limit {
+		return &TxSizeError{Size: tracker.size + size, Limit: tracker.limit}
+	}
+	tracker.size += size
+	return nil
+}
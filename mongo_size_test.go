@@ -0,0 +1,59 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TestTrackMongoWrite verifies that accumulated writes fail with a helpful
+// error before the limit is crossed, rolling the transaction back.
+func TestTrackMongoWrite(t *testing.T) {
+	client := newLazyMongoClient(t)
+	mongoTx := NewMongoTx(client, "test", WithTxSizeLimit(4096))
+	txs := New(mongoTx)
+
+	chunk := bson.M{"payload": strings.Repeat("x", 1000)}
+	writes := 0
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		for i := 0; i < 10; i++ {
+			if err := TrackMongoWrite(ctx, chunk); err != nil {
+				return err
+			}
+			writes++
+		}
+		return nil
+	})
+
+	var sizeErr *TxSizeError
+	if !errors.As(err, &sizeErr) {
+		t.Fatalf("expected *TxSizeError, got %v", err)
+	}
+	if writes != 4 {
+		t.Errorf("expected the fifth write to be rejected, got %d accepted writes", writes)
+	}
+	if sizeErr.Limit != 4096 || sizeErr.Size <= 4096 {
+		t.Errorf("unexpected size error %+v", sizeErr)
+	}
+	if !strings.Contains(err.Error(), "smaller chunks") {
+		t.Errorf("expected error to suggest chunking, got %q", err)
+	}
+	if n := client.NumberSessionsInProgress(); n != 0 {
+		t.Errorf("expected session to be ended by rollback, got %d in progress", n)
+	}
+}
+
+// TestTrackMongoWrite_Disabled verifies that tracking is a no-op unless
+// enabled.
+func TestTrackMongoWrite_Disabled(t *testing.T) {
+	txs := New(NewMongoTx(newLazyMongoClient(t), "test"))
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		return TrackMongoWrite(ctx, bson.M{"payload": strings.Repeat("x", 1<<20)})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,370 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readconcern"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.mongodb.org/mongo-driver/mongo/writeconcern"
+	"go.mongodb.org/mongo-driver/x/mongo/driver/session"
+)
+
+// newLazyMongoClient returns a client that never connects to a server.
+// Sessions and transactions can be started and aborted on it as long as no
+// operation is sent, which is enough to unit-test MongoTx.
+func newLazyMongoClient(t testing.TB) *mongo.Client {
+	t.Helper()
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://localhost:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+	return client
+}
+
+// TestMongoTx_TransactionOptions verifies that the concern and preference
+// options are collected into the transaction options and that transactions
+// can be started with them.
+func TestMongoTx_TransactionOptions(t *testing.T) {
+	client := newLazyMongoClient(t)
+
+	if m := NewMongoTx(client, "test"); m.txOptions != nil {
+		t.Errorf("expected no transaction options by default, got %+v", m.txOptions)
+	}
+
+	m := NewMongoTx(client, "test",
+		WithWriteConcern(writeconcern.Majority()),
+		WithReadConcern(readconcern.Snapshot()),
+		WithReadPreference(readpref.Primary()),
+	)
+	if wc := m.txOptions.WriteConcern; wc == nil || wc.W != "majority" {
+		t.Errorf("expected majority write concern, got %+v", wc)
+	}
+	if rc := m.txOptions.ReadConcern; rc == nil || rc.Level != "snapshot" {
+		t.Errorf("expected snapshot read concern, got %+v", rc)
+	}
+	if rp := m.txOptions.ReadPreference; rp == nil || rp.Mode() != readpref.PrimaryMode {
+		t.Errorf("expected primary read preference, got %+v", rp)
+	}
+
+	ctx, err := m.Ctx(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Rollback(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestMongoTx_CancelledContext verifies that an already cancelled context is
+// rejected with a clear error and without leaking a session.
+func TestMongoTx_CancelledContext(t *testing.T) {
+	client := newLazyMongoClient(t)
+	txs := New(NewMongoTx(client, "test"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := txs.Run(ctx, func(_ context.Context) error {
+		t.Error("fn must not run with a cancelled context")
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), "context cancelled before transaction start") {
+		t.Errorf("expected a cancelled-before-start error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "on *uow.MongoTx(database=test)") {
+		t.Errorf("expected the error to name the runner and database, got %v", err)
+	}
+	if n := client.NumberSessionsInProgress(); n != 0 {
+		t.Errorf("expected no sessions in progress, got %d", n)
+	}
+}
+
+// TestMongoTx_NestedRun verifies that a nested Run joins the session of the
+// enclosing one and leaves ending it to the outermost unit of work.
+func TestMongoTx_NestedRun(t *testing.T) {
+	client := newLazyMongoClient(t)
+	txs := New(NewMongoTx(client, "test"))
+
+	innerErr := errors.New("inner failed")
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		outer := mongo.SessionFromContext(ctx)
+
+		err := txs.Run(ctx, func(ctx context.Context) error {
+			if mongo.SessionFromContext(ctx) != outer {
+				t.Error("expected the inner unit of work to reuse the outer session")
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		err = txs.Run(ctx, func(_ context.Context) error { return innerErr })
+		if !errors.Is(err, innerErr) {
+			t.Errorf("expected inner error, got %v", err)
+		}
+		if n := client.NumberSessionsInProgress(); n != 1 {
+			t.Errorf("expected the outer session to stay open, got %d sessions", n)
+		}
+		return err
+	})
+	if !errors.Is(err, innerErr) {
+		t.Errorf("expected the inner error to roll back the outer unit of work, got %v", err)
+	}
+	if n := client.NumberSessionsInProgress(); n != 0 {
+		t.Errorf("expected no sessions in progress, got %d", n)
+	}
+}
+
+// TestMongoDatabase verifies that the database of the active transaction can
+// be read from the context alone.
+func TestMongoDatabase(t *testing.T) {
+	client := newLazyMongoClient(t)
+	txs := New(NewMongoTx(client, "test"))
+
+	if _, ok := MongoDatabase(context.Background()); ok {
+		t.Error("expected no database outside a unit of work")
+	}
+
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		db, ok := MongoDatabase(ctx)
+		if !ok {
+			t.Fatal("expected a database inside the unit of work")
+		}
+		if db.Name() != "test" {
+			t.Errorf("expected database %q, got %q", "test", db.Name())
+		}
+		if db.Client() != mongo.SessionFromContext(ctx).Client() {
+			t.Error("expected the database to belong to the session's client")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestMongoCollection verifies that the collection is resolved on the
+// database of the active transaction with the given options.
+func TestMongoCollection(t *testing.T) {
+	client := newLazyMongoClient(t)
+	txs := New(NewMongoTx(client, "test"))
+
+	if _, ok := MongoCollection(context.Background(), "users"); ok {
+		t.Error("expected no collection outside a unit of work")
+	}
+
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		coll, ok := MongoCollection(ctx, "users", options.Collection().SetWriteConcern(writeconcern.Majority()))
+		if !ok {
+			t.Fatal("expected a collection inside the unit of work")
+		}
+		if coll.Name() != "users" || coll.Database().Name() != "test" {
+			t.Errorf("expected test.users, got %s.%s", coll.Database().Name(), coll.Name())
+		}
+		if coll.Database().Client() != mongo.SessionFromContext(ctx).Client() {
+			t.Error("expected the collection to belong to the session's client")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestCommitWithRetry verifies that only commits with an unknown result are
+// retried, and only a bounded number of times.
+func TestCommitWithRetry(t *testing.T) {
+	unknown := &mongo.CommandError{Message: "unknown", Labels: []string{"UnknownTransactionCommitResult"}}
+	transient := &mongo.CommandError{Message: "transient", Labels: []string{"TransientTransactionError"}}
+	maxTime := mongo.CommandError{Code: 50, Name: "MaxTimeMSExpired"}
+
+	tests := []struct {
+		name      string
+		errs      []error
+		wantCalls int
+		wantErr   error
+	}{
+		{name: "success", errs: []error{nil}, wantCalls: 1},
+		{name: "unknown_then_success", errs: []error{unknown, nil}, wantCalls: 2},
+		{name: "unknown_exhausted", errs: []error{unknown, unknown, unknown, unknown, nil}, wantCalls: mongoCommitRetries + 1, wantErr: unknown},
+		{name: "other_error", errs: []error{transient, nil}, wantCalls: 1, wantErr: transient},
+		{name: "max_time_then_success", errs: []error{maxTime, nil}, wantCalls: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := commitWithRetry(context.Background(), func(_ context.Context) error {
+				err := tt.errs[calls]
+				calls++
+				return err
+			})
+			if calls != tt.wantCalls {
+				t.Errorf("expected %d commit calls, got %d", tt.wantCalls, calls)
+			}
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestMongoTx_GetStrict verifies that GetStrict only returns the database
+// inside a transaction.
+func TestMongoTx_GetStrict(t *testing.T) {
+	client := newLazyMongoClient(t)
+	mt := NewMongoTx(client, "test")
+	txs := New(mt)
+
+	if _, err := mt.GetStrict(context.Background()); !errors.Is(err, ErrNoTransaction) {
+		t.Errorf("expected ErrNoTransaction outside a unit of work, got %v", err)
+	}
+
+	var kept context.Context
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		db, err := mt.GetStrict(ctx)
+		if err != nil {
+			return err
+		}
+		if db.(*mongo.Database).Name() != "test" {
+			t.Errorf("expected database %q, got %q", "test", db.(*mongo.Database).Name())
+		}
+		kept = ctx
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := mt.GetStrict(kept); !errors.Is(err, ErrFinished) {
+		t.Errorf("expected ErrFinished after Run, got %v", err)
+	}
+}
+
+// TestMongoSession verifies that the session of the unit of work can be read
+// from the context.
+func TestMongoSession(t *testing.T) {
+	client := newLazyMongoClient(t)
+	txs := New(NewMongoTx(client, "test"))
+
+	if _, ok := MongoSession(context.Background()); ok {
+		t.Error("expected no session outside a unit of work")
+	}
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		sess, ok := MongoSession(ctx)
+		if !ok || sess != mongo.SessionFromContext(ctx) {
+			t.Error("expected the session of the unit of work")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestMongoTx_RunTransaction verifies that RunTransaction passes a
+// transactional context to fn, reports its errors and ends the session.
+func TestMongoTx_RunTransaction(t *testing.T) {
+	client := newLazyMongoClient(t)
+	mt := NewMongoTx(client, "test")
+
+	err := mt.RunTransaction(context.Background(), func(ctx context.Context) error {
+		if _, ok := MongoSession(ctx); !ok {
+			t.Error("expected a session in the context")
+		}
+		if db, ok := MongoDatabase(ctx); !ok || db.Name() != "test" {
+			t.Error("expected the database in the context")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fnErr := errors.New("fn failed")
+	if err := mt.RunTransaction(context.Background(), func(_ context.Context) error { return fnErr }); !errors.Is(err, fnErr) {
+		t.Errorf("expected fn error, got %v", err)
+	}
+	if err := mt.RunTransaction(context.Background(), func(_ context.Context) error { return ErrAbort }); err != nil {
+		t.Errorf("expected nil after an abort, got %v", err)
+	}
+	if n := client.NumberSessionsInProgress(); n != 0 {
+		t.Errorf("expected no sessions in progress, got %d", n)
+	}
+}
+
+// TestRunWithWriteConcern verifies that the write concern overrides the
+// runner's for one unit of work only, and that other runners ignore it.
+func TestRunWithWriteConcern(t *testing.T) {
+	client := newLazyMongoClient(t)
+	txs := New(NewMongoTx(client, "test", WithWriteConcern(writeconcern.W1())))
+
+	currentWc := func(ctx context.Context) *writeconcern.WriteConcern {
+		sess, ok := mongo.SessionFromContext(ctx).(interface{ ClientSession() *session.Client })
+		if !ok {
+			t.Fatal("expected the driver session to expose its client session")
+		}
+		return sess.ClientSession().CurrentWc
+	}
+
+	err := txs.RunWithWriteConcern(context.Background(), writeconcern.Majority(), func(ctx context.Context) error {
+		if wc := currentWc(ctx); wc == nil || wc.W != "majority" {
+			t.Errorf("expected the majority write concern, got %+v", wc)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = txs.Run(context.Background(), func(ctx context.Context) error {
+		if wc := currentWc(ctx); wc == nil || wc.W != 1 {
+			t.Errorf("expected the runner's write concern, got %+v", wc)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u := New(NewMockTx())
+	if err := u.RunWithWriteConcern(context.Background(), writeconcern.Majority(), func(context.Context) error { return nil }); err != nil {
+		t.Errorf("expected other runners to ignore the write concern, got %v", err)
+	}
+}
+
+// TestWithMaxCommitTime verifies that the max commit time is threaded into the
+// started transaction and that an expired commit is labeled retryable.
+func TestWithMaxCommitTime(t *testing.T) {
+	client := newLazyMongoClient(t)
+	txs := New(NewMongoTx(client, "test", WithMaxCommitTime(2*time.Second)))
+
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		sess, ok := mongo.SessionFromContext(ctx).(interface{ ClientSession() *session.Client })
+		if !ok {
+			t.Fatal("expected the driver session to expose its client session")
+		}
+		if mct := sess.ClientSession().CurrentMct; mct == nil || *mct != 2*time.Second {
+			t.Errorf("expected a max commit time of 2s, got %v", mct)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expired := &unknownCommitResultError{err: mongo.CommandError{Code: 50, Name: "MaxTimeMSExpired"}}
+	if !IsMongoTransient(fmt.Errorf("failed to commit transaction: %w", expired)) {
+		t.Error("expected an expired commit to be retryable")
+	}
+	if IsMongoTransient(mongo.CommandError{Code: 50, Name: "MaxTimeMSExpired"}) {
+		t.Error("expected an expired operation outside commit not to be retryable")
+	}
+}
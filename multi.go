@@ -0,0 +1,224 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MultiRunner implements the Runner interface on top of several runners, to
+// run one unit of work against multiple data stores, e.g. MongoDB and Redis.
+// Transactions are started in the order the runners were given, committed in
+// the same order and rolled back in reverse order.
+//
+// The coordination is best effort, not atomic: if committing one runner
+// fails, the runners after it are rolled back, but the runners before it have
+// already committed. Their effects are undone only if they implement
+// Compensator, and a crash or a failing compensation still leaves the stores
+// inconsistent. Put the runner most likely to fail on commit first, and make
+// the effects on later stores idempotent or compensable.
+//
+// The transaction contexts of the runners are merged into one, so every
+// runner's Get works on the context passed to fn. Runners that store their
+// transaction under the same context key, such as two SQLTx instances, cannot
+// be combined.
+var _ Runner = &MultiRunner{}
+
+// MultiRunner struct holds the coordinated runners.
+type MultiRunner struct {
+	runners []Runner
+}
+
+// NewMultiRunner creates a new MultiRunner coordinating runners in the given
+// order.
+func NewMultiRunner(runners ...Runner) *MultiRunner {
+	return &MultiRunner{
+		runners: runners,
+	}
+}
+
+// Compensator is implemented by runners that can undo the effects of a
+// transaction they have already committed. MultiRunner calls Compensate with
+// the transaction context when a runner after it fails to commit. Decorators
+// wrapping a runner that cannot compensate return ErrCompensationUnsupported.
+type Compensator interface {
+	Compensate(ctx context.Context) error
+}
+
+// ErrCompensationUnsupported is returned by the Compensate method of a
+// decorator, such as TimeoutRunner, wrapping a runner that does not implement
+// Compensator.
+var ErrCompensationUnsupported = errors.New("runner does not support compensation")
+
+// OutcomeStatus describes what happened to a runner of a MultiRunner whose
+// commit failed.
+type OutcomeStatus string
+
+const (
+	// OutcomeCommitted means the runner committed and could not be undone
+	// because it does not implement Compensator.
+	OutcomeCommitted OutcomeStatus = "committed"
+
+	// OutcomeCompensated means the runner committed and was compensated.
+	OutcomeCompensated OutcomeStatus = "compensated"
+
+	// OutcomeCompensationFailed means the runner committed and its
+	// compensation failed.
+	OutcomeCompensationFailed OutcomeStatus = "compensation failed"
+
+	// OutcomeCommitFailed means the commit of the runner failed.
+	OutcomeCommitFailed OutcomeStatus = "commit failed"
+
+	// OutcomeRolledBack means the runner was rolled back before committing.
+	OutcomeRolledBack OutcomeStatus = "rolled back"
+
+	// OutcomeRollbackFailed means rolling back the runner failed.
+	OutcomeRollbackFailed OutcomeStatus = "rollback failed"
+)
+
+// MultiOutcome is the outcome of a single runner of a MultiRunner whose
+// commit failed.
+type MultiOutcome struct {
+	// Index is the position of the runner in the MultiRunner.
+	Index int
+
+	// Runner describes the runner.
+	Runner string
+
+	// Status is what happened to the runner.
+	Status OutcomeStatus
+
+	// Err is the error of the failed operation, if any.
+	Err error
+}
+
+// MultiCommitError is returned by MultiRunner.Commit when committing one of
+// its runners failed. It lists the outcome of every runner, so that callers
+// can tell which stores are left committed. The errors of all runners remain
+// reachable via errors.Is and errors.As.
+type MultiCommitError struct {
+	// Failed is the index of the runner whose commit failed.
+	Failed int
+
+	// Outcomes holds the outcome of every runner, in order.
+	Outcomes []MultiOutcome
+}
+
+// Error implements the error interface.
+func (e *MultiCommitError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "error in committing runner %d (%s)", e.Failed, e.Outcomes[e.Failed].Runner)
+	for _, o := range e.Outcomes {
+		fmt.Fprintf(&b, "; runner %d (%s): %s", o.Index, o.Runner, o.Status)
+		if o.Err != nil {
+			fmt.Fprintf(&b, ": %v", o.Err)
+		}
+	}
+	return b.String()
+}
+
+// Unwrap returns the errors of the runners.
+func (e *MultiCommitError) Unwrap() []error {
+	var errs []error
+	for _, o := range e.Outcomes {
+		if o.Err != nil {
+			errs = append(errs, o.Err)
+		}
+	}
+	return errs
+}
+
+// Ctx starts a transaction on every runner in order, each on the context
+// returned by the previous one. When a runner fails to start, the
+// transactions already started are rolled back.
+func (m *MultiRunner) Ctx(ctx context.Context) (context.Context, error) {
+	for i, r := range m.runners {
+		next, err := r.Ctx(ctx)
+		if err != nil {
+			err = fmt.Errorf("error in starting runner %d (%s): %w", i, describeRunner(r), err)
+			return nil, errors.Join(err, m.rollback(ctx, i))
+		}
+		ctx = next
+	}
+	return ctx, nil
+}
+
+// Get returns the values returned by the Get method of every runner, in
+// order, as a []any.
+func (m *MultiRunner) Get(ctx context.Context) any {
+	values := make([]any, len(m.runners))
+	for i, r := range m.runners {
+		values[i] = r.Get(ctx)
+	}
+	return values
+}
+
+// Commit commits the runners in order. When a commit fails, the runners after
+// it are rolled back, in reverse order, and then the runners already committed
+// are compensated, in reverse order, if they implement Compensator. The
+// failed runner is not rolled back, since a failed Commit ends its
+// transaction, as documented on Runner. The returned *MultiCommitError
+// reports the outcome of every runner.
+func (m *MultiRunner) Commit(ctx context.Context) error {
+	for i, r := range m.runners {
+		err := r.Commit(ctx)
+		if err == nil {
+			continue
+		}
+
+		outcomes := make([]MultiOutcome, len(m.runners))
+		for j, r := range m.runners {
+			outcomes[j] = MultiOutcome{Index: j, Runner: describeRunner(r)}
+		}
+		outcomes[i].Status, outcomes[i].Err = OutcomeCommitFailed, err
+		for j := len(m.runners) - 1; j > i; j-- {
+			outcomes[j].Status = OutcomeRolledBack
+			if rbErr := m.runners[j].Rollback(ctx); rbErr != nil {
+				outcomes[j].Status, outcomes[j].Err = OutcomeRollbackFailed, rbErr
+			}
+		}
+		for j := i - 1; j >= 0; j-- {
+			outcomes[j].Status = OutcomeCommitted
+			c, ok := m.runners[j].(Compensator)
+			if !ok || !supports[Compensator](m.runners[j]) {
+				continue
+			}
+			outcomes[j].Status = OutcomeCompensated
+			if cErr := c.Compensate(ctx); cErr != nil {
+				outcomes[j].Status, outcomes[j].Err = OutcomeCompensationFailed, cErr
+			}
+		}
+		return &MultiCommitError{Failed: i, Outcomes: outcomes}
+	}
+	return nil
+}
+
+// Rollback rolls back every runner in reverse order. All runners are rolled
+// back even if some fail; their errors are joined.
+func (m *MultiRunner) Rollback(ctx context.Context) error {
+	return m.rollback(ctx, len(m.runners))
+}
+
+// rollback rolls back the first n runners in reverse order.
+func (m *MultiRunner) rollback(ctx context.Context, n int) error {
+	var errs []error
+	for i := n - 1; i >= 0; i-- {
+		if err := m.runners[i].Rollback(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("error in rolling back runner %d (%s): %w", i, describeRunner(m.runners[i]), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes the runners implementing io.Closer, in reverse order, and
+// returns their joined errors.
+func (m *MultiRunner) Close() error {
+	var errs []error
+	for i := len(m.runners) - 1; i >= 0; i-- {
+		if err := closeRunner(m.runners[i]); err != nil {
+			errs = append(errs, fmt.Errorf("error in closing runner %d (%s): %w", i, describeRunner(m.runners[i]), err))
+		}
+	}
+	return errors.Join(errs...)
+}
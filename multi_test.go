@@ -0,0 +1,242 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// TestMultiRunner verifies that all runners commit together and roll back
+// together.
+func TestMultiRunner(t *testing.T) {
+	tests := []struct {
+		name      string
+		fnErr     error
+		wantState string
+	}{
+		{name: "commit", wantState: " committed!"},
+		{name: "rollback", fnErr: errors.New("fn failed"), wantState: " rolled back!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, second := NewMockTx(), NewMockTx()
+			u := New(NewMultiRunner(first, second))
+
+			err := u.Run(context.Background(), func(ctx context.Context) error {
+				values := u.Get(ctx).([]any)
+				if len(values) != 2 || values[0] != first.state || values[1] != second.state {
+					t.Errorf("expected the states of both runners, got %v", values)
+				}
+				return tt.fnErr
+			})
+			if !errors.Is(err, tt.fnErr) {
+				t.Errorf("expected %v, got %v", tt.fnErr, err)
+			}
+			for i, mt := range []*MockTx{first, second} {
+				if got := mt.state.Value(); got != tt.wantState {
+					t.Errorf("runner %d: expected state %q, got %q", i, tt.wantState, got)
+				}
+			}
+		})
+	}
+}
+
+// TestMultiRunner_Order verifies the deterministic ordering of the calls and
+// that a runner whose commit failed is not rolled back afterwards.
+func TestMultiRunner_Order(t *testing.T) {
+	var order []string
+	commitErr := errors.New("commit failed")
+	first := &orderRunner{name: "first", order: &order}
+	second := &orderRunner{name: "second", order: &order, commitErr: commitErr}
+	u := New(NewMultiRunner(first, second))
+
+	err := u.Run(context.Background(), func(_ context.Context) error { return nil })
+	if !errors.Is(err, commitErr) {
+		t.Errorf("expected commit error, got %v", err)
+	}
+	want := []string{"first ctx", "second ctx", "first commit", "second commit"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("expected %v, got %v", want, order)
+	}
+
+	order = nil
+	_ = u.Run(context.Background(), func(_ context.Context) error { return errors.New("fn failed") })
+	want = []string{"first ctx", "second ctx", "second rollback", "first rollback"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("expected %v, got %v", want, order)
+	}
+}
+
+// TestMultiRunner_CtxError verifies that a runner failing to start rolls back
+// the runners started before it.
+func TestMultiRunner_CtxError(t *testing.T) {
+	first := NewMockTx()
+	ctxErr := errors.New("ctx failed")
+	u := New(NewMultiRunner(first, NewMockTx().WithCtxError(ctxErr)))
+
+	err := u.Run(context.Background(), func(_ context.Context) error {
+		t.Error("fn must not run")
+		return nil
+	})
+	if !errors.Is(err, ctxErr) {
+		t.Errorf("expected ctx error, got %v", err)
+	}
+	if got := first.state.Value(); got != " rolled back!" {
+		t.Errorf("expected the first runner to be rolled back, got %q", got)
+	}
+}
+
+// TestMultiRunner_CommitFailure verifies that a commit failure on the second
+// runner rolls back the runners after it, compensates the committed first
+// runner and reports the outcome of every runner.
+func TestMultiRunner_CommitFailure(t *testing.T) {
+	var order []string
+	commitErr := errors.New("commit failed")
+	compensateErr := errors.New("compensation failed")
+	first := &compensatingRunner{orderRunner: orderRunner{name: "first", order: &order}}
+	second := &orderRunner{name: "second", order: &order, commitErr: commitErr}
+	third := &orderRunner{name: "third", order: &order}
+	u := New(NewMultiRunner(first, second, third))
+
+	err := u.Run(context.Background(), func(_ context.Context) error { return nil })
+	want := []string{
+		"first ctx", "second ctx", "third ctx",
+		"first commit", "second commit",
+		"third rollback", "first compensate",
+	}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("expected %v, got %v", want, order)
+	}
+	var multiErr *MultiCommitError
+	if !errors.As(err, &multiErr) || !errors.Is(err, commitErr) {
+		t.Fatalf("expected a *MultiCommitError wrapping the commit error, got %v", err)
+	}
+	statuses := func() []OutcomeStatus {
+		var got []OutcomeStatus
+		for _, o := range multiErr.Outcomes {
+			got = append(got, o.Status)
+		}
+		return got
+	}
+	if got, want := statuses(), []OutcomeStatus{OutcomeCompensated, OutcomeCommitFailed, OutcomeRolledBack}; multiErr.Failed != 1 || !reflect.DeepEqual(got, want) {
+		t.Errorf("expected runner 1 to fail with outcomes %v, got %d and %v", want, multiErr.Failed, got)
+	}
+
+	first.err = compensateErr
+	err = u.Run(context.Background(), func(_ context.Context) error { return nil })
+	if !errors.As(err, &multiErr) || !errors.Is(err, compensateErr) {
+		t.Fatalf("expected the compensation error, got %v", err)
+	}
+	if got := multiErr.Outcomes[0].Status; got != OutcomeCompensationFailed {
+		t.Errorf("expected %q, got %q", OutcomeCompensationFailed, got)
+	}
+}
+
+// TestMultiRunner_CommitFailure_NotCompensable verifies that a committed
+// runner without compensation is reported as left committed.
+func TestMultiRunner_CommitFailure_NotCompensable(t *testing.T) {
+	first := NewMockTx()
+	commitErr := errors.New("commit failed")
+	u := New(NewMultiRunner(first, NewMockTx().WithCommitError(commitErr)))
+
+	err := u.Run(context.Background(), func(_ context.Context) error { return nil })
+	var multiErr *MultiCommitError
+	if !errors.As(err, &multiErr) || !errors.Is(err, commitErr) {
+		t.Fatalf("expected a *MultiCommitError wrapping the commit error, got %v", err)
+	}
+	if got := multiErr.Outcomes[0].Status; got != OutcomeCommitted {
+		t.Errorf("expected %q, got %q", OutcomeCommitted, got)
+	}
+	if got := first.State().Status(); got != StateCommitted {
+		t.Errorf("expected the first runner to stay committed, got %v", got)
+	}
+	if want := "error in committing runner 1 (*uow.MockTx); runner 0 (*uow.MockTx): committed; runner 1 (*uow.MockTx): commit failed: commit failed"; multiErr.Error() != want {
+		t.Errorf("expected %q, got %q", want, multiErr.Error())
+	}
+}
+
+// TestMultiRunner_CommitFailure_PooledMongo verifies that a pooled MongoTx
+// whose commit fails gives its session back to the pool exactly once.
+func TestMultiRunner_CommitFailure_PooledMongo(t *testing.T) {
+	client := newLazyMongoClient(t)
+	pool := &countingPool{MongoSessionCache: NewMongoSessionCache(client, 2), released: map[mongo.Session]int{}}
+	t.Cleanup(func() { _ = pool.Close() })
+	u := New(NewMultiRunner(NewMockTx(), NewMongoTx(client, "test", WithSessionPool(pool))))
+
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		// Aborting behind the runner's back makes its commit fail without
+		// contacting a server.
+		return mongo.SessionFromContext(ctx).AbortTransaction(ctx)
+	})
+	var multiErr *MultiCommitError
+	if !errors.As(err, &multiErr) || multiErr.Failed != 1 {
+		t.Fatalf("expected the commit of the MongoTx to fail, got %v", err)
+	}
+	if strings.Contains(err.Error(), "abortTransaction twice") {
+		t.Errorf("expected no rollback after the failed commit, got %v", err)
+	}
+	if len(pool.released) != 1 {
+		t.Fatalf("expected one session released, got %d", len(pool.released))
+	}
+	for _, n := range pool.released {
+		if n != 1 {
+			t.Errorf("expected the session to be released once, got %d", n)
+		}
+	}
+}
+
+// countingPool is a MongoSessionCache counting how often each session is
+// released.
+type countingPool struct {
+	*MongoSessionCache
+	mu       sync.Mutex
+	released map[mongo.Session]int
+}
+
+func (p *countingPool) Release(sess mongo.Session) {
+	p.mu.Lock()
+	p.released[sess]++
+	p.mu.Unlock()
+	p.MongoSessionCache.Release(sess)
+}
+
+// orderRunner is a Runner appending its calls to a shared log.
+type orderRunner struct {
+	name      string
+	order     *[]string
+	commitErr error
+}
+
+func (r *orderRunner) Ctx(ctx context.Context) (context.Context, error) {
+	*r.order = append(*r.order, r.name+" ctx")
+	return ctx, nil
+}
+
+func (r *orderRunner) Get(_ context.Context) any { return nil }
+
+func (r *orderRunner) Commit(_ context.Context) error {
+	*r.order = append(*r.order, r.name+" commit")
+	return r.commitErr
+}
+
+func (r *orderRunner) Rollback(_ context.Context) error {
+	*r.order = append(*r.order, r.name+" rollback")
+	return nil
+}
+
+// compensatingRunner is an orderRunner implementing Compensator.
+type compensatingRunner struct {
+	orderRunner
+	err error
+}
+
+func (r *compensatingRunner) Compensate(_ context.Context) error {
+	*r.order = append(*r.order, r.name+" compensate")
+	return r.err
+}
@@ -0,0 +1,43 @@
+package uow
+
+import "context"
+
+// NoopRunner implements the Runner interface without any transaction. Ctx
+// returns the context unchanged, Get returns a fixed value and Commit and
+// Rollback do nothing, so code written for a UoW also runs against data
+// sources that do not support transactions, e.g. in local development.
+// Changes made by fn are not rolled back when it fails.
+var _ Runner = &NoopRunner{}
+
+// NoopRunner struct holds the value returned by Get.
+type NoopRunner struct {
+	value any
+}
+
+// NewNoopRunner creates a new NoopRunner instance. It takes the value Get
+// should return, e.g. a database handle, which may be nil.
+func NewNoopRunner(value any) *NoopRunner {
+	return &NoopRunner{
+		value: value,
+	}
+}
+
+// Ctx returns ctx unchanged.
+func (n *NoopRunner) Ctx(ctx context.Context) (context.Context, error) {
+	return ctx, nil
+}
+
+// Get returns the value passed to NewNoopRunner.
+func (n *NoopRunner) Get(_ context.Context) any {
+	return n.value
+}
+
+// Rollback does nothing.
+func (n *NoopRunner) Rollback(_ context.Context) error {
+	return nil
+}
+
+// Commit does nothing.
+func (n *NoopRunner) Commit(_ context.Context) error {
+	return nil
+}
@@ -0,0 +1,33 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestNoopRunner verifies that NoopRunner passes the configured value to fn
+// and lets errors from fn through.
+func TestNoopRunner(t *testing.T) {
+	db := &struct{ name string }{name: "db"}
+	u := New(NewNoopRunner(db))
+
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		if got := u.Get(ctx); got != db {
+			t.Errorf("expected the configured value, got %v", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fnErr := errors.New("fn failed")
+	if err := u.Run(context.Background(), func(_ context.Context) error { return fnErr }); !errors.Is(err, fnErr) {
+		t.Errorf("expected fn error, got %v", err)
+	}
+
+	if got := NewNoopRunner(nil).Get(context.Background()); got != nil {
+		t.Errorf("expected nil, got %v", got)
+	}
+}
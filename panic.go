@@ -0,0 +1,13 @@
+package uow
+
+// WithPanicHandler makes Run return an error instead of re-panicking when fn or
+// the work around it panics. The transaction is rolled back first, as without
+// the option, and handler converts the recovered value into the error Run
+// returns, e.g. to attach a stack trace. When handler returns nil, Run returns
+// an error describing the panic. Without this option the panic propagates
+// once the transaction has been rolled back.
+func WithPanicHandler(handler func(recovered any) error) Option {
+	return func(c *config) {
+		c.panicHandler = handler
+	}
+}
@@ -0,0 +1,38 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// TestWithPanicHandler verifies that a panic in fn is rolled back and returned
+// as the error produced by the handler.
+func TestWithPanicHandler(t *testing.T) {
+	mt := NewMockTx()
+	errPanicked := errors.New("panicked")
+	u := New(mt, WithPanicHandler(func(recovered any) error {
+		return fmt.Errorf("%w: %v", errPanicked, recovered)
+	}))
+
+	err := u.Run(context.Background(), func(_ context.Context) error {
+		panic("boom")
+	})
+	if !errors.Is(err, errPanicked) || err.Error() != "panicked: boom" {
+		t.Errorf("expected the converted panic, got %v", err)
+	}
+	if got := mt.State().Status(); got != StateRolledBack {
+		t.Errorf("expected a rollback, got status %v", got)
+	}
+
+	rollbackErr := errors.New("rollback failed")
+	u = New(NewMockTx().WithRollbackError(rollbackErr), WithPanicHandler(func(any) error { return nil }))
+	err = u.Run(context.Background(), func(_ context.Context) error {
+		panic("boom")
+	})
+	var rbErr *RollbackError
+	if !errors.As(err, &rbErr) || !errors.Is(err, rollbackErr) || rbErr.OpErr.Error() != "panic: boom" {
+		t.Errorf("expected a RollbackError describing the panic, got %v", err)
+	}
+}
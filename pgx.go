@@ -0,0 +1,115 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// pgxTxKey is the context key for storing the pgx transaction.
+var pgxTxKey = ctxKey{"pgx_tx"}
+
+// PgxTx implements the Runner interface for PostgreSQL transactions through
+// the native github.com/jackc/pgx/v5 driver, giving fn access to features that
+// database/sql does not expose, such as COPY and LISTEN/NOTIFY.
+var _ Runner = &PgxTx{}
+
+// PgxTx struct holds the pgx connection pool and the transaction options.
+type PgxTx struct {
+	pool      *pgxpool.Pool
+	txOptions pgx.TxOptions
+}
+
+// PgxOption configures optional behavior of a PgxTx. Options are passed to
+// NewPgxTx.
+type PgxOption func(*PgxTx)
+
+// WithPgxTxOptions sets the options every transaction is started with, such
+// as the isolation level and access mode.
+func WithPgxTxOptions(opts pgx.TxOptions) PgxOption {
+	return func(p *PgxTx) {
+		p.txOptions = opts
+	}
+}
+
+// NewPgxTx creates a new PgxTx instance. It takes a pgx connection pool and
+// optional settings as arguments.
+func NewPgxTx(pool *pgxpool.Pool, opts ...PgxOption) *PgxTx {
+	p := &PgxTx{
+		pool: pool,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	return p
+}
+
+// Ctx acquires a connection from the pool and starts a new transaction on it
+// with the configured options. A statement timeout configured with
+// WithStatementTimeout is applied with SET LOCAL statement_timeout. When ctx
+// already carries a transaction, the unit of work is nested and gets a
+// savepoint instead, released on Commit and rolled back to on Rollback.
+func (p *PgxTx) Ctx(ctx context.Context) (context.Context, error) {
+	if outer, ok := ctx.Value(pgxTxKey).(pgx.Tx); ok {
+		// Nested unit of work: pgx maps a transaction begun on a transaction
+		// to a savepoint.
+		tx, err := outer.Begin(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("error in creating savepoint: %w", err)
+		}
+		return context.WithValue(ctx, pgxTxKey, tx), nil
+	}
+
+	opts := p.txOptions
+	if IsReadOnly(ctx) {
+		opts.AccessMode = pgx.ReadOnly
+	}
+	tx, err := p.pool.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, fmt.Errorf("error in starting transaction: %w", err)
+	}
+
+	if d, ok := StatementTimeout(ctx); ok {
+		if _, err := tx.Exec(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", d.Milliseconds())); err != nil {
+			_ = tx.Rollback(ctx)
+			return nil, fmt.Errorf("error in setting statement timeout: %w", err)
+		}
+	}
+	return context.WithValue(ctx, pgxTxKey, tx), nil
+}
+
+// Get retrieves the pgx transaction. If a transaction exists in the context,
+// it returns the pgx.Tx. Otherwise, it returns the *pgxpool.Pool.
+func (p *PgxTx) Get(ctx context.Context) any {
+	if tx, ok := ctx.Value(pgxTxKey).(pgx.Tx); ok {
+		return tx
+	}
+	return p.pool
+}
+
+// Rollback aborts the current transaction and returns its connection to the
+// pool. pgx.ErrTxClosed, reported when the transaction has already been
+// committed or rolled back, is swallowed so that it cannot mask the error
+// that caused the rollback.
+func (p *PgxTx) Rollback(ctx context.Context) error {
+	tx, ok := ctx.Value(pgxTxKey).(pgx.Tx)
+	if !ok {
+		return nil
+	}
+	if err := tx.Rollback(ctx); err != nil && !errors.Is(err, pgx.ErrTxClosed) {
+		return err
+	}
+	return nil
+}
+
+// Commit commits the current transaction and returns its connection to the
+// pool.
+func (p *PgxTx) Commit(ctx context.Context) error {
+	if tx, ok := ctx.Value(pgxTxKey).(pgx.Tx); ok {
+		return tx.Commit(ctx)
+	}
+	return nil
+}
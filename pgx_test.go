@@ -0,0 +1,66 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// fakePgxTx is a pgx.Tx whose Commit and Rollback return preset errors.
+type fakePgxTx struct {
+	pgx.Tx
+	commitErr   error
+	rollbackErr error
+}
+
+func (f *fakePgxTx) Commit(_ context.Context) error   { return f.commitErr }
+func (f *fakePgxTx) Rollback(_ context.Context) error { return f.rollbackErr }
+
+// TestPgxTx_Rollback verifies that ErrTxClosed is swallowed while other
+// rollback errors are returned.
+func TestPgxTx_Rollback(t *testing.T) {
+	connErr := errors.New("conn busy")
+
+	tests := []struct {
+		name        string
+		rollbackErr error
+		wantErr     error
+	}{
+		{name: "ok"},
+		{name: "tx_closed", rollbackErr: pgx.ErrTxClosed},
+		{name: "other", rollbackErr: connErr, wantErr: connErr},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPgxTx(nil)
+			tx := &fakePgxTx{rollbackErr: tt.rollbackErr}
+			ctx := context.WithValue(context.Background(), pgxTxKey, pgx.Tx(tx))
+
+			if got := p.Get(ctx); got != pgx.Tx(tx) {
+				t.Errorf("expected Get to return the transaction, got %v", got)
+			}
+			err := p.Rollback(ctx)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("expected no error, got %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+// TestPgxTx_NoTransaction verifies that Commit and Rollback are no-ops outside
+// a transaction.
+func TestPgxTx_NoTransaction(t *testing.T) {
+	p := NewPgxTx(nil)
+	if err := p.Commit(context.Background()); err != nil {
+		t.Errorf("expected nil from Commit, got %v", err)
+	}
+	if err := p.Rollback(context.Background()); err != nil {
+		t.Errorf("expected nil from Rollback, got %v", err)
+	}
+}
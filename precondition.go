@@ -0,0 +1,28 @@
+package uow
+
+import (
+	"context"
+	"fmt"
+)
+
+// WithPrecondition registers a check that runs inside the transaction before
+// fn, for check-then-act patterns such as "the row exists and its status is
+// pending". When the check fails, the transaction rolls back and Run returns
+// its error without running fn. Preconditions are cumulative and run in
+// registration order.
+func WithPrecondition(check func(ctx context.Context) error) Option {
+	return func(c *config) {
+		c.preconditions = append(c.preconditions, check)
+	}
+}
+
+// checkPreconditions runs the configured preconditions in the transactional
+// context.
+func (u *UoW) checkPreconditions(ctx context.Context) error {
+	for _, check := range u.config.preconditions {
+		if err := check(ctx); err != nil {
+			return fmt.Errorf("precondition failed: %w", err)
+		}
+	}
+	return nil
+}
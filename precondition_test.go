@@ -0,0 +1,53 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestWithPrecondition verifies that a failing precondition prevents fn from
+// running and rolls back, while a passing one lets fn run and commit.
+func TestWithPrecondition(t *testing.T) {
+	errNotPending := errors.New("order is not pending")
+
+	tests := []struct {
+		name      string
+		checkErr  error
+		wantCalls int
+		wantState string
+	}{
+		{name: "holds", wantCalls: 1, wantState: " committed!"},
+		{name: "fails", checkErr: errNotPending, wantCalls: 0, wantState: " rolled back!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt := NewMockTx()
+			u := New(mt, WithPrecondition(func(ctx context.Context) error {
+				if runStateFrom(ctx) == nil {
+					t.Error("expected precondition to run inside the unit of work")
+				}
+				return tt.checkErr
+			}))
+
+			calls := 0
+			err := u.Run(context.Background(), func(_ context.Context) error {
+				calls++
+				return nil
+			})
+			if tt.checkErr != nil && !errors.Is(err, tt.checkErr) {
+				t.Errorf("expected precondition error, got %v", err)
+			}
+			if tt.checkErr == nil && err != nil {
+				t.Fatal(err)
+			}
+			if calls != tt.wantCalls {
+				t.Errorf("expected fn to be called %d times, got %d", tt.wantCalls, calls)
+			}
+			if got := mt.state.Value(); got != tt.wantState {
+				t.Errorf("expected state %q, got %q", tt.wantState, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package uow
+
+import "context"
+
+// WithReadOnly runs every unit of work in a read-only transaction, which lets
+// the driver and database optimize it and prevents accidental writes. The
+// transaction is always rolled back at the end instead of committed, and
+// after-commit hooks do not run. Runners start the transaction as follows:
+//
+//   - SQLTx and GormTx begin with sql.TxOptions{ReadOnly: true}, and PgxTx
+//     with the read-only access mode; writes fail with a database error.
+//   - BoltTx begins a read-only transaction, which does not wait for the
+//     writer slot; writes fail with bolt.ErrTxNotWritable.
+//   - MongoTx uses the snapshot read concern, unless another one is set with
+//     WithReadConcern. MongoDB has no read-only transactions, so writes are
+//     not rejected but discarded by the final abort.
+//
+// Custom runners can query the mode with IsReadOnly.
+func WithReadOnly() Option {
+	return func(c *config) {
+		c.readOnly = true
+	}
+}
+
+// ReadOnly is a per-run option running a single call to Run in a read-only
+// transaction, as WithReadOnly does for every call.
+func ReadOnly() RunOption {
+	return func(rc *runConfig) {
+		rc.readOnly = true
+	}
+}
+
+// RunReadOnly runs fn in a read-only transaction. It is shorthand for
+// u.Run(ctx, fn, append(opts, ReadOnly())...).
+func (u *UoW) RunReadOnly(ctx context.Context, fn func(ctx context.Context) error, opts ...RunOption) error {
+	return u.Run(ctx, fn, append(opts, ReadOnly())...)
+}
+
+// IsReadOnly reports whether ctx belongs to a unit of work running in
+// read-only mode. Runners call it from Ctx to start a read-only transaction.
+func IsReadOnly(ctx context.Context) bool {
+	rs := runStateFrom(ctx)
+	return rs != nil && rs.readOnly
+}
@@ -0,0 +1,100 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// TestRunReadOnly verifies that a read-only unit of work is rolled back
+// instead of committed and that the mode is visible inside fn.
+func TestRunReadOnly(t *testing.T) {
+	mt := NewMockTx()
+	afterCommit := false
+	u := New(mt, WithAfterCommit(func(_ context.Context) { afterCommit = true }))
+
+	err := u.RunReadOnly(context.Background(), func(ctx context.Context) error {
+		if !IsReadOnly(ctx) {
+			t.Error("expected read-only mode inside fn")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mt.Ops(); len(got) != 2 || got[1] != "rollback" {
+		t.Errorf("expected the read-only run to roll back, got %v", got)
+	}
+	if afterCommit {
+		t.Error("expected no after-commit hook for a read-only run")
+	}
+
+	err = u.Run(context.Background(), func(ctx context.Context) error {
+		if IsReadOnly(ctx) {
+			t.Error("expected read-write mode for a regular run")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestWithReadOnly_SQLite verifies that a read-only SQL unit of work never
+// commits. go-sqlite3 ignores the read-only transaction option, so the write
+// succeeds here but is rolled back.
+func TestWithReadOnly_SQLite(t *testing.T) {
+	db := openAuditDB(t)
+	txs := New(NewSQLTx(db), WithReadOnly())
+
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		_, err := txs.Get(ctx).(*sql.Tx).ExecContext(ctx, "INSERT INTO test (name) VALUES ('discarded')")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := countRows(t, db, "test"); n != 0 {
+		t.Errorf("expected no rows, got %d", n)
+	}
+}
+
+// TestWithReadOnly_Mongo verifies that a read-only Mongo unit of work starts
+// and aborts its transaction.
+func TestWithReadOnly_Mongo(t *testing.T) {
+	client := newLazyMongoClient(t)
+	txs := New(NewMongoTx(client, "test"), WithReadOnly())
+
+	if err := txs.Run(context.Background(), func(_ context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if n := client.NumberSessionsInProgress(); n != 0 {
+		t.Errorf("expected no sessions in progress, got %d", n)
+	}
+}
+
+// TestWithReadOnly_Bolt verifies that a read-only BoltDB unit of work rejects
+// writes and does not take the writer slot.
+func TestWithReadOnly_Bolt(t *testing.T) {
+	db := openBolt(t)
+	boltTx := NewBoltTx(db)
+	writes := New(boltTx)
+	reads := New(boltTx, WithReadOnly())
+
+	err := writes.Run(context.Background(), func(ctx context.Context) error {
+		// The writer slot is held here, yet the reader must not block.
+		return reads.Run(context.Background(), func(ctx context.Context) error {
+			tx := reads.Get(ctx).(*bolt.Tx)
+			if err := tx.Bucket([]byte("test")).Put([]byte("key"), []byte("value")); !errors.Is(err, bolt.ErrTxNotWritable) {
+				t.Errorf("expected ErrTxNotWritable, got %v", err)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,211 @@
+package uow
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// RecordingRunner implements the Runner interface by wrapping another runner
+// and recording every call to Ctx, Commit and Rollback, with its timing and
+// error, for post-mortem analysis of a real data store, much like the call log
+// of MockTx. The calls are passed through unchanged; Get is not recorded.
+// Savepoints, compensation and closing are forwarded to the wrapped runner and
+// recorded too.
+var _ Runner = &RecordingRunner{}
+
+var (
+	_ Savepointer = &RecordingRunner{}
+	_ Compensator = &RecordingRunner{}
+	_ io.Closer   = &RecordingRunner{}
+)
+
+// RecordingRunner struct holds the wrapped runner and the recorded calls.
+type RecordingRunner struct {
+	runner Runner
+	limit  int
+
+	mu    sync.Mutex
+	seq   int
+	calls []RecordedCall
+}
+
+// RecordedCall records a single call to a RecordingRunner method.
+type RecordedCall struct {
+	// Seq is the 1-based position of the call among all calls on the runner.
+	Seq int `json:"seq"`
+
+	// Op is the method called: "ctx", "commit", "rollback", "savepoint",
+	// "rollback_to_savepoint", "release_savepoint", "compensate" or "close".
+	Op string `json:"op"`
+
+	// Savepoint is the name of the savepoint of a savepoint call.
+	Savepoint string `json:"savepoint,omitempty"`
+
+	// TxID identifies the transaction of the call; it is empty when the
+	// runner is used outside UoW.Run.
+	TxID string `json:"tx_id,omitempty"`
+
+	// Start is the time the call started.
+	Start time.Time `json:"start"`
+
+	// Duration is the time the call took.
+	Duration time.Duration `json:"duration"`
+
+	// Err is the error returned by the call, if any.
+	Err string `json:"error,omitempty"`
+}
+
+// RecordingOption configures optional behavior of a RecordingRunner. Options
+// are passed to NewRecordingRunner.
+type RecordingOption func(*RecordingRunner)
+
+// WithRecordLimit keeps only the n most recent calls, bounding the memory used
+// by a long-running recorder. A non-positive n keeps every call.
+func WithRecordLimit(n int) RecordingOption {
+	return func(r *RecordingRunner) {
+		r.limit = n
+	}
+}
+
+// NewRecordingRunner creates a new RecordingRunner instance. It takes the
+// runner to wrap and optional settings as arguments.
+func NewRecordingRunner(runner Runner, opts ...RecordingOption) *RecordingRunner {
+	r := &RecordingRunner{
+		runner: runner,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Ctx starts a transaction on the wrapped runner and records the call.
+func (r *RecordingRunner) Ctx(ctx context.Context) (context.Context, error) {
+	start := time.Now()
+	txCtx, err := r.runner.Ctx(ctx)
+	r.record(ctx, "ctx", "", start, err)
+	return txCtx, err
+}
+
+// Get delegates to the wrapped runner.
+func (r *RecordingRunner) Get(ctx context.Context) any {
+	return r.runner.Get(ctx)
+}
+
+// Rollback rolls back the wrapped runner's transaction and records the call.
+func (r *RecordingRunner) Rollback(ctx context.Context) error {
+	start := time.Now()
+	err := r.runner.Rollback(ctx)
+	r.record(ctx, "rollback", "", start, err)
+	return err
+}
+
+// Commit commits the wrapped runner's transaction and records the call.
+func (r *RecordingRunner) Commit(ctx context.Context) error {
+	start := time.Now()
+	err := r.runner.Commit(ctx)
+	r.record(ctx, "commit", "", start, err)
+	return err
+}
+
+// unwrap returns the wrapped runner.
+func (r *RecordingRunner) unwrap() Runner {
+	return r.runner
+}
+
+// Savepoint forwards to the wrapped runner and records the call. It returns
+// ErrSavepointsUnsupported when the wrapped runner does not implement
+// Savepointer.
+func (r *RecordingRunner) Savepoint(ctx context.Context, name string) error {
+	start := time.Now()
+	err := savepoint(ctx, r.runner, name)
+	r.record(ctx, "savepoint", name, start, err)
+	return err
+}
+
+// RollbackToSavepoint forwards to the wrapped runner and records the call. It
+// returns ErrSavepointsUnsupported when the wrapped runner does not implement
+// Savepointer.
+func (r *RecordingRunner) RollbackToSavepoint(ctx context.Context, name string) error {
+	start := time.Now()
+	err := rollbackToSavepoint(ctx, r.runner, name)
+	r.record(ctx, "rollback_to_savepoint", name, start, err)
+	return err
+}
+
+// ReleaseSavepoint forwards to the wrapped runner and records the call. It
+// returns ErrSavepointsUnsupported when the wrapped runner does not implement
+// Savepointer.
+func (r *RecordingRunner) ReleaseSavepoint(ctx context.Context, name string) error {
+	start := time.Now()
+	err := releaseSavepoint(ctx, r.runner, name)
+	r.record(ctx, "release_savepoint", name, start, err)
+	return err
+}
+
+// Compensate forwards to the wrapped runner and records the call. It returns
+// ErrCompensationUnsupported when the wrapped runner does not implement
+// Compensator.
+func (r *RecordingRunner) Compensate(ctx context.Context) error {
+	start := time.Now()
+	err := compensate(ctx, r.runner)
+	r.record(ctx, "compensate", "", start, err)
+	return err
+}
+
+// Close closes the wrapped runner if it implements io.Closer and records the
+// call.
+func (r *RecordingRunner) Close() error {
+	start := time.Now()
+	err := closeRunner(r.runner)
+	r.record(context.Background(), "close", "", start, err)
+	return err
+}
+
+// Calls returns the recorded calls, in order.
+func (r *RecordingRunner) Calls() []RecordedCall {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]RecordedCall(nil), r.calls...)
+}
+
+// JSON returns the recorded calls as a JSON array, in order.
+func (r *RecordingRunner) JSON() ([]byte, error) {
+	return json.Marshal(r.Calls())
+}
+
+// Reset discards the recorded calls.
+func (r *RecordingRunner) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = nil
+}
+
+// record appends a call to the trace, dropping the oldest call when the limit
+// is reached.
+func (r *RecordingRunner) record(ctx context.Context, op, savepoint string, start time.Time, err error) {
+	call := RecordedCall{
+		Op:        op,
+		Savepoint: savepoint,
+		Start:     start,
+		Duration:  time.Since(start),
+	}
+	if rs := runStateFrom(ctx); rs != nil {
+		call.TxID = rs.txID
+	}
+	if err != nil {
+		call.Err = err.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	call.Seq = r.seq
+	if r.limit > 0 && len(r.calls) >= r.limit {
+		r.calls = append(r.calls[:0], r.calls[len(r.calls)-r.limit+1:]...)
+	}
+	r.calls = append(r.calls, call)
+}
@@ -0,0 +1,98 @@
+package uow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestRecordingRunner verifies that lifecycle calls are recorded in order with
+// their transaction and error, and that the trace is valid JSON.
+func TestRecordingRunner(t *testing.T) {
+	rollbackErr := errors.New("rollback failed")
+	runner := NewRecordingRunner(NewMockTx().WithRollbackError(rollbackErr))
+	u := New(runner)
+
+	if err := u.Run(context.Background(), func(_ context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	_ = u.Run(context.Background(), func(_ context.Context) error { return errors.New("fn failed") })
+
+	calls := runner.Calls()
+	var ops []string
+	for _, c := range calls {
+		ops = append(ops, c.Op)
+	}
+	if want := []string{"ctx", "commit", "ctx", "rollback"}; !reflect.DeepEqual(ops, want) {
+		t.Fatalf("expected ops %v, got %v", want, ops)
+	}
+	if calls[0].TxID == "" || calls[0].TxID != calls[1].TxID || calls[1].TxID == calls[2].TxID {
+		t.Errorf("expected calls to carry their transaction ID, got %+v", calls)
+	}
+	if calls[3].Err != rollbackErr.Error() || calls[1].Err != "" {
+		t.Errorf("expected only the rollback to record an error, got %+v", calls)
+	}
+
+	data, err := runner.JSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded []RecordedCall
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != len(calls) || decoded[3].Seq != 4 {
+		t.Errorf("expected the JSON trace to round-trip, got %s", data)
+	}
+}
+
+// TestRecordingRunner_Limit verifies that only the most recent calls are kept.
+func TestRecordingRunner_Limit(t *testing.T) {
+	runner := NewRecordingRunner(NewMockTx(), WithRecordLimit(3))
+	u := New(runner)
+	for i := 0; i < 3; i++ {
+		if err := u.Run(context.Background(), func(_ context.Context) error { return nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	calls := runner.Calls()
+	if len(calls) != 3 || calls[0].Seq != 4 || calls[2].Seq != 6 {
+		t.Errorf("expected the calls 4 to 6, got %+v", calls)
+	}
+	runner.Reset()
+	if n := len(runner.Calls()); n != 0 {
+		t.Errorf("expected no calls after Reset, got %d", n)
+	}
+}
+
+// TestRecordingRunner_Forwarding verifies that savepoints, compensation and
+// closing are forwarded to the wrapped runner and recorded.
+func TestRecordingRunner_Forwarding(t *testing.T) {
+	var recorders []*RecordingRunner
+	testForwarding(t, func(r Runner) Runner {
+		rec := NewRecordingRunner(r)
+		recorders = append(recorders, rec)
+		return rec
+	})
+
+	var ops, savepoints []string
+	for _, c := range recorders[0].Calls() {
+		ops = append(ops, c.Op)
+		if c.Savepoint != "" {
+			savepoints = append(savepoints, c.Savepoint)
+		}
+	}
+	wantOps := []string{
+		"ctx", "savepoint", "rollback_to_savepoint", "release_savepoint", "savepoint", "commit",
+		"compensate", "close",
+	}
+	if !reflect.DeepEqual(ops, wantOps) {
+		t.Errorf("expected recorded calls %v, got %v", wantOps, ops)
+	}
+	if len(savepoints) != 4 || savepoints[0] != "uow_checkpoint_0" {
+		t.Errorf("expected the savepoint names to be recorded, got %v", savepoints)
+	}
+}
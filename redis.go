@@ -0,0 +1,145 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisTxKey is the context key for storing the Redis transaction.
+var redisTxKey = ctxKey{"redis_tx"}
+
+// RedisTx implements the Runner interface for Redis MULTI/EXEC transactions on
+// top of github.com/redis/go-redis/v9. Get returns a redis.Pipeliner that
+// queues commands; they are sent in a single MULTI/EXEC block on Commit and
+// dropped on Rollback. Because commands are only queued, the results of the
+// commands issued through Get are not available until the transaction has
+// committed: inspect the returned redis.Cmder values after Run returns.
+//
+// With WithWatch, the given keys are watched on a dedicated connection before
+// the transaction starts, for optimistic locking: fn can read their current
+// values through Conn, and Commit fails with an error matching
+// IsRedisWatchConflict if any of them changed in the meantime.
+var _ Runner = &RedisTx{}
+
+// RedisTx struct holds the Redis client and the keys to watch.
+type RedisTx struct {
+	client *redis.Client
+	watch  []string
+}
+
+// redisTx is stored in the context for the duration of a transaction.
+type redisTx struct {
+	pipe redis.Pipeliner
+	conn *redis.Conn
+}
+
+// RedisOption configures optional behavior of a RedisTx. Options are passed
+// to NewRedisTx.
+type RedisOption func(*RedisTx)
+
+// WithWatch makes every transaction WATCH keys before it starts.
+func WithWatch(keys ...string) RedisOption {
+	return func(r *RedisTx) {
+		r.watch = append(r.watch, keys...)
+	}
+}
+
+// NewRedisTx creates a new RedisTx instance. It takes a Redis client and
+// optional settings as arguments.
+func NewRedisTx(client *redis.Client, opts ...RedisOption) *RedisTx {
+	r := &RedisTx{
+		client: client,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Ctx starts a new transaction pipeline. When keys are watched, a connection
+// is taken from the pool and pinned to the transaction, and the keys are
+// watched on it.
+func (r *RedisTx) Ctx(ctx context.Context) (context.Context, error) {
+	if len(r.watch) == 0 {
+		return context.WithValue(ctx, redisTxKey, &redisTx{pipe: r.client.TxPipeline()}), nil
+	}
+
+	conn := r.client.Conn()
+	args := make([]any, 0, len(r.watch)+1)
+	args = append(args, "watch")
+	for _, key := range r.watch {
+		args = append(args, key)
+	}
+	if err := conn.Process(ctx, redis.NewStatusCmd(ctx, args...)); err != nil {
+		_ = conn.Close()
+		return nil, fmt.Errorf("error in watching keys: %w", err)
+	}
+	return context.WithValue(ctx, redisTxKey, &redisTx{pipe: conn.TxPipeline(), conn: conn}), nil
+}
+
+// Get retrieves the transaction pipeline. If a transaction exists in the
+// context, it returns its redis.Pipeliner. Otherwise, it returns the
+// *redis.Client.
+func (r *RedisTx) Get(ctx context.Context) any {
+	if tx, ok := ctx.Value(redisTxKey).(*redisTx); ok {
+		return tx.pipe
+	}
+	return r.client
+}
+
+// Conn returns the connection the watched keys were watched on, to read their
+// current values inside fn. It returns nil when no keys are watched or ctx
+// does not carry a transaction.
+func (r *RedisTx) Conn(ctx context.Context) *redis.Conn {
+	if tx, ok := ctx.Value(redisTxKey).(*redisTx); ok {
+		return tx.conn
+	}
+	return nil
+}
+
+// Rollback discards the queued commands and releases the pinned connection,
+// if any.
+func (r *RedisTx) Rollback(ctx context.Context) error {
+	tx, ok := ctx.Value(redisTxKey).(*redisTx)
+	if !ok {
+		return nil
+	}
+	tx.pipe.Discard()
+	if tx.conn != nil {
+		unwatchCtx := context.WithoutCancel(ctx)
+		_ = tx.conn.Process(unwatchCtx, redis.NewStatusCmd(unwatchCtx, "unwatch"))
+		return tx.conn.Close()
+	}
+	return nil
+}
+
+// Commit sends the queued commands in a MULTI/EXEC block and releases the
+// pinned connection, if any. redis.Nil results of individual commands, such
+// as a GET of a missing key, are not treated as failures.
+func (r *RedisTx) Commit(ctx context.Context) error {
+	tx, ok := ctx.Value(redisTxKey).(*redisTx)
+	if !ok {
+		return nil
+	}
+	if tx.conn != nil {
+		defer func() { _ = tx.conn.Close() }()
+	}
+	_, err := tx.pipe.Exec(ctx)
+	if errors.Is(err, redis.Nil) {
+		return nil
+	}
+	if errors.Is(err, redis.TxFailedErr) {
+		return fmt.Errorf("watched keys changed: %w", err)
+	}
+	return err
+}
+
+// IsRedisWatchConflict reports whether err indicates that a key watched with
+// WithWatch changed before the transaction committed. Such transactions can
+// be retried by passing it to WithRetryIf.
+func IsRedisWatchConflict(err error) bool {
+	return errors.Is(err, redis.TxFailedErr)
+}
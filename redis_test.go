@@ -0,0 +1,102 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+)
+
+// newMiniredisClient starts an in-memory Redis server and returns a client
+// connected to it.
+func newMiniredisClient(t *testing.T) (*miniredis.Miniredis, *redis.Client) {
+	t.Helper()
+	srv := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: srv.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return srv, client
+}
+
+// TestRedisTx verifies that queued commands are applied on commit and dropped
+// on rollback.
+func TestRedisTx(t *testing.T) {
+	srv, client := newMiniredisClient(t)
+	txs := New(NewRedisTx(client))
+
+	var incr *redis.IntCmd
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		pipe := txs.Get(ctx).(redis.Pipeliner)
+		pipe.Set(ctx, "name", "committed", 0)
+		incr = pipe.Incr(ctx, "counter")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := srv.Get("name"); got != "committed" {
+		t.Errorf("expected committed value, got %q", got)
+	}
+	if incr.Val() != 1 {
+		t.Errorf("expected the INCR result after commit, got %d", incr.Val())
+	}
+
+	fnErr := errors.New("fn failed")
+	err = txs.Run(context.Background(), func(ctx context.Context) error {
+		txs.Get(ctx).(redis.Pipeliner).Set(ctx, "name", "rolled back", 0)
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) {
+		t.Fatalf("expected fn error, got %v", err)
+	}
+	if got, _ := srv.Get("name"); got != "committed" {
+		t.Errorf("expected the value to be unchanged, got %q", got)
+	}
+}
+
+// TestRedisTx_Watch verifies that a concurrent change to a watched key makes
+// the commit fail with a watch conflict, and that the unit of work succeeds
+// when the key is left alone.
+func TestRedisTx_Watch(t *testing.T) {
+	srv, client := newMiniredisClient(t)
+	if err := srv.Set("balance", "10"); err != nil {
+		t.Fatal(err)
+	}
+	runner := NewRedisTx(client, WithWatch("balance"))
+	txs := New(runner)
+
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		balance, err := runner.Conn(ctx).Get(ctx, "balance").Int()
+		if err != nil {
+			return err
+		}
+		// Another client changes the watched key before the commit.
+		if err := client.Set(ctx, "balance", 0, 0).Err(); err != nil {
+			return err
+		}
+		txs.Get(ctx).(redis.Pipeliner).Set(ctx, "balance", balance-5, 0)
+		return nil
+	})
+	if !IsRedisWatchConflict(err) {
+		t.Fatalf("expected a watch conflict, got %v", err)
+	}
+	if got, _ := srv.Get("balance"); got != "0" {
+		t.Errorf("expected the concurrent write to win, got %q", got)
+	}
+
+	err = txs.Run(context.Background(), func(ctx context.Context) error {
+		balance, err := runner.Conn(ctx).Get(ctx, "balance").Int()
+		if err != nil {
+			return err
+		}
+		txs.Get(ctx).(redis.Pipeliner).Set(ctx, "balance", balance+20, 0)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := srv.Get("balance"); got != "20" {
+		t.Errorf("expected the uncontended write to commit, got %q", got)
+	}
+}
@@ -0,0 +1,63 @@
+package uow
+
+import "context"
+
+// Repository binds a repository to a UoW and standardizes how it obtains the
+// transactional handle of the runner, such as *mongo.Database or *sql.Tx, and
+// runs work with it. It is a thin helper and imposes no data model:
+// repositories typically embed it and implement their methods with WithTx.
+//
+//	type UserRepo struct{ *uow.Repository[*mongo.Database] }
+//
+//	func (r UserRepo) Create(ctx context.Context, u User) error {
+//		return r.WithTx(ctx, func(ctx context.Context, db *mongo.Database) error {
+//			_, err := db.Collection("users").InsertOne(ctx, u)
+//			return err
+//		})
+//	}
+type Repository[T any] struct {
+	uow *UoW
+
+	// extract is nil when the value is asserted to be a T with GetTyped.
+	extract func(v any) (T, error)
+}
+
+// NewRepository creates a new Repository instance. It takes the UoW and a
+// function extracting the store from the value Get retrieves from the runner
+// as arguments. A nil extract asserts that the value is a T, as GetTyped does.
+func NewRepository[T any](u *UoW, extract func(v any) (T, error)) *Repository[T] {
+	return &Repository[T]{
+		uow:     u,
+		extract: extract,
+	}
+}
+
+// Store returns the store of the unit of work running in ctx, or the
+// non-transactional store outside one.
+func (r *Repository[T]) Store(ctx context.Context) (T, error) {
+	if r.extract == nil {
+		return GetTyped[T](ctx, r.uow)
+	}
+	return r.extract(r.uow.Get(ctx))
+}
+
+// WithTx runs fn with the store. When ctx belongs to a running unit of work on
+// the same runner, fn joins its transaction, so that several repository calls
+// made by the same fn commit or roll back together; otherwise fn runs in a
+// unit of work of its own, started with Run and opts.
+func (r *Repository[T]) WithTx(ctx context.Context, fn func(ctx context.Context, store T) error, opts ...RunOption) error {
+	if rs := runStateFrom(ctx); rs != nil && rs.runner == r.uow.runner && !rs.done.Load() {
+		store, err := r.Store(ctx)
+		if err != nil {
+			return err
+		}
+		return fn(ctx, store)
+	}
+	return r.uow.Run(ctx, func(ctx context.Context) error {
+		store, err := r.Store(ctx)
+		if err != nil {
+			return err
+		}
+		return fn(ctx, store)
+	}, opts...)
+}
@@ -0,0 +1,77 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestRepository_WithTx verifies that WithTx starts a unit of work of its own
+// outside one and joins the running one otherwise.
+func TestRepository_WithTx(t *testing.T) {
+	mt := NewMockTx()
+	txs := New(mt)
+	repo := NewRepository[*State](&txs, nil)
+
+	put := func(key string) error {
+		return repo.WithTx(context.Background(), func(_ context.Context, s *State) error {
+			s.Put(key, true)
+			return nil
+		})
+	}
+	if err := put("alone"); err != nil {
+		t.Fatal(err)
+	}
+	if got := mt.Ops(); !reflect.DeepEqual(got, []string{"ctx", "get", "commit"}) {
+		t.Errorf("expected a unit of work of its own, got %v", got)
+	}
+
+	mt.Reset()
+	fnErr := errors.New("fn failed")
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		for _, key := range []string{"a", "b"} {
+			if err := repo.WithTx(ctx, func(_ context.Context, s *State) error {
+				s.Put(key, true)
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) {
+		t.Fatalf("expected fn error, got %v", err)
+	}
+	if got := mt.Ops(); !reflect.DeepEqual(got, []string{"ctx", "get", "get", "rollback"}) {
+		t.Errorf("expected the calls to join the running unit of work, got %v", got)
+	}
+	if len(mt.State().Data()) != 0 {
+		t.Errorf("expected both writes rolled back, got %v", mt.State().Data())
+	}
+}
+
+// TestRepository_Extract verifies that the store is obtained through extract
+// and that its error rolls the unit of work back without calling fn.
+func TestRepository_Extract(t *testing.T) {
+	mt := NewMockTx()
+	txs := New(mt)
+
+	wrongType := NewRepository[string](&txs, nil)
+	err := wrongType.WithTx(context.Background(), func(_ context.Context, _ string) error {
+		t.Error("expected fn not to run")
+		return nil
+	})
+	if err == nil || err.Error() != "runner *uow.MockTx returned *uow.State, not string" {
+		t.Errorf("expected a type error, got %v", err)
+	}
+
+	repo := NewRepository(&txs, func(v any) (string, error) {
+		return v.(*State).Value(), nil
+	})
+	mt.State().SetValue("stored")
+	store, err := repo.Store(context.Background())
+	if err != nil || store != "stored" {
+		t.Errorf("expected the extracted store, got %q, %v", store, err)
+	}
+}
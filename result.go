@@ -0,0 +1,45 @@
+package uow
+
+import "context"
+
+// RunWithResult runs fn through u and returns the value it produces. When fn
+// succeeds, the transaction commits and the value is returned. When fn or
+// finishing the transaction fails, the transaction rolls back and the zero
+// value is returned with the error, wrapped as Run would wrap it.
+func RunWithResult[T any](ctx context.Context, u *UoW, fn func(ctx context.Context) (T, error)) (T, error) {
+	return RunWithResultIf(ctx, u, fn, func(T) bool { return true })
+}
+
+// RunWithResultIf runs fn through u and decides whether to commit based on the
+// value it produces. When fn fails, the transaction rolls back and the zero
+// value is returned with the error. When fn succeeds, shouldCommit is called
+// with the value: if it returns true the transaction commits, otherwise it
+// rolls back. In both cases the value is returned with a nil error, unless
+// finishing the transaction fails.
+func RunWithResultIf[T any](ctx context.Context, u *UoW, fn func(ctx context.Context) (T, error), shouldCommit func(T) bool) (T, error) {
+	var result T
+	err := u.Run(ctx, func(ctx context.Context) error {
+		v, err := fn(ctx)
+		if err != nil {
+			return err
+		}
+		result = v
+		if !shouldCommit(v) {
+			return ErrAbort
+		}
+		return nil
+	})
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	return result, nil
+}
+
+// RunResult is RunWithResult for untyped values, for code that cannot use
+// type parameters, such as dynamically typed glue code. It returns the value
+// produced by fn when the transaction commits, and nil with the error, wrapped
+// as Run would wrap it, otherwise.
+func RunResult(ctx context.Context, u *UoW, fn func(ctx context.Context) (any, error)) (any, error) {
+	return RunWithResult(ctx, u, fn)
+}
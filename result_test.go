@@ -0,0 +1,125 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestRunWithResult verifies that the produced value is returned on commit and
+// that a failure rolls back, returning the zero value and the wrapped errors.
+func TestRunWithResult(t *testing.T) {
+	mt := NewMockTx()
+	u := New(mt)
+	id, err := RunWithResult(context.Background(), &u, func(_ context.Context) (string, error) {
+		return "doc-1", nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "doc-1" {
+		t.Errorf("expected doc-1, got %q", id)
+	}
+	if state := mt.state.Value(); state != " committed!" {
+		t.Errorf("expected commit, got %q", state)
+	}
+
+	fnErr := errors.New("insert failed")
+	rbErr := errors.New("rollback failed")
+	u = New(&errorRunner{rollbackErr: rbErr})
+	id, err = RunWithResult(context.Background(), &u, func(_ context.Context) (string, error) {
+		return "doc-2", fnErr
+	})
+	if !errors.Is(err, fnErr) || !errors.Is(err, rbErr) {
+		t.Errorf("expected both fn and rollback errors, got %v", err)
+	}
+	if id != "" {
+		t.Errorf("expected zero value, got %q", id)
+	}
+}
+
+// TestRunWithResultIf verifies that the predicate over the produced value
+// decides between commit and rollback.
+func TestRunWithResultIf(t *testing.T) {
+	validBalance := func(balance int) bool { return balance >= 0 }
+
+	tests := []struct {
+		name      string
+		balance   int
+		wantState string
+	}{
+		{name: "commit", balance: 10, wantState: " committed!"},
+		{name: "rollback", balance: -5, wantState: " rolled back!"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt := NewMockTx()
+			u := New(mt)
+			got, err := RunWithResultIf(context.Background(), &u, func(_ context.Context) (int, error) {
+				return tt.balance, nil
+			}, validBalance)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.balance {
+				t.Errorf("expected value %d, got %d", tt.balance, got)
+			}
+			if state := mt.state.Value(); state != tt.wantState {
+				t.Errorf("expected state %q, got %q", tt.wantState, state)
+			}
+		})
+	}
+}
+
+// TestRunWithResultIf_Error verifies that an error from fn rolls back and
+// returns the zero value without consulting the predicate.
+func TestRunWithResultIf_Error(t *testing.T) {
+	fnErr := errors.New("fn failed")
+	u := New(NewMockTx())
+	got, err := RunWithResultIf(context.Background(), &u, func(_ context.Context) (int, error) {
+		return 42, fnErr
+	}, func(int) bool {
+		t.Error("expected predicate not to be called")
+		return true
+	})
+	if !errors.Is(err, fnErr) {
+		t.Errorf("expected fn error, got %v", err)
+	}
+	if got != 0 {
+		t.Errorf("expected zero value, got %d", got)
+	}
+}
+
+// TestRunResult verifies that RunResult returns the untyped value on commit
+// and nil with the composed error on rollback.
+func TestRunResult(t *testing.T) {
+	mt := NewMockTx()
+	u := New(mt)
+	v, err := RunResult(context.Background(), &u, func(_ context.Context) (any, error) {
+		return 42, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 42 {
+		t.Errorf("expected 42, got %v", v)
+	}
+	if state := mt.State().Value(); state != " committed!" {
+		t.Errorf("expected commit, got %q", state)
+	}
+
+	fnErr := errors.New("insert failed")
+	rbErr := errors.New("rollback failed")
+	u = New(&errorRunner{rollbackErr: rbErr})
+	v, err = RunResult(context.Background(), &u, func(_ context.Context) (any, error) {
+		return 43, fnErr
+	})
+	var rollbackErr *RollbackError
+	if !errors.As(err, &rollbackErr) || !errors.Is(err, fnErr) || !errors.Is(err, rbErr) {
+		t.Errorf("expected a RollbackError with both errors, got %v", err)
+	}
+	if v != nil {
+		t.Errorf("expected nil, got %v", v)
+	}
+}
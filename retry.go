@@ -0,0 +1,257 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// WithMaxRetries sets how many times a failed unit of work is retried. Each
+// retry rolls back the failed attempt, starts a new transaction and runs fn
+// again. Serialization failures, see IsSerializationFailure, and errors
+// accepted by a classifier registered with WithRetryIf are retried; any other
+// error is returned immediately.
+//
+// Since fn may run several times, it must not have side effects outside the
+// transaction, such as sending messages or calling external APIs, unless they
+// are idempotent; defer them with OnCommit or check MayRetry instead.
+func WithMaxRetries(n int) Option {
+	return func(c *config) {
+		c.maxRetries = n
+	}
+}
+
+// WithRetryIf registers a classifier reporting whether an error may be
+// retried. Classifiers are cumulative: an error is retried when any of them
+// accepts it.
+func WithRetryIf(fn func(err error) bool) Option {
+	return func(c *config) {
+		c.retryIf = append(c.retryIf, fn)
+	}
+}
+
+// WithRetryOnDeadline tells the two ways a unit of work can be interrupted
+// apart: an attempt failing with context.DeadlineExceeded, e.g. because a
+// statement timeout, a TimeoutRunner or a deadline set inside fn expired, is
+// retried within the budget of WithMaxRetries, while context.Canceled, e.g.
+// because the client went away, is terminal and only rolls back, even when a
+// classifier registered with WithRetryIf accepts it. The context passed to Run
+// is the larger budget: once it is done, nothing is retried. Like
+// serialization failures, deadlines are not retried by a unit of work nested
+// in another one.
+func WithRetryOnDeadline() Option {
+	return func(c *config) {
+		c.retryDeadline = true
+	}
+}
+
+// WithBackoff sets the delay before the first retry. The delay doubles with
+// every further retry. The wait is aborted when the context is done, in which
+// case the error of the last attempt is returned.
+func WithBackoff(base time.Duration) Option {
+	return func(c *config) {
+		c.backoff = base
+	}
+}
+
+// WithRetryBackoff sets an exponential backoff between retries: the delay
+// before the first retry is base and doubles with every further retry, up to
+// max; a zero max leaves it practically unbounded. With jitter, each delay is
+// instead drawn at random between zero and that value, so that units of work
+// failing together, e.g. on the same conflict, do not retry in lockstep.
+func WithRetryBackoff(base, max time.Duration, jitter bool) Option {
+	return func(c *config) {
+		c.backoff = base
+		c.backoffMax = max
+		c.jitter = jitter
+	}
+}
+
+// WithMaxElapsed bounds the total time spent retrying a unit of work, so that
+// retries do not outlive the budget of the request. Before each retry, Run
+// gives up when the time elapsed since the first attempt plus the backoff
+// delay would reach d.
+func WithMaxElapsed(d time.Duration) Option {
+	return func(c *config) {
+		c.maxElapsed = d
+	}
+}
+
+// IsMongoTransient reports whether err carries one of the MongoDB labels that
+// mark a transaction as safe to retry: "TransientTransactionError" or
+// "UnknownTransactionCommitResult". An error carries labels when it, or an
+// error it wraps, implements mongo.LabeledError, i.e. has a
+// HasErrorLabel(label string) bool method, as the errors of the MongoDB
+// driver and MockLabeledError do. Use it with WithRetryIf:
+//
+//	uow.New(runner, uow.WithMaxRetries(3), uow.WithRetryIf(uow.IsMongoTransient))
+func IsMongoTransient(err error) bool {
+	var le mongo.LabeledError
+	if !errors.As(err, &le) {
+		return false
+	}
+	return le.HasErrorLabel("TransientTransactionError") ||
+		le.HasErrorLabel("UnknownTransactionCommitResult")
+}
+
+// MayRetry reports whether the current attempt could be followed by another
+// one if it fails, i.e. whether the retry policy has attempts left. Code
+// inside fn can use it to defer non-idempotent side effects, such as calling
+// external APIs, to the final attempt. It returns false outside a unit of
+// work.
+func MayRetry(ctx context.Context) bool {
+	rs := runStateFrom(ctx)
+	if rs == nil {
+		return false
+	}
+	return rs.attempt < rs.maxAttempts
+}
+
+// IsSerializationFailure reports whether err carries the SQLSTATE 40001
+// (serialization_failure) that PostgreSQL reports when a SERIALIZABLE or
+// REPEATABLE READ transaction conflicts with a concurrent one. The whole
+// transaction has to be run again, so Run retries such failures when
+// WithMaxRetries allows it. Errors of both github.com/lib/pq and
+// github.com/jackc/pgx are recognized through their SQLState method.
+func IsSerializationFailure(err error) bool {
+	var se interface{ SQLState() string }
+	return errors.As(err, &se) && se.SQLState() == "40001"
+}
+
+// runWithRetry runs fn through run, retrying retryable failures according to
+// the configured policy. It returns the state of the final attempt.
+func (u *UoW) runWithRetry(ctx context.Context, fn func(ctx context.Context) error, rc *runConfig) (*runState, error) {
+	maxAttempts := u.config.maxRetries + 1
+	parent := runStateFrom(ctx)
+	start := u.clock().Now()
+
+	for attempt := 1; ; attempt++ {
+		rs := &runState{
+			txID:        rc.txID,
+			attempt:     attempt,
+			maxAttempts: maxAttempts,
+
+			statementTimeout: u.config.statementTimeout,
+			readOnly:         u.config.readOnly || rc.readOnly,
+			dryRun:           rc.dryRun,
+			timeout:          rc.timeout,
+
+			runner: u.runner,
+			uow:    u,
+			parent: parent,
+		}
+		err := u.run(ctx, fn, rs)
+		if err == nil || rs.keptErr || !u.retryable(err, parent) || ctx.Err() != nil {
+			return rs, err
+		}
+		if attempt >= maxAttempts {
+			if maxAttempts > 1 {
+				return rs, fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+			}
+			return rs, err
+		}
+		delay := u.backoffDelay(attempt)
+		if elapsed := u.clock().Now().Sub(start); u.config.maxElapsed > 0 && elapsed+delay >= u.config.maxElapsed {
+			return rs, fmt.Errorf("giving up after %d attempts in %v: %w", attempt, elapsed, err)
+		}
+		if !u.waitBackoff(ctx, delay) {
+			return rs, err
+		}
+	}
+}
+
+// retryable reports whether err is a serialization failure or is accepted by
+// one of the retry classifiers. A serialization failure aborts the whole
+// transaction, so it is not retried by a unit of work nested in another one,
+// whose transaction cannot be restarted from inside; parent is the enclosing
+// run, if any. With WithRetryOnDeadline, the same holds for
+// context.DeadlineExceeded, and context.Canceled is never retried.
+func (u *UoW) retryable(err error, parent *runState) bool {
+	if u.config.retryDeadline {
+		if errors.Is(err, context.Canceled) {
+			return false
+		}
+		if parent == nil && errors.Is(err, context.DeadlineExceeded) {
+			return true
+		}
+	}
+	if parent == nil && IsSerializationFailure(err) {
+		return true
+	}
+	for _, isRetryable := range u.config.retryIf {
+		if isRetryable(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// backoffCeiling caps the backoff delay when WithRetryBackoff sets no
+// maximum, so that doubling it cannot overflow. It is far beyond any useful
+// delay.
+const backoffCeiling = time.Duration(math.MaxInt64 / 2)
+
+// backoffDelay returns the delay before the retry following attempt.
+func (u *UoW) backoffDelay(attempt int) time.Duration {
+	if u.config.backoff <= 0 {
+		return 0
+	}
+	limit := u.config.backoffMax
+	if limit <= 0 || limit > backoffCeiling {
+		limit = backoffCeiling
+	}
+	delay := u.config.backoff
+	for i := 1; i < attempt && delay < limit; i++ {
+		delay *= 2
+	}
+	if delay > limit {
+		delay = limit
+	}
+	if u.config.jitter {
+		delay = time.Duration(rand.Int64N(int64(delay) + 1))
+	}
+	return delay
+}
+
+// waitBackoff waits for delay before a retry. It returns false when ctx is
+// done before the delay has passed.
+func (u *UoW) waitBackoff(ctx context.Context, delay time.Duration) bool {
+	if delay <= 0 {
+		return true
+	}
+	select {
+	case <-u.clock().After(delay):
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// clock abstracts the passing of time for the retry loop, so that tests can
+// control it.
+type clock interface {
+	Now() time.Time
+	After(d time.Duration) <-chan time.Time
+}
+
+// realClock is the clock backed by the time package.
+type realClock struct{}
+
+// Now returns the current time.
+func (realClock) Now() time.Time { return time.Now() }
+
+// After waits for d to elapse and then sends the current time.
+func (realClock) After(d time.Duration) <-chan time.Time { return time.After(d) }
+
+// clock returns the clock used by the retry loop.
+func (u *UoW) clock() clock {
+	if u.config.clock != nil {
+		return u.config.clock
+	}
+	return realClock{}
+}
@@ -0,0 +1,391 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// errRetryable is a retryable error used by the retry tests.
+var errRetryable = errors.New("retryable")
+
+// isErrRetryable classifies errRetryable as retryable.
+func isErrRetryable(err error) bool {
+	return errors.Is(err, errRetryable)
+}
+
+// TestRun_Retry verifies that retryable errors are retried up to the
+// configured limit and that the last attempt's outcome is returned.
+func TestRun_Retry(t *testing.T) {
+	mt := NewMockTx()
+	u := New(mt, WithMaxRetries(2), WithRetryIf(isErrRetryable))
+
+	attempts := 0
+	err := u.Run(context.Background(), func(_ context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errRetryable
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+	if got := mt.state.Value(); got != " rolled back! rolled back! committed!" {
+		t.Errorf("unexpected state %q", got)
+	}
+}
+
+// TestRun_RetryNonRetryable verifies that an error not accepted by a
+// classifier is returned immediately.
+func TestRun_RetryNonRetryable(t *testing.T) {
+	fnErr := errors.New("fatal")
+	u := New(NewMockTx(), WithMaxRetries(3), WithRetryIf(isErrRetryable))
+
+	attempts := 0
+	err := u.Run(context.Background(), func(_ context.Context) error {
+		attempts++
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) {
+		t.Errorf("expected fn error, got %v", err)
+	}
+	if attempts != 1 {
+		t.Errorf("expected 1 attempt, got %d", attempts)
+	}
+}
+
+// TestMayRetry verifies that MayRetry is true on non-final attempts and false
+// on the last one.
+func TestMayRetry(t *testing.T) {
+	u := New(NewMockTx(), WithMaxRetries(2), WithRetryIf(isErrRetryable))
+
+	var got []bool
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		got = append(got, MayRetry(ctx))
+		return errRetryable
+	})
+	if !errors.Is(err, errRetryable) {
+		t.Errorf("expected retryable error after exhausting attempts, got %v", err)
+	}
+
+	want := []bool{true, true, false}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("expected MayRetry %v, got %v", want, got)
+	}
+}
+
+// TestMayRetry_NoPolicy verifies that MayRetry is false without a retry policy
+// and outside a unit of work.
+func TestMayRetry_NoPolicy(t *testing.T) {
+	u := New(NewMockTx())
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		if MayRetry(ctx) {
+			t.Error("expected MayRetry to be false without retries")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if MayRetry(context.Background()) {
+		t.Error("expected MayRetry to be false outside a unit of work")
+	}
+}
+
+// TestWithBackoff verifies that retries wait for the doubling backoff and that
+// a done context stops the wait.
+func TestWithBackoff(t *testing.T) {
+	u := New(NewMockTx(), WithMaxRetries(2), WithRetryIf(isErrRetryable), WithBackoff(10*time.Millisecond))
+
+	start := time.Now()
+	err := u.Run(context.Background(), func(_ context.Context) error {
+		return errRetryable
+	})
+	if !errors.Is(err, errRetryable) {
+		t.Fatalf("expected retryable error, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond {
+		t.Errorf("expected at least 30ms of backoff, got %v", elapsed)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	u = New(NewMockTx(), WithMaxRetries(2), WithRetryIf(isErrRetryable), WithBackoff(time.Hour))
+	attempts := 0
+	err = u.Run(ctx, func(_ context.Context) error {
+		attempts++
+		time.AfterFunc(10*time.Millisecond, cancel)
+		return errRetryable
+	})
+	if !errors.Is(err, errRetryable) || attempts != 1 {
+		t.Errorf("expected one attempt and the retryable error, got %d and %v", attempts, err)
+	}
+}
+
+// TestIsMongoTransient verifies that the transaction retry labels are
+// recognized, also when wrapped.
+func TestIsMongoTransient(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "transient", err: mongo.CommandError{Labels: []string{"TransientTransactionError"}}, want: true},
+		{name: "unknown_commit", err: mongo.CommandError{Labels: []string{"UnknownTransactionCommitResult"}}, want: true},
+		{name: "wrapped", err: fmt.Errorf("insert: %w", mongo.CommandError{Labels: []string{"TransientTransactionError"}}), want: true},
+		{name: "other_label", err: mongo.CommandError{Labels: []string{"NetworkError"}}, want: false},
+		{name: "plain", err: errors.New("duplicate key"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsMongoTransient(tt.err); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestIsSerializationFailure verifies that SQLSTATE 40001 is recognized.
+func TestIsSerializationFailure(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "pgx", err: &pgconn.PgError{Code: "40001"}, want: true},
+		{name: "wrapped", err: fmt.Errorf("update: %w", &pgconn.PgError{Code: "40001"}), want: true},
+		{name: "unique_violation", err: &pgconn.PgError{Code: "23505"}, want: false},
+		{name: "plain", err: errors.New("could not serialize access"), want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsSerializationFailure(tt.err); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+// TestRun_RetrySerializationFailure verifies that serialization failures are
+// retried without a classifier, each time in a new transaction, and that a
+// nested unit of work leaves the retry to the outermost one.
+func TestRun_RetrySerializationFailure(t *testing.T) {
+	serializationErr := &pgconn.PgError{Code: "40001"}
+	mt := NewMockTx()
+	u := New(mt, WithMaxRetries(2))
+
+	calls := 0
+	err := u.Run(context.Background(), func(_ context.Context) error {
+		calls++
+		if calls == 1 {
+			return serializationErr
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"ctx", "rollback", "ctx", "commit"}; !reflect.DeepEqual(mt.Ops(), want) {
+		t.Errorf("expected calls %v, got %v", want, mt.Ops())
+	}
+
+	inner, outer := 0, 0
+	err = u.Run(context.Background(), func(ctx context.Context) error {
+		outer++
+		return u.Run(ctx, func(_ context.Context) error {
+			inner++
+			if outer == 1 {
+				return serializationErr
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inner != 2 || outer != 2 {
+		t.Errorf("expected the outer unit of work to retry, got %d outer and %d inner attempts", outer, inner)
+	}
+}
+
+// fakeClock is a clock whose After returns immediately and advances the
+// current time by the requested delay, recording it.
+type fakeClock struct {
+	now    time.Time
+	delays []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) After(d time.Duration) <-chan time.Time {
+	c.delays = append(c.delays, d)
+	c.now = c.now.Add(d)
+	ch := make(chan time.Time, 1)
+	ch <- c.now
+	return ch
+}
+
+// TestWithRetryBackoff verifies that the backoff doubles up to its maximum,
+// that jitter keeps every delay within it, and that the error of the last
+// attempt is wrapped with the attempt count.
+func TestWithRetryBackoff(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	u := New(NewMockTx(), WithMaxRetries(4), WithRetryIf(isErrRetryable),
+		WithRetryBackoff(10*time.Millisecond, 25*time.Millisecond, false))
+	u.config.clock = clock
+
+	err := u.Run(context.Background(), func(_ context.Context) error {
+		return errRetryable
+	})
+	if !errors.Is(err, errRetryable) {
+		t.Fatalf("expected retryable error, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "5 attempts") {
+		t.Errorf("expected the error to name the attempt count, got %q", err)
+	}
+	want := []time.Duration{10 * time.Millisecond, 20 * time.Millisecond, 25 * time.Millisecond, 25 * time.Millisecond}
+	if !reflect.DeepEqual(clock.delays, want) {
+		t.Errorf("expected delays %v, got %v", want, clock.delays)
+	}
+
+	clock = &fakeClock{now: time.Unix(0, 0)}
+	u = New(NewMockTx(), WithMaxRetries(4), WithRetryIf(isErrRetryable),
+		WithRetryBackoff(10*time.Millisecond, 25*time.Millisecond, true))
+	u.config.clock = clock
+	_ = u.Run(context.Background(), func(_ context.Context) error {
+		return errRetryable
+	})
+	for i, d := range clock.delays {
+		if d < 0 || d > want[i] {
+			t.Errorf("expected jittered delay %d within [0, %v], got %v", i, want[i], d)
+		}
+	}
+}
+
+// TestWithRetryBackoff_Unbounded verifies that an unbounded backoff stops
+// growing instead of overflowing after many attempts, with and without
+// jitter.
+func TestWithRetryBackoff_Unbounded(t *testing.T) {
+	for _, jitter := range []bool{false, true} {
+		clock := &fakeClock{now: time.Unix(0, 0)}
+		u := New(NewMockTx(), WithMaxRetries(100), WithRetryIf(isErrRetryable),
+			WithRetryBackoff(time.Millisecond, 0, jitter))
+		u.config.clock = clock
+
+		_ = u.Run(context.Background(), func(_ context.Context) error {
+			return errRetryable
+		})
+		if len(clock.delays) != 100 {
+			t.Fatalf("jitter %v: expected 100 delays, got %d", jitter, len(clock.delays))
+		}
+		prev := time.Duration(0)
+		for i, d := range clock.delays {
+			if d < 0 || d > backoffCeiling || (!jitter && d < prev) {
+				t.Fatalf("jitter %v: delay %d out of range: %v after %v", jitter, i, d, prev)
+			}
+			prev = d
+		}
+		if !jitter && prev != backoffCeiling {
+			t.Errorf("expected the delay to settle at the ceiling, got %v", prev)
+		}
+	}
+}
+
+// TestWithMaxElapsed verifies that retries stop once the elapsed time plus the
+// next delay would exceed the budget.
+func TestWithMaxElapsed(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	u := New(NewMockTx(), WithMaxRetries(10), WithRetryIf(isErrRetryable),
+		WithRetryBackoff(10*time.Millisecond, 0, false), WithMaxElapsed(50*time.Millisecond))
+	u.config.clock = clock
+
+	attempts := 0
+	err := u.Run(context.Background(), func(_ context.Context) error {
+		attempts++
+		return errRetryable
+	})
+	if !errors.Is(err, errRetryable) {
+		t.Fatalf("expected retryable error, got %v", err)
+	}
+	// The delays of 10ms and 20ms fit the budget, the following 40ms does not.
+	if attempts != 3 {
+		t.Errorf("expected 3 attempts, got %d", attempts)
+	}
+	if !strings.Contains(err.Error(), "3 attempts") {
+		t.Errorf("expected the error to name the attempt count, got %q", err)
+	}
+}
+
+// TestWithRetryOnDeadline verifies that a deadline returned by fn is retried
+// while a cancellation only rolls back, and that neither is retried once the
+// context passed to Run is done.
+func TestWithRetryOnDeadline(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         []Option
+		err          error
+		wantAttempts int
+	}{
+		{name: "deadline", opts: []Option{WithRetryOnDeadline()}, err: context.DeadlineExceeded, wantAttempts: 2},
+		{name: "wrapped deadline", opts: []Option{WithRetryOnDeadline()}, err: fmt.Errorf("query: %w", context.DeadlineExceeded), wantAttempts: 2},
+		{name: "deadline without option", err: context.DeadlineExceeded, wantAttempts: 1},
+		{name: "canceled", opts: []Option{WithRetryOnDeadline()}, err: context.Canceled, wantAttempts: 1},
+		{name: "canceled accepted by classifier", opts: []Option{WithRetryOnDeadline(), WithRetryIf(func(error) bool { return true })}, err: context.Canceled, wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt := NewMockTx()
+			u := New(mt, append([]Option{WithMaxRetries(2)}, tt.opts...)...)
+
+			attempts := 0
+			err := u.Run(context.Background(), func(_ context.Context) error {
+				attempts++
+				if attempts == 1 {
+					return tt.err
+				}
+				return nil
+			})
+			if attempts != tt.wantAttempts {
+				t.Errorf("expected %d attempts, got %d", tt.wantAttempts, attempts)
+			}
+			if tt.wantAttempts == 1 && !errors.Is(err, tt.err) {
+				t.Errorf("expected %v, got %v", tt.err, err)
+			}
+			if tt.wantAttempts > 1 && (err != nil || mt.CallCount("commit") != 1) {
+				t.Errorf("expected the retry to commit, got %v", err)
+			}
+			if n := mt.CallCount("rollback"); n != 1 {
+				t.Errorf("expected the interrupted attempt to roll back, got %d rollbacks", n)
+			}
+		})
+	}
+
+	t.Run("budget exhausted", func(t *testing.T) {
+		u := New(NewMockTx(), WithMaxRetries(2), WithRetryOnDeadline())
+		ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+
+		attempts := 0
+		err := u.Run(ctx, func(ctx context.Context) error {
+			attempts++
+			<-ctx.Done()
+			return ctx.Err()
+		})
+		if attempts != 1 || !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("expected a single attempt failing with the deadline, got %d and %v", attempts, err)
+		}
+	})
+}
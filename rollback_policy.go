@@ -0,0 +1,23 @@
+package uow
+
+import "errors"
+
+// WithShouldRollback sets a predicate that decides whether an error returned by
+// fn rolls the transaction back. When it returns false, the work done by fn
+// is committed and Run returns the error anyway, e.g. for a recoverable "no
+// rows updated" condition that the caller still wants to know about. A unit of
+// work committed this way is never retried. Errors raised around fn, such as
+// failing preconditions or hooks, and ErrAbort always roll back. Without this
+// option every error from fn rolls back.
+func WithShouldRollback(shouldRollback func(err error) bool) Option {
+	return func(c *config) {
+		c.shouldRollback = shouldRollback
+	}
+}
+
+// keepOnCommit reports whether err returned by fn should be committed instead
+// of rolled back. An abort is never committed.
+func (u *UoW) keepOnCommit(err error) bool {
+	return err != nil && !errors.Is(err, ErrAbort) &&
+		u.config.shouldRollback != nil && !u.config.shouldRollback(err)
+}
@@ -0,0 +1,120 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestWithShouldRollback verifies that an error accepted by the predicate is
+// committed and still returned, without being retried, while other errors
+// roll back as before.
+func TestWithShouldRollback(t *testing.T) {
+	errNoRows := errors.New("no rows updated")
+	mt := NewMockTx()
+	u := New(mt,
+		WithShouldRollback(func(err error) bool { return !errors.Is(err, errNoRows) }),
+		WithMaxRetries(2),
+		WithRetryIf(func(error) bool { return true }),
+	)
+
+	calls := 0
+	err := u.Run(context.Background(), func(_ context.Context) error {
+		calls++
+		return errNoRows
+	})
+	if !errors.Is(err, errNoRows) {
+		t.Errorf("expected the fn error, got %v", err)
+	}
+	if got := mt.State().Status(); got != StateCommitted {
+		t.Errorf("expected a commit, got status %v", got)
+	}
+	if calls != 1 {
+		t.Errorf("expected a committed unit of work not to be retried, got %d calls", calls)
+	}
+
+	fnErr := errors.New("fn failed")
+	err = u.Run(context.Background(), func(_ context.Context) error { return fnErr })
+	if !errors.Is(err, fnErr) {
+		t.Errorf("expected the fn error, got %v", err)
+	}
+	if got := mt.State().Status(); got != StateRolledBack {
+		t.Errorf("expected a rollback, got status %v", got)
+	}
+}
+
+// TestWithShouldRollback_Default verifies that every error from fn rolls back
+// without the option.
+func TestWithShouldRollback_Default(t *testing.T) {
+	mt := NewMockTx()
+	u := New(mt)
+
+	fnErr := errors.New("no rows updated")
+	err := u.Run(context.Background(), func(_ context.Context) error { return fnErr })
+	if !errors.Is(err, fnErr) {
+		t.Errorf("expected the fn error, got %v", err)
+	}
+	if got := mt.State().Status(); got != StateRolledBack {
+		t.Errorf("expected a rollback, got status %v", got)
+	}
+}
+
+// TestWithShouldRollback_AroundFn verifies that errors of the after-begin hooks
+// and preconditions roll back even when the predicate would keep them.
+func TestWithShouldRollback_AroundFn(t *testing.T) {
+	keep := WithShouldRollback(func(error) bool { return false })
+	hookErr := errors.New("hook failed")
+	tests := []struct {
+		name string
+		opt  Option
+	}{
+		{name: "after_begin", opt: WithAfterBegin(func(context.Context) error { return hookErr })},
+		{name: "precondition", opt: WithPrecondition(func(context.Context) error { return hookErr })},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mt := NewMockTx()
+			u := New(mt, keep, tt.opt)
+			ran := false
+			err := u.Run(context.Background(), func(_ context.Context) error {
+				ran = true
+				return nil
+			})
+			if !errors.Is(err, hookErr) {
+				t.Errorf("expected the hook error, got %v", err)
+			}
+			if ran {
+				t.Error("expected fn not to run")
+			}
+			if got := mt.State().Status(); got != StateRolledBack {
+				t.Errorf("expected a rollback, got status %v", got)
+			}
+		})
+	}
+}
+
+// TestWithShouldRollback_Abort verifies that an abort rolls back even when the
+// predicate would keep it.
+func TestWithShouldRollback_Abort(t *testing.T) {
+	mt := NewMockTx()
+	u := New(mt, WithShouldRollback(func(error) bool { return false }))
+
+	if err := u.Run(context.Background(), func(_ context.Context) error { return ErrAbort }); err != nil {
+		t.Errorf("expected a silent abort, got %v", err)
+	}
+	if got := mt.State().Status(); got != StateRolledBack {
+		t.Errorf("expected a rollback, got status %v", got)
+	}
+
+	mt.Reset()
+	_, err := RunWithResultIf(context.Background(), &u, func(_ context.Context) (int, error) {
+		return 1, nil
+	}, func(int) bool { return false })
+	if err != nil {
+		t.Errorf("expected a silent abort, got %v", err)
+	}
+	if got := mt.State().Status(); got != StateRolledBack {
+		t.Errorf("expected a rollback, got status %v", got)
+	}
+}
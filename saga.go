@@ -0,0 +1,67 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Saga coordinates a sequence of independent units of work that cannot share a
+// single transaction, for example because they target different stores. Each
+// step runs its action in its own transaction. When a step fails, the
+// compensations of all previously committed steps run in reverse order, each
+// in its own transaction, to undo their effects.
+type Saga struct {
+	steps []sagaStep
+}
+
+// sagaStep is a single step of a Saga.
+type sagaStep struct {
+	uow          *UoW
+	action       func(ctx context.Context) error
+	compensation func(ctx context.Context) error
+}
+
+// NewSaga creates a new, empty Saga.
+func NewSaga() *Saga {
+	return &Saga{}
+}
+
+// Step appends a step to the saga. The action runs through u when the step is
+// reached; the compensation runs through u if a later step fails. A nil
+// compensation means the step has nothing to undo. Step returns the saga to
+// allow chaining.
+func (s *Saga) Step(u *UoW, action, compensation func(ctx context.Context) error) *Saga {
+	s.steps = append(s.steps, sagaStep{
+		uow:          u,
+		action:       action,
+		compensation: compensation,
+	})
+	return s
+}
+
+// Run executes the steps in order. If a step fails, the compensations of the
+// already committed steps run in reverse order and the step error is returned.
+// Compensation failures do not stop the remaining compensations; they are
+// joined to the returned error.
+func (s *Saga) Run(ctx context.Context) error {
+	for i, step := range s.steps {
+		err := step.uow.Run(ctx, step.action)
+		if err == nil {
+			continue
+		}
+
+		errs := []error{fmt.Errorf("saga step %d failed: %w", i, err)}
+		for j := i - 1; j >= 0; j-- {
+			done := s.steps[j]
+			if done.compensation == nil {
+				continue
+			}
+			if cErr := done.uow.Run(ctx, done.compensation); cErr != nil {
+				errs = append(errs, fmt.Errorf("saga step %d compensation failed: %w", j, cErr))
+			}
+		}
+		return errors.Join(errs...)
+	}
+	return nil
+}
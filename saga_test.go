@@ -0,0 +1,74 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// TestSaga_CompensatesOnFailure verifies that when the third step fails, the
+// first two steps are compensated in reverse order.
+func TestSaga_CompensatesOnFailure(t *testing.T) {
+	ctx := context.Background()
+	stepErr := errors.New("step 3 failed")
+
+	var order []string
+	record := func(name string, err error) func(ctx context.Context) error {
+		return func(_ context.Context) error {
+			order = append(order, name)
+			return err
+		}
+	}
+
+	mocks := []*MockTx{NewMockTx(), NewMockTx(), NewMockTx()}
+	u1, u2, u3 := New(mocks[0]), New(mocks[1]), New(mocks[2])
+
+	err := NewSaga().
+		Step(&u1, record("action1", nil), record("compensate1", nil)).
+		Step(&u2, record("action2", nil), record("compensate2", nil)).
+		Step(&u3, record("action3", stepErr), record("compensate3", nil)).
+		Run(ctx)
+	if !errors.Is(err, stepErr) {
+		t.Fatalf("expected step error, got %v", err)
+	}
+
+	want := []string{"action1", "action2", "action3", "compensate2", "compensate1"}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("expected order %v, got %v", want, order)
+	}
+
+	// Steps 1 and 2 committed their action and their compensation; step 3
+	// rolled back its action.
+	if got := mocks[0].state.Value(); got != " committed! committed!" {
+		t.Errorf("unexpected state for step 1: %q", got)
+	}
+	if got := mocks[2].state.Value(); got != " rolled back!" {
+		t.Errorf("unexpected state for step 3: %q", got)
+	}
+}
+
+// TestSaga_CompensationError verifies that a failing compensation is reported
+// and does not prevent the remaining compensations from running.
+func TestSaga_CompensationError(t *testing.T) {
+	ctx := context.Background()
+	stepErr := errors.New("step failed")
+	compErr := errors.New("compensation failed")
+
+	compensated := false
+	u := New(NewMockTx())
+	err := NewSaga().
+		Step(&u, func(_ context.Context) error { return nil }, func(_ context.Context) error {
+			compensated = true
+			return nil
+		}).
+		Step(&u, func(_ context.Context) error { return nil }, func(_ context.Context) error { return compErr }).
+		Step(&u, func(_ context.Context) error { return stepErr }, nil).
+		Run(ctx)
+	if !errors.Is(err, stepErr) || !errors.Is(err, compErr) {
+		t.Errorf("expected step and compensation errors, got %v", err)
+	}
+	if !compensated {
+		t.Error("expected first step to be compensated")
+	}
+}
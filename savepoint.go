@@ -0,0 +1,32 @@
+package uow
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrNoTransaction is returned by operations that require an active
+// transaction when the context does not carry one.
+var ErrNoTransaction = errors.New("no transaction in context")
+
+// ErrSavepointsUnsupported is returned when a feature relying on savepoints is
+// used with a runner that does not implement Savepointer.
+var ErrSavepointsUnsupported = errors.New("runner does not support savepoints")
+
+// Savepointer is implemented by runners whose transactions support savepoints.
+// Savepoint names must be valid SQL identifiers.
+type Savepointer interface {
+	// Savepoint creates a savepoint with the given name in the current
+	// transaction.
+	Savepoint(ctx context.Context, name string) error
+
+	// RollbackToSavepoint undoes the changes made since the named savepoint
+	// was created, keeping the transaction open.
+	RollbackToSavepoint(ctx context.Context, name string) error
+
+	// ReleaseSavepoint forgets the named savepoint, keeping the changes made
+	// since it was created.
+	ReleaseSavepoint(ctx context.Context, name string) error
+}
+
+var _ Savepointer = &SQLTx{}
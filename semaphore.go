@@ -0,0 +1,171 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"golang.org/x/sync/semaphore"
+)
+
+// semaphorePermitKey is the context key for storing the permit held by a
+// SemaphoreRunner transaction.
+var semaphorePermitKey = ctxKey{"semaphore_permit"}
+
+// ErrConcurrencyLimit is returned by a SemaphoreRunner created with
+// WithFailFast when the maximum number of transactions is already in flight.
+var ErrConcurrencyLimit = errors.New("concurrency limit reached")
+
+// SemaphoreRunner implements the Runner interface by wrapping another runner
+// and capping the number of its transactions in flight at once, protecting
+// the data store from connection exhaustion. Ctx acquires a permit of a
+// weighted semaphore before starting the transaction, waiting until one is
+// free or the context is done, and Commit and Rollback release it. Nested
+// units of work join the permit of the outermost one, so they never wait for
+// themselves. Savepoints, closing and compensation are forwarded to the
+// wrapped runner.
+var _ Runner = &SemaphoreRunner{}
+
+var (
+	_ Savepointer = &SemaphoreRunner{}
+	_ Compensator = &SemaphoreRunner{}
+	_ io.Closer   = &SemaphoreRunner{}
+)
+
+// SemaphoreRunner struct holds the wrapped runner and the semaphore.
+type SemaphoreRunner struct {
+	runner   Runner
+	sem      *semaphore.Weighted
+	limit    int64
+	failFast bool
+}
+
+// semaphorePermit is stored in the context for the duration of a transaction.
+// Only the permit of the outermost transaction is owned and released.
+type semaphorePermit struct {
+	runner *SemaphoreRunner
+	owned  bool
+	once   sync.Once
+}
+
+// SemaphoreOption configures optional behavior of a SemaphoreRunner. Options
+// are passed to NewSemaphoreRunner.
+type SemaphoreOption func(*SemaphoreRunner)
+
+// WithFailFast makes Ctx return ErrConcurrencyLimit instead of waiting when
+// no permit is free.
+func WithFailFast() SemaphoreOption {
+	return func(r *SemaphoreRunner) {
+		r.failFast = true
+	}
+}
+
+// NewSemaphoreRunner creates a new SemaphoreRunner instance. It takes the
+// runner to wrap, the maximum number of transactions in flight and optional
+// settings as arguments.
+func NewSemaphoreRunner(runner Runner, limit int64, opts ...SemaphoreOption) *SemaphoreRunner {
+	r := &SemaphoreRunner{
+		runner: runner,
+		sem:    semaphore.NewWeighted(limit),
+		limit:  limit,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Ctx acquires a permit and starts a transaction on the wrapped runner. The
+// permit is released again when the transaction cannot be started.
+func (r *SemaphoreRunner) Ctx(ctx context.Context) (context.Context, error) {
+	if p, ok := ctx.Value(semaphorePermitKey).(*semaphorePermit); ok && p.runner == r {
+		txCtx, err := r.runner.Ctx(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return context.WithValue(txCtx, semaphorePermitKey, &semaphorePermit{runner: r}), nil
+	}
+
+	if r.failFast {
+		if !r.sem.TryAcquire(1) {
+			return nil, fmt.Errorf("%w: %d transactions in flight", ErrConcurrencyLimit, r.limit)
+		}
+	} else if err := r.sem.Acquire(ctx, 1); err != nil {
+		return nil, fmt.Errorf("error in waiting for a permit: %w", err)
+	}
+
+	txCtx, err := r.runner.Ctx(ctx)
+	if err != nil {
+		r.sem.Release(1)
+		return nil, err
+	}
+	return context.WithValue(txCtx, semaphorePermitKey, &semaphorePermit{runner: r, owned: true}), nil
+}
+
+// Get delegates to the wrapped runner.
+func (r *SemaphoreRunner) Get(ctx context.Context) any {
+	return r.runner.Get(ctx)
+}
+
+// Rollback rolls back the wrapped runner's transaction and releases the
+// permit.
+func (r *SemaphoreRunner) Rollback(ctx context.Context) error {
+	defer r.release(ctx)
+	return r.runner.Rollback(ctx)
+}
+
+// Commit commits the wrapped runner's transaction and releases the permit.
+func (r *SemaphoreRunner) Commit(ctx context.Context) error {
+	defer r.release(ctx)
+	return r.runner.Commit(ctx)
+}
+
+// unwrap returns the wrapped runner.
+func (r *SemaphoreRunner) unwrap() Runner {
+	return r.runner
+}
+
+// Savepoint forwards to the wrapped runner. It returns
+// ErrSavepointsUnsupported when the wrapped runner does not implement
+// Savepointer.
+func (r *SemaphoreRunner) Savepoint(ctx context.Context, name string) error {
+	return savepoint(ctx, r.runner, name)
+}
+
+// RollbackToSavepoint forwards to the wrapped runner. It returns
+// ErrSavepointsUnsupported when the wrapped runner does not implement
+// Savepointer.
+func (r *SemaphoreRunner) RollbackToSavepoint(ctx context.Context, name string) error {
+	return rollbackToSavepoint(ctx, r.runner, name)
+}
+
+// ReleaseSavepoint forwards to the wrapped runner. It returns
+// ErrSavepointsUnsupported when the wrapped runner does not implement
+// Savepointer.
+func (r *SemaphoreRunner) ReleaseSavepoint(ctx context.Context, name string) error {
+	return releaseSavepoint(ctx, r.runner, name)
+}
+
+// Compensate forwards to the wrapped runner. It returns
+// ErrCompensationUnsupported when the wrapped runner does not implement
+// Compensator.
+func (r *SemaphoreRunner) Compensate(ctx context.Context) error {
+	return compensate(ctx, r.runner)
+}
+
+// Close closes the wrapped runner if it implements io.Closer.
+func (r *SemaphoreRunner) Close() error {
+	return closeRunner(r.runner)
+}
+
+// release returns the permit of the transaction in ctx, once, if it is owned
+// by the outermost transaction of r.
+func (r *SemaphoreRunner) release(ctx context.Context) {
+	p, ok := ctx.Value(semaphorePermitKey).(*semaphorePermit)
+	if !ok || p.runner != r || !p.owned {
+		return
+	}
+	p.once.Do(func() { r.sem.Release(1) })
+}
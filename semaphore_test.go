@@ -0,0 +1,112 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// TestSemaphoreRunner verifies that concurrent calls to Run never exceed the
+// limit and that every permit is released afterwards.
+func TestSemaphoreRunner(t *testing.T) {
+	const limit = 3
+	runner := NewSemaphoreRunner(NewNoopRunner(nil), limit)
+	txs := New(runner)
+
+	var inFlight, peak atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := txs.Run(context.Background(), func(_ context.Context) error {
+				n := inFlight.Add(1)
+				defer inFlight.Add(-1)
+				for {
+					p := peak.Load()
+					if n <= p || peak.CompareAndSwap(p, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				return nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if p := peak.Load(); p != limit {
+		t.Errorf("expected at most %d units of work in flight, got %d", limit, p)
+	}
+	if !runner.sem.TryAcquire(limit) {
+		t.Error("expected every permit to be released")
+	}
+}
+
+// TestSemaphoreRunner_FailFast verifies that a full runner created with
+// WithFailFast rejects new transactions, and that a waiting one gives up when
+// its context is done.
+func TestSemaphoreRunner_FailFast(t *testing.T) {
+	mt := NewMockTx()
+	fast := New(NewSemaphoreRunner(mt, 1, WithFailFast()))
+	waiting := New(NewSemaphoreRunner(mt, 1))
+
+	for name, txs := range map[string]*UoW{"fail fast": &fast, "waiting": &waiting} {
+		t.Run(name, func(t *testing.T) {
+			hold := make(chan struct{})
+			started := make(chan struct{})
+			go func() {
+				_ = txs.Run(context.Background(), func(_ context.Context) error {
+					close(started)
+					<-hold
+					return nil
+				})
+			}()
+			<-started
+			defer close(hold)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+			defer cancel()
+			err := txs.Run(ctx, func(_ context.Context) error {
+				t.Error("expected fn not to run")
+				return nil
+			})
+			want := ErrConcurrencyLimit
+			if name == "waiting" {
+				want = context.DeadlineExceeded
+			}
+			if !errors.Is(err, want) {
+				t.Errorf("expected %v, got %v", want, err)
+			}
+		})
+	}
+}
+
+// TestSemaphoreRunner_Nested verifies that a nested unit of work joins the
+// permit of the outer one instead of waiting for it.
+func TestSemaphoreRunner_Nested(t *testing.T) {
+	runner := NewSemaphoreRunner(NewMockTx(), 1)
+	txs := New(runner)
+
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		return txs.Run(ctx, func(_ context.Context) error { return nil })
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !runner.sem.TryAcquire(1) {
+		t.Error("expected the permit to be released")
+	}
+}
+
+// TestSemaphoreRunner_Forwarding verifies that savepoints, compensation and
+// closing are forwarded to the wrapped runner.
+func TestSemaphoreRunner_Forwarding(t *testing.T) {
+	testForwarding(t, func(r Runner) Runner { return NewSemaphoreRunner(r, 1) })
+}
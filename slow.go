@@ -0,0 +1,42 @@
+package uow
+
+import "time"
+
+// WarnLogger is implemented by loggers that have a warning level, such as
+// *slog.Logger. A Logger passed to WithLogger that does not implement it
+// receives warnings through Error.
+type WarnLogger interface {
+	// Warn logs conditions that are not failures but need attention, such
+	// as slow transactions.
+	Warn(msg string, keysAndValues ...any)
+}
+
+// WithSlowThreshold makes Run log a warning through the logger set with
+// WithLogger whenever a call takes longer than d, measured across all
+// attempts, to catch slow transactions before they become incidents. The
+// record carries the transaction ID, the runner type, the duration and
+// whether the unit of work committed, and is written for committed and
+// rolled back units of work alike.
+func WithSlowThreshold(d time.Duration) Option {
+	return func(c *config) {
+		c.slowThreshold = d
+	}
+}
+
+// checkSlow logs a warning when the call that started at start and ended with
+// the attempt described by rs exceeded the slow threshold.
+func (u *UoW) checkSlow(rs *runState, start time.Time) {
+	if u.config.slowThreshold <= 0 || u.config.logger == nil || rs == nil {
+		return
+	}
+	elapsed := u.clock().Now().Sub(start)
+	if elapsed <= u.config.slowThreshold {
+		return
+	}
+	fields := u.logFields(rs, []any{"duration", elapsed, "threshold", u.config.slowThreshold, "committed", rs.commitSucceeded})
+	if w, ok := u.config.logger.(WarnLogger); ok {
+		w.Warn("slow transaction", fields...)
+		return
+	}
+	u.config.logger.Error("slow transaction", fields...)
+}
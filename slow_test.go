@@ -0,0 +1,78 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// warnLogger is a recordingLogger with a warning level.
+type warnLogger struct {
+	recordingLogger
+}
+
+func (l *warnLogger) Warn(msg string, keysAndValues ...any) {
+	l.record("warn", msg, keysAndValues)
+}
+
+// TestWithSlowThreshold verifies that committed and rolled back units of work
+// slower than the threshold are logged as warnings, and faster ones are not.
+func TestWithSlowThreshold(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	logger := &warnLogger{}
+	u := New(NewMockTx(), WithLogger(logger), WithSlowThreshold(time.Second))
+	u.config.clock = clock
+
+	sleep := func(d time.Duration, err error) func(ctx context.Context) error {
+		return func(_ context.Context) error {
+			<-clock.After(d)
+			return err
+		}
+	}
+	_ = u.Run(context.Background(), sleep(100*time.Millisecond, nil))
+	var txID string
+	_ = u.Run(context.Background(), func(ctx context.Context) error {
+		txID = TxID(ctx)
+		return sleep(2*time.Second, nil)(ctx)
+	})
+	_ = u.Run(context.Background(), sleep(3*time.Second, errors.New("fn failed")))
+
+	var warnings []logRecord
+	for _, r := range logger.records {
+		if r.level == "warn" {
+			warnings = append(warnings, r)
+		}
+	}
+	if len(warnings) != 2 {
+		t.Fatalf("expected 2 warnings, got %v", logger.messages())
+	}
+	first := warnings[0]
+	if first.msg != "slow transaction" || first.kv["tx_id"] != txID || first.kv["runner"] != "*uow.MockTx" {
+		t.Errorf("expected the transaction to be identified, got %v", first.kv)
+	}
+	if first.kv["duration"] != 2*time.Second || first.kv["committed"] != true {
+		t.Errorf("expected the duration of the committed run, got %v", first.kv)
+	}
+	if warnings[1].kv["duration"] != 3*time.Second || warnings[1].kv["committed"] != false {
+		t.Errorf("expected the duration of the rolled back run, got %v", warnings[1].kv)
+	}
+}
+
+// TestWithSlowThreshold_NoWarnLevel verifies that a logger without a warning
+// level receives the warning as an error.
+func TestWithSlowThreshold_NoWarnLevel(t *testing.T) {
+	logger := &recordingLogger{}
+	u := New(NewMockTx(), WithLogger(logger), WithSlowThreshold(time.Nanosecond))
+
+	if err := u.Run(context.Background(), func(_ context.Context) error {
+		time.Sleep(time.Millisecond)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	msgs := logger.messages()
+	if len(msgs) == 0 || msgs[len(msgs)-1] != "error: slow transaction" {
+		t.Errorf("expected a slow transaction error record, got %v", msgs)
+	}
+}
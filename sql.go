@@ -4,13 +4,22 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"time"
 )
 
-// ctxKey is an unexported type used for context value keys to avoid collisions.
-type ctxKey string
-
 // txKey is the context key for storing the SQL transaction.
-const txKey ctxKey = "tx"
+var txKey = ctxKey{"tx"}
+
+// sqlSavepointKey is the context key for storing the savepoint of a nested
+// unit of work.
+var sqlSavepointKey = ctxKey{"sql_savepoint"}
+
+// sqlSavepoint is the savepoint created for a nested unit of work.
+type sqlSavepoint struct {
+	tx    *sql.Tx
+	name  string
+	depth int
+}
 
 // SQLTx implements the Runner interface for SQL database transactions. It manages
 // the lifecycle of SQL database connections and transactions for any database
@@ -26,30 +35,163 @@ var _ Runner = &SQLTx{}
 
 // SQLTx struct holds the SQL database connection pool.
 type SQLTx struct {
-	db *sql.DB
+	db        *sql.DB
+	dialect   SQLDialect
+	txOptions *sql.TxOptions
+}
+
+// SQLOption configures optional behavior of a SQLTx. Options are passed to
+// NewSQLTx.
+type SQLOption func(*SQLTx)
+
+// SQLDialect identifies the SQL flavor spoken by the database. It is used to
+// translate backend-specific settings, such as statement timeouts, into SQL.
+type SQLDialect int
+
+const (
+	// DialectGeneric is the default dialect. Backend-specific settings are not
+	// applied.
+	DialectGeneric SQLDialect = iota
+
+	// DialectPostgres targets PostgreSQL.
+	DialectPostgres
+
+	// DialectMySQL targets MySQL and MariaDB.
+	DialectMySQL
+)
+
+// WithSQLDialect sets the SQL dialect of the database.
+func WithSQLDialect(dialect SQLDialect) SQLOption {
+	return func(s *SQLTx) {
+		s.dialect = dialect
+	}
+}
+
+// WithTxOptions sets the options every transaction is started with, such as
+// the isolation level and read-only mode. Without it the driver's defaults
+// are used.
+func WithTxOptions(opts *sql.TxOptions) SQLOption {
+	return func(s *SQLTx) {
+		s.txOptions = opts
+	}
 }
 
 // NewSQLTx creates a new SQLTx instance. It takes a SQL database
-// connection pool as an argument. This function should be called to initialize
-// a new transaction with any SQL database.
-func NewSQLTx(db *sql.DB) *SQLTx {
-	return &SQLTx{
+// connection pool and optional settings as arguments. This function should be
+// called to initialize a new transaction with any SQL database.
+func NewSQLTx(db *sql.DB, opts ...SQLOption) *SQLTx {
+	s := &SQLTx{
 		db: db,
 	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
-// Ctx starts a new SQL transaction. It uses the provided context and
-// starts a new transaction with default isolation level. If any errors
-// occur during this process, they are wrapped and returned. This function
-// is crucial for initiating transactions in the context.
+// Ctx starts a new SQL transaction. It uses the provided context and starts a
+// new transaction with the options set by WithTxOptions, or the driver's
+// defaults. If any errors occur during this process, they are wrapped and
+// returned. This function is crucial for initiating transactions in the
+// context.
+//
+// When ctx already carries a transaction, the unit of work is nested: a
+// savepoint named after the nesting level is created instead, Rollback rolls
+// back to it and Commit releases it, so that an inner unit of work can fail
+// while the outer transaction continues.
 func (s *SQLTx) Ctx(ctx context.Context) (context.Context, error) {
-	tx, err := s.db.BeginTx(ctx, nil)
+	if tx, ok := ctx.Value(txKey).(*sql.Tx); ok {
+		return nestSavepoint(ctx, tx)
+	}
+
+	tx, err := s.db.BeginTx(ctx, readOnlyTxOptions(ctx, s.txOptions))
 	if err != nil {
 		return nil, fmt.Errorf("error in starting transaction: %w", err)
 	}
+
+	if d, ok := StatementTimeout(ctx); ok {
+		if err := s.setStatementTimeout(ctx, tx, d); err != nil {
+			_ = tx.Rollback()
+			return nil, fmt.Errorf("error in setting statement timeout: %w", err)
+		}
+	}
 	return context.WithValue(ctx, txKey, tx), nil
 }
 
+// readOnlyTxOptions returns opts with ReadOnly set when ctx belongs to a
+// read-only unit of work, and opts unchanged otherwise.
+func readOnlyTxOptions(ctx context.Context, opts *sql.TxOptions) *sql.TxOptions {
+	if !IsReadOnly(ctx) {
+		return opts
+	}
+	ro := sql.TxOptions{ReadOnly: true}
+	if opts != nil {
+		ro.Isolation = opts.Isolation
+	}
+	return &ro
+}
+
+// nestSavepoint creates the savepoint of a unit of work nested in tx.
+func nestSavepoint(ctx context.Context, tx *sql.Tx) (context.Context, error) {
+	depth := 1
+	if outer := savepointFrom(ctx, tx); outer != nil {
+		depth = outer.depth + 1
+	}
+	sp := &sqlSavepoint{tx: tx, name: fmt.Sprintf("uow_sp_%d", depth), depth: depth}
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+sp.name); err != nil {
+		return nil, fmt.Errorf("error in creating savepoint: %w", err)
+	}
+	return context.WithValue(ctx, sqlSavepointKey, sp), nil
+}
+
+// savepointFrom returns the savepoint stored in ctx for tx, or nil when ctx
+// does not belong to a nested unit of work.
+func savepointFrom(ctx context.Context, tx *sql.Tx) *sqlSavepoint {
+	sp, ok := ctx.Value(sqlSavepointKey).(*sqlSavepoint)
+	if !ok || sp.tx != tx {
+		return nil
+	}
+	return sp
+}
+
+// rollback undoes the changes made since the savepoint was created and
+// releases it, leaving the enclosing transaction open.
+func (sp *sqlSavepoint) rollback(ctx context.Context) error {
+	if _, err := sp.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+sp.name); err != nil {
+		return err
+	}
+	return sp.release(ctx)
+}
+
+// release forgets the savepoint, keeping the changes made since it was
+// created.
+func (sp *sqlSavepoint) release(ctx context.Context) error {
+	_, err := sp.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+sp.name)
+	return err
+}
+
+// setStatementTimeout bounds the execution time of every statement run in tx
+// according to the dialect. PostgreSQL scopes the setting to the transaction;
+// MySQL scopes it to the session, so it is reset before the transaction ends.
+func (s *SQLTx) setStatementTimeout(ctx context.Context, tx *sql.Tx, d time.Duration) error {
+	var err error
+	switch s.dialect {
+	case DialectPostgres:
+		_, err = tx.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", d.Milliseconds()))
+	case DialectMySQL:
+		_, err = tx.ExecContext(ctx, fmt.Sprintf("SET SESSION max_execution_time = %d", d.Milliseconds()))
+	}
+	return err
+}
+
+// resetStatementTimeout restores the session-scoped statement timeout set by
+// setStatementTimeout, for dialects that need it.
+func (s *SQLTx) resetStatementTimeout(ctx context.Context, tx *sql.Tx) {
+	if _, ok := StatementTimeout(ctx); ok && s.dialect == DialectMySQL {
+		_, _ = tx.ExecContext(context.WithoutCancel(ctx), "SET SESSION max_execution_time = DEFAULT")
+	}
+}
+
 // Get retrieves the SQL transaction. It checks if a transaction is present
 // in the context. If a transaction exists, it returns the transaction. Otherwise,
 // it returns the database connection pool. This function provides access to the
@@ -63,9 +205,14 @@ func (s *SQLTx) Get(ctx context.Context) any {
 
 // Rollback aborts the current transaction. It checks for the presence of a
 // transaction in the context and rolls it back if one exists. This function
-// is essential for handling transaction failures.
+// is essential for handling transaction failures. A nested unit of work rolls
+// back to its savepoint instead.
 func (s *SQLTx) Rollback(ctx context.Context) error {
 	if tx, ok := ctx.Value(txKey).(*sql.Tx); ok {
+		if sp := savepointFrom(ctx, tx); sp != nil {
+			return sp.rollback(ctx)
+		}
+		s.resetStatementTimeout(ctx, tx)
 		return tx.Rollback()
 	}
 	return nil
@@ -73,10 +220,47 @@ func (s *SQLTx) Rollback(ctx context.Context) error {
 
 // Commit commits the current transaction. It checks for the presence of a
 // transaction in the context and commits it if one exists. This function
-// is crucial for saving changes made within a transaction.
+// is crucial for saving changes made within a transaction. A nested unit of
+// work releases its savepoint instead.
 func (s *SQLTx) Commit(ctx context.Context) error {
 	if tx, ok := ctx.Value(txKey).(*sql.Tx); ok {
+		if sp := savepointFrom(ctx, tx); sp != nil {
+			return sp.release(ctx)
+		}
+		s.resetStatementTimeout(ctx, tx)
 		return tx.Commit()
 	}
 	return nil
 }
+
+// Savepoint creates a savepoint with the given name in the current
+// transaction. It returns ErrNoTransaction when there is no transaction in the
+// context.
+func (s *SQLTx) Savepoint(ctx context.Context, name string) error {
+	return s.execInTx(ctx, "SAVEPOINT "+name)
+}
+
+// RollbackToSavepoint undoes the changes made since the named savepoint was
+// created, keeping the transaction open.
+func (s *SQLTx) RollbackToSavepoint(ctx context.Context, name string) error {
+	return s.execInTx(ctx, "ROLLBACK TO SAVEPOINT "+name)
+}
+
+// ReleaseSavepoint forgets the named savepoint, keeping the changes made
+// since it was created.
+func (s *SQLTx) ReleaseSavepoint(ctx context.Context, name string) error {
+	return s.execInTx(ctx, "RELEASE SAVEPOINT "+name)
+}
+
+// execInTx executes query in the transaction stored in the context.
+func (s *SQLTx) execInTx(ctx context.Context, query string) error {
+	tx, ok := ctx.Value(txKey).(*sql.Tx)
+	if !ok {
+		return ErrNoTransaction
+	}
+	if runFinished(ctx) {
+		return ErrFinished
+	}
+	_, err := tx.ExecContext(ctx, query)
+	return err
+}
@@ -0,0 +1,98 @@
+//go:build integration
+
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// openPostgres connects to the PostgreSQL instance given by POSTGRES_DSN. The
+// test is skipped when the variable is not set.
+func openPostgres(t *testing.T) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_DSN not set; skipping integration test")
+	}
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	return db
+}
+
+// TestSQLTx_StatementTimeout_Postgres verifies that the statement timeout is
+// set for the transaction and interrupts a long query.
+func TestSQLTx_StatementTimeout_Postgres(t *testing.T) {
+	db := openPostgres(t)
+	txs := New(NewSQLTx(db, WithSQLDialect(DialectPostgres)), WithStatementTimeout(100*time.Millisecond))
+
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		tx := txs.Get(ctx).(*sql.Tx)
+
+		var setting string
+		if err := tx.QueryRowContext(ctx, "SHOW statement_timeout").Scan(&setting); err != nil {
+			return err
+		}
+		if setting != "100ms" {
+			t.Errorf("expected statement_timeout to be 100ms, got %q", setting)
+		}
+
+		_, err := tx.ExecContext(ctx, "SELECT pg_sleep(2)")
+		return err
+	})
+	if err == nil || !strings.Contains(err.Error(), "statement timeout") {
+		t.Errorf("expected statement timeout error, got %v", err)
+	}
+}
+
+// openPgxPool connects a pgx pool to the PostgreSQL instance given by
+// POSTGRES_DSN. The test is skipped when the variable is not set.
+func openPgxPool(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	dsn := os.Getenv("POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_DSN not set; skipping integration test")
+	}
+	pool, err := pgxpool.New(context.Background(), dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// TestPgxTx_Postgres verifies that PgxTx starts transactions with the
+// configured options and rolls them back when fn fails.
+func TestPgxTx_Postgres(t *testing.T) {
+	pool := openPgxPool(t)
+	ctx := context.Background()
+
+	txs := New(NewPgxTx(pool, WithPgxTxOptions(pgx.TxOptions{IsoLevel: pgx.Serializable})))
+	fnErr := errors.New("fn failed")
+	err := txs.Run(ctx, func(ctx context.Context) error {
+		tx := txs.Get(ctx).(pgx.Tx)
+		var level string
+		if err := tx.QueryRow(ctx, "SHOW transaction_isolation").Scan(&level); err != nil {
+			return err
+		}
+		if level != "serializable" {
+			t.Errorf("expected serializable isolation, got %q", level)
+		}
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) {
+		t.Errorf("expected fn error, got %v", err)
+	}
+}
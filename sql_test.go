@@ -0,0 +1,124 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+// TestSQLTx_Sqlmock verifies the commit and rollback paths of SQLTx configured
+// with transaction options against a mocked driver.
+func TestSQLTx_Sqlmock(t *testing.T) {
+	fnErr := errors.New("insert failed")
+
+	tests := []struct {
+		name    string
+		fnErr   error
+		expect  func(mock sqlmock.Sqlmock)
+		wantErr error
+	}{
+		{
+			name: "commit",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectCommit()
+			},
+		},
+		{
+			name:  "rollback",
+			fnErr: fnErr,
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectBegin()
+				mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(1, 1))
+				mock.ExpectRollback()
+			},
+			wantErr: fnErr,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = db.Close() }()
+			tt.expect(mock)
+
+			txs := New(NewSQLTx(db, WithTxOptions(&sql.TxOptions{Isolation: sql.LevelSerializable})))
+			err = txs.Run(context.Background(), func(ctx context.Context) error {
+				tx := txs.Get(ctx).(*sql.Tx)
+				if _, err := tx.ExecContext(ctx, "INSERT INTO users (name) VALUES (?)", "John Doe"); err != nil {
+					return err
+				}
+				return tt.fnErr
+			})
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+			if err := mock.ExpectationsWereMet(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+// TestSQLTx_NestedRun verifies that nested units of work are isolated by
+// savepoints: an inner failure keeps the outer work, and an outer rollback
+// discards committed inner work.
+func TestSQLTx_NestedRun(t *testing.T) {
+	innerErr := errors.New("inner failed")
+	outerErr := errors.New("outer failed")
+
+	tests := []struct {
+		name     string
+		innerErr error
+		outerErr error
+		wantRows int
+	}{
+		{name: "inner_fail_outer_commit", innerErr: innerErr, wantRows: 1},
+		{name: "inner_success_outer_rollback", outerErr: outerErr, wantRows: 0},
+		{name: "both_commit", wantRows: 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openAuditDB(t)
+			txs := New(NewSQLTx(db))
+			insert := func(ctx context.Context, name string) error {
+				_, err := txs.Get(ctx).(*sql.Tx).ExecContext(ctx, "INSERT INTO test (name) VALUES (?)", name)
+				return err
+			}
+
+			err := txs.Run(context.Background(), func(ctx context.Context) error {
+				if err := insert(ctx, "outer"); err != nil {
+					return err
+				}
+				err := txs.Run(ctx, func(ctx context.Context) error {
+					if err := insert(ctx, "inner"); err != nil {
+						return err
+					}
+					// A second level of nesting gets its own savepoint.
+					if err := txs.Run(ctx, func(ctx context.Context) error { return insert(ctx, "innermost") }); err != nil {
+						return err
+					}
+					return tt.innerErr
+				})
+				if !errors.Is(err, tt.innerErr) {
+					t.Errorf("expected inner error %v, got %v", tt.innerErr, err)
+				}
+				return tt.outerErr
+			})
+			if !errors.Is(err, tt.outerErr) {
+				t.Errorf("expected outer error %v, got %v", tt.outerErr, err)
+			}
+			if n := countRows(t, db, "test"); n != tt.wantRows {
+				t.Errorf("expected %d rows, got %d", tt.wantRows, n)
+			}
+		})
+	}
+}
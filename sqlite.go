@@ -0,0 +1,77 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+)
+
+// SQLiteReadTx implements the Runner interface for read transactions on a
+// SQLite database in WAL mode. In WAL mode readers and a writer can proceed
+// concurrently, but only if reads do not go through the writer's connections
+// and do not take the write lock. SQLiteReadTx therefore runs on a dedicated
+// read pool whose connections begin deferred transactions and reject writes,
+// while writes go through a SQLTx on a separate pool that begins with
+// BEGIN IMMEDIATE.
+//
+// Open the two pools with SQLiteReadDSN and SQLiteWriteDSN:
+//
+//	writeDB, _ := sql.Open("sqlite3", uow.SQLiteWriteDSN("app.db"))
+//	writeDB.SetMaxOpenConns(1)
+//	readDB, _ := sql.Open("sqlite3", uow.SQLiteReadDSN("app.db"))
+//
+//	writes := uow.New(uow.NewSQLTx(writeDB))
+//	reads := uow.New(uow.NewSQLiteReadTx(readDB))
+var _ Runner = &SQLiteReadTx{}
+
+// SQLiteReadTx struct holds the runner for the read pool.
+type SQLiteReadTx struct {
+	*SQLTx
+}
+
+// NewSQLiteReadTx creates a new SQLiteReadTx instance. db must be a dedicated
+// read pool, typically opened with SQLiteReadDSN.
+func NewSQLiteReadTx(db *sql.DB, opts ...SQLOption) *SQLiteReadTx {
+	return &SQLiteReadTx{
+		SQLTx: NewSQLTx(db, opts...),
+	}
+}
+
+// Ctx starts a new read-only transaction on the read pool.
+func (s *SQLiteReadTx) Ctx(ctx context.Context) (context.Context, error) {
+	tx, err := s.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("error in starting transaction: %w", err)
+	}
+	return context.WithValue(ctx, txKey, tx), nil
+}
+
+// SQLiteReadDSN returns a github.com/mattn/go-sqlite3 DSN for a read pool on
+// the database file at path. Connections use WAL mode, begin deferred
+// transactions so that reads never take the write lock, and are query-only.
+func SQLiteReadDSN(path string) string {
+	return sqliteDSN(path, url.Values{
+		"_journal_mode": {"WAL"},
+		"_txlock":       {"deferred"},
+		"_query_only":   {"true"},
+	})
+}
+
+// SQLiteWriteDSN returns a github.com/mattn/go-sqlite3 DSN for a write pool on
+// the database file at path. Connections use WAL mode and begin transactions
+// with BEGIN IMMEDIATE, so that a writer takes the write lock up front instead
+// of failing when upgrading a read lock. Limit the pool to a single connection
+// since SQLite allows only one writer.
+func SQLiteWriteDSN(path string) string {
+	return sqliteDSN(path, url.Values{
+		"_journal_mode": {"WAL"},
+		"_txlock":       {"immediate"},
+		"_busy_timeout": {"5000"},
+	})
+}
+
+// sqliteDSN builds a file URI for path with the given parameters.
+func sqliteDSN(path string, params url.Values) string {
+	return "file:" + path + "?" + params.Encode()
+}
@@ -0,0 +1,139 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// openSQLitePools opens a write pool and a read pool on a WAL database file
+// containing a test table.
+func openSQLitePools(t *testing.T) (writeDB, readDB *sql.DB) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "wal.db")
+
+	writeDB, err := sql.Open("sqlite3", SQLiteWriteDSN(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = writeDB.Close() })
+	writeDB.SetMaxOpenConns(1)
+
+	if _, err := writeDB.Exec("CREATE TABLE test (id INTEGER PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	readDB, err = sql.Open("sqlite3", SQLiteReadDSN(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = readDB.Close() })
+	return writeDB, readDB
+}
+
+// TestSQLiteReadTx_ReaderDoesNotBlockWriter verifies that an open read
+// transaction does not prevent a writer from committing, and that the reader
+// keeps its snapshot.
+func TestSQLiteReadTx_ReaderDoesNotBlockWriter(t *testing.T) {
+	writeDB, readDB := openSQLitePools(t)
+	writes := New(NewSQLTx(writeDB))
+	reads := New(NewSQLiteReadTx(readDB))
+
+	err := reads.Run(context.Background(), func(ctx context.Context) error {
+		tx := reads.Get(ctx).(*sql.Tx)
+		var before int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM test").Scan(&before); err != nil {
+			return err
+		}
+
+		done := make(chan error, 1)
+		go func() {
+			done <- writes.Run(context.Background(), func(ctx context.Context) error {
+				_, err := writes.Get(ctx).(*sql.Tx).ExecContext(ctx, "INSERT INTO test (name) VALUES ('hello')")
+				return err
+			})
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("expected writer to commit, got %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("writer blocked by open read transaction")
+		}
+
+		var after int
+		if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM test").Scan(&after); err != nil {
+			return err
+		}
+		if after != before {
+			t.Errorf("expected reader to keep its snapshot of %d rows, got %d", before, after)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSQLiteReadTx_WriterDoesNotBlockReaders verifies that concurrent readers
+// proceed while a write transaction holds the write lock.
+func TestSQLiteReadTx_WriterDoesNotBlockReaders(t *testing.T) {
+	writeDB, readDB := openSQLitePools(t)
+	writer := NewSQLTx(writeDB)
+	reads := New(NewSQLiteReadTx(readDB))
+
+	wctx, err := writer.Ctx(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := writer.Get(wctx).(*sql.Tx).Exec("INSERT INTO test (name) VALUES ('pending')"); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan error, 4)
+	for i := 0; i < 4; i++ {
+		go func() {
+			errs <- reads.Run(context.Background(), func(ctx context.Context) error {
+				var n int
+				if err := reads.Get(ctx).(*sql.Tx).QueryRowContext(ctx, "SELECT COUNT(*) FROM test").Scan(&n); err != nil {
+					return err
+				}
+				if n != 0 {
+					t.Errorf("expected uncommitted row to be invisible, got %d rows", n)
+				}
+				return nil
+			})
+		}()
+	}
+	for i := 0; i < 4; i++ {
+		select {
+		case err := <-errs:
+			if err != nil {
+				t.Errorf("expected reader to succeed, got %v", err)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatal("reader blocked by open write transaction")
+		}
+	}
+
+	if err := writer.Commit(wctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestSQLiteReadTx_RejectsWrites verifies that the read pool is query-only.
+func TestSQLiteReadTx_RejectsWrites(t *testing.T) {
+	_, readDB := openSQLitePools(t)
+	reads := New(NewSQLiteReadTx(readDB))
+
+	err := reads.Run(context.Background(), func(ctx context.Context) error {
+		_, err := reads.Get(ctx).(*sql.Tx).ExecContext(ctx, "INSERT INTO test (name) VALUES ('nope')")
+		return err
+	})
+	if err == nil {
+		t.Error("expected write to fail on the read pool")
+	}
+}
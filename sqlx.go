@@ -0,0 +1,94 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// sqlxTxKey is the context key for storing the sqlx transaction.
+var sqlxTxKey = ctxKey{"sqlx_tx"}
+
+// SqlxTx implements the Runner interface for github.com/jmoiron/sqlx. The
+// *sqlx.Tx returned by Get offers the sqlx extensions, such as Get, Select
+// and StructScan, on top of the standard transaction.
+var _ Runner = &SqlxTx{}
+
+// SqlxTx struct holds the sqlx handle and the transaction options.
+type SqlxTx struct {
+	db        *sqlx.DB
+	txOptions *sql.TxOptions
+}
+
+// SqlxOption configures optional behavior of a SqlxTx. Options are passed to
+// NewSqlxTx.
+type SqlxOption func(*SqlxTx)
+
+// WithSqlxTxOptions sets the options every transaction is started with, such
+// as the isolation level and read-only mode.
+func WithSqlxTxOptions(opts *sql.TxOptions) SqlxOption {
+	return func(s *SqlxTx) {
+		s.txOptions = opts
+	}
+}
+
+// NewSqlxTx creates a new SqlxTx instance. It takes a sqlx handle and
+// optional settings as arguments.
+func NewSqlxTx(db *sqlx.DB, opts ...SqlxOption) *SqlxTx {
+	s := &SqlxTx{
+		db: db,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Ctx starts a new transaction with BeginTxx and stores it in the returned
+// context. When ctx already carries a transaction, a nested unit of work joins
+// it through a savepoint, as with SQLTx.
+func (s *SqlxTx) Ctx(ctx context.Context) (context.Context, error) {
+	if tx, ok := ctx.Value(sqlxTxKey).(*sqlx.Tx); ok {
+		return nestSavepoint(ctx, tx.Tx)
+	}
+	tx, err := s.db.BeginTxx(ctx, readOnlyTxOptions(ctx, s.txOptions))
+	if err != nil {
+		return nil, fmt.Errorf("error in starting transaction: %w", err)
+	}
+	return context.WithValue(ctx, sqlxTxKey, tx), nil
+}
+
+// Get retrieves the sqlx handle. If a transaction exists in the context, it
+// returns the *sqlx.Tx. Otherwise, it returns the *sqlx.DB.
+func (s *SqlxTx) Get(ctx context.Context) any {
+	if tx, ok := ctx.Value(sqlxTxKey).(*sqlx.Tx); ok {
+		return tx
+	}
+	return s.db
+}
+
+// Rollback aborts the current transaction. A nested unit of work rolls back
+// to its savepoint instead.
+func (s *SqlxTx) Rollback(ctx context.Context) error {
+	if tx, ok := ctx.Value(sqlxTxKey).(*sqlx.Tx); ok {
+		if sp := savepointFrom(ctx, tx.Tx); sp != nil {
+			return sp.rollback(ctx)
+		}
+		return tx.Rollback()
+	}
+	return nil
+}
+
+// Commit commits the current transaction. A nested unit of work releases its
+// savepoint instead.
+func (s *SqlxTx) Commit(ctx context.Context) error {
+	if tx, ok := ctx.Value(sqlxTxKey).(*sqlx.Tx); ok {
+		if sp := savepointFrom(ctx, tx.Tx); sp != nil {
+			return sp.release(ctx)
+		}
+		return tx.Commit()
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqlxUser is the struct scanned by the sqlx tests.
+type sqlxUser struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+// openSqlx opens an in-memory SQLite database through sqlx with a users
+// table.
+func openSqlx(t *testing.T) *sqlx.DB {
+	t.Helper()
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { _ = db.Close() })
+
+	db.MustExec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")
+	return db
+}
+
+// TestSqlxTx verifies the commit and rollback round trips through sqlx,
+// scanning the rows into structs.
+func TestSqlxTx(t *testing.T) {
+	db := openSqlx(t)
+	txs := New(NewSqlxTx(db, WithSqlxTxOptions(&sql.TxOptions{Isolation: sql.LevelSerializable})))
+
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		tx := txs.Get(ctx).(*sqlx.Tx)
+		if _, err := tx.NamedExecContext(ctx, "INSERT INTO users (name) VALUES (:name)", sqlxUser{Name: "alice"}); err != nil {
+			return err
+		}
+		var user sqlxUser
+		if err := tx.GetContext(ctx, &user, "SELECT id, name FROM users WHERE name = ?", "alice"); err != nil {
+			return err
+		}
+		if user.ID == 0 {
+			t.Error("expected the scanned user to have an ID")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fnErr := errors.New("fn failed")
+	err = txs.Run(context.Background(), func(ctx context.Context) error {
+		tx := txs.Get(ctx).(*sqlx.Tx)
+		if _, err := tx.ExecContext(ctx, "INSERT INTO users (name) VALUES (?)", "bob"); err != nil {
+			return err
+		}
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) {
+		t.Fatalf("expected fn error, got %v", err)
+	}
+
+	var users []sqlxUser
+	if err := db.Select(&users, "SELECT id, name FROM users ORDER BY id"); err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 || users[0].Name != "alice" {
+		t.Errorf("expected only the committed user, got %+v", users)
+	}
+}
+
+// TestSqlxTx_NestedRun verifies that a nested unit of work joins the outer
+// transaction through a savepoint: an inner failure keeps the outer work.
+func TestSqlxTx_NestedRun(t *testing.T) {
+	db := openSqlx(t)
+	txs := New(NewSqlxTx(db))
+	insert := func(ctx context.Context, name string) error {
+		_, err := txs.Get(ctx).(*sqlx.Tx).ExecContext(ctx, "INSERT INTO users (name) VALUES (?)", name)
+		return err
+	}
+
+	innerErr := errors.New("inner failed")
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		if err := insert(ctx, "outer"); err != nil {
+			return err
+		}
+		err := txs.Run(ctx, func(ctx context.Context) error {
+			if err := insert(ctx, "inner"); err != nil {
+				return err
+			}
+			return innerErr
+		})
+		if !errors.Is(err, innerErr) {
+			t.Errorf("expected inner error, got %v", err)
+		}
+		return txs.Run(ctx, func(ctx context.Context) error { return insert(ctx, "kept") })
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	if err := db.Select(&names, "SELECT name FROM users ORDER BY id"); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "outer" || names[1] != "kept" {
+		t.Errorf("expected the outer and kept users, got %v", names)
+	}
+}
@@ -0,0 +1,186 @@
+package uow
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// runStateKey is the context key for storing the state of the current run.
+var runStateKey = ctxKey{"run_state"}
+
+// runState holds the state of a single attempt of UoW.Run. It is stored in
+// the context passed to fn so that package-level helpers can reach it.
+type runState struct {
+	// txID uniquely identifies the transaction. It is shared by all attempts
+	// of the same run.
+	txID string
+
+	// attempt is the 1-based number of the current attempt.
+	attempt int
+
+	// maxAttempts is the total number of attempts allowed by the retry policy.
+	maxAttempts int
+
+	// statementTimeout bounds individual statements; zero means no limit.
+	statementTimeout time.Duration
+
+	// readOnly reports whether the transaction is read-only.
+	readOnly bool
+
+	// dryRun reports whether the transaction is always rolled back.
+	dryRun bool
+
+	// timeout is the limit on the whole run set with WithTimeout; zero means
+	// no limit.
+	timeout time.Duration
+
+	// runner is the runner of the unit of work the attempt belongs to.
+	runner Runner
+
+	// uow is the UoW running the attempt.
+	uow *UoW
+
+	// commitSucceeded reports whether the attempt committed.
+	commitSucceeded bool
+
+	// rollbackErr is the error returned by the runner's Rollback, if any.
+	rollbackErr error
+
+	// keptErr reports whether the attempt committed despite an error from fn
+	// accepted by WithShouldRollback.
+	keptErr bool
+
+	// checklist tracks resources registered with RegisterResource.
+	checklist checklist
+
+	// affected accumulates the counts reported with RecordAffected.
+	affected atomic.Int64
+
+	// mu guards the fields below.
+	mu sync.Mutex
+
+	// preCommit holds callbacks run inside the transaction before commit.
+	preCommit []func(ctx context.Context) error
+
+	// buffers holds the items of each WriteBuffer, keyed by the buffer.
+	buffers map[any]any
+
+	// parent is the state of the enclosing run when runs are nested.
+	parent *runState
+
+	// onCommit and onRollback hold the callbacks registered with OnCommit
+	// and OnRollback.
+	onCommit   []func()
+	onRollback []func()
+
+	// checkpoint reports whether a checkpoint savepoint exists.
+	checkpoint bool
+
+	// decision holds the outcome requested with MarkCommit or MarkRollback.
+	decision decision
+
+	// events holds the events appended with AppendEvent.
+	events []any
+
+	// values holds the values stored with SetValue.
+	values map[any]any
+
+	// done is set once the attempt has committed or rolled back.
+	done atomic.Bool
+}
+
+// preCommitCallbacks returns the registered pre-commit callbacks in
+// registration order.
+func (rs *runState) preCommitCallbacks() []func(ctx context.Context) error {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	return append([]func(ctx context.Context) error(nil), rs.preCommit...)
+}
+
+// withRunState returns a copy of ctx carrying rs.
+func withRunState(ctx context.Context, rs *runState) context.Context {
+	return context.WithValue(ctx, runStateKey, rs)
+}
+
+// runStateFrom returns the run state stored in ctx, or nil when ctx does not
+// belong to a unit of work.
+func runStateFrom(ctx context.Context) *runState {
+	rs, _ := ctx.Value(runStateKey).(*runState)
+	return rs
+}
+
+// InTransaction reports whether ctx belongs to a unit of work, i.e. whether
+// it was derived from the context UoW.Run passes to fn. It works the same for
+// every runner, so library code can use it to decide whether to start its own
+// unit of work or take part in the caller's.
+func InTransaction(ctx context.Context) bool {
+	return runStateFrom(ctx) != nil
+}
+
+// FromContext returns the UoW running the unit of work ctx belongs to, so that
+// code that only receives the context, such as a repository, can reach the
+// runner's handle with Get or start a nested unit of work with Run. Callbacks
+// can be registered with OnCommit and OnRollback without it. FromContext
+// reports false when ctx does not belong to a unit of work or the unit of
+// work has already committed or rolled back, so a context kept beyond Run
+// does not hand out the UoW.
+func FromContext(ctx context.Context) (*UoW, bool) {
+	rs := runStateFrom(ctx)
+	if rs == nil || rs.uow == nil || rs.done.Load() {
+		return nil, false
+	}
+	return rs.uow, true
+}
+
+// ErrFinished is returned when a context is used after the unit of work it
+// belongs to has committed or rolled back, which usually means it was kept
+// beyond the call to Run, e.g. by a goroutine started in fn.
+var ErrFinished = errors.New("unit of work already finished")
+
+// CheckActive returns nil when ctx belongs to a unit of work whose
+// transaction is still open. It returns ErrFinished when the unit of work has
+// already committed or rolled back, and ErrNoTransaction when ctx does not
+// belong to a unit of work. Repository code can call it to fail with a clear
+// error instead of a driver error about an ended session or transaction.
+func CheckActive(ctx context.Context) error {
+	rs := runStateFrom(ctx)
+	if rs == nil {
+		return ErrNoTransaction
+	}
+	if runFinished(ctx) {
+		return ErrFinished
+	}
+	return nil
+}
+
+// runFinished reports whether ctx belongs to a unit of work that has already
+// committed or rolled back.
+func runFinished(ctx context.Context) bool {
+	rs := runStateFrom(ctx)
+	return rs != nil && rs.done.Load()
+}
+
+// newTxID generates a random transaction ID formatted as a version 4 UUID.
+func newTxID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], b[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], b[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], b[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], b[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], b[10:])
+	return string(buf[:])
+}
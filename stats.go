@@ -0,0 +1,39 @@
+package uow
+
+import (
+	"context"
+	"time"
+)
+
+// Stats describes a completed call to RunWithStats.
+type Stats struct {
+	// Duration is the time the call took, across all attempts and including
+	// the backoff between them.
+	Duration time.Duration
+
+	// Attempts is the number of attempts made, 1 when fn was not retried. It
+	// is 0 when no attempt was made, e.g. because the leader check failed.
+	Attempts int
+
+	// Committed reports whether the final attempt committed.
+	Committed bool
+
+	// RollbackErr is the error returned by the runner when rolling back the
+	// final attempt failed, and nil otherwise.
+	RollbackErr error
+}
+
+// RunWithStats is Run that also returns Stats about the call, such as its
+// duration and number of attempts, for logging or SLOs of a single call
+// without configuring WithMetrics.
+func (u *UoW) RunWithStats(ctx context.Context, fn func(ctx context.Context) error, opts ...RunOption) (Stats, error) {
+	start := u.clock().Now()
+	rs, err := u.execute(ctx, fn, opts...)
+	stats := Stats{Duration: u.clock().Now().Sub(start)}
+	if rs != nil {
+		stats.Attempts = rs.attempt
+		stats.Committed = rs.commitSucceeded
+		stats.RollbackErr = rs.rollbackErr
+	}
+	return stats, err
+}
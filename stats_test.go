@@ -0,0 +1,63 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// TestRunWithStats verifies that the attempt count increments across retries
+// and that the outcome of the final attempt is reported.
+func TestRunWithStats(t *testing.T) {
+	u := New(NewMockTx(), WithMaxRetries(3), WithRetryIf(isErrRetryable))
+
+	attempts := 0
+	stats, err := u.RunWithStats(context.Background(), func(_ context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errRetryable
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Attempts != 3 || !stats.Committed || stats.RollbackErr != nil {
+		t.Errorf("expected 3 attempts and a commit, got %+v", stats)
+	}
+	if stats.Duration <= 0 {
+		t.Errorf("expected a positive duration, got %v", stats.Duration)
+	}
+
+	rollbackErr := errors.New("rollback failed")
+	u = New(NewMockTx().WithRollbackError(rollbackErr))
+	stats, err = u.RunWithStats(context.Background(), func(_ context.Context) error {
+		return errors.New("fn failed")
+	})
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if stats.Attempts != 1 || stats.Committed || !errors.Is(stats.RollbackErr, rollbackErr) {
+		t.Errorf("expected a single failed attempt with the rollback error, got %+v", stats)
+	}
+}
+
+// TestRunWithStats_Clock verifies that the duration is measured with the
+// clock of the UoW, so that it covers the backoff between attempts.
+func TestRunWithStats_Clock(t *testing.T) {
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	u := New(NewMockTx(), WithMaxRetries(2), WithRetryIf(isErrRetryable),
+		WithRetryBackoff(10*time.Millisecond, 0, false))
+	u.config.clock = clock
+
+	stats, err := u.RunWithStats(context.Background(), func(_ context.Context) error {
+		return errRetryable
+	})
+	if !errors.Is(err, errRetryable) {
+		t.Fatalf("expected retryable error, got %v", err)
+	}
+	if want := 30 * time.Millisecond; stats.Duration != want {
+		t.Errorf("expected a duration of %v, got %v", want, stats.Duration)
+	}
+}
@@ -0,0 +1,142 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// WithStatementTimeout bounds the execution time of every individual statement
+// run inside the unit of work. It is distinct from a timeout on the whole
+// transaction. Runners translate it to their backend when the transaction
+// starts:
+//
+//   - SQLTx with DialectPostgres and PgxTx run SET LOCAL statement_timeout.
+//   - SQLTx with DialectMySQL runs SET SESSION max_execution_time and resets
+//     it before the transaction ends.
+//
+// Other runners, including MongoTx, do not apply the limit. MongoDB offers no
+// per-transaction statement limit and the driver sets maxTimeMS per
+// operation only, so MongoTx cannot enforce it; repositories that want it have
+// to read the value with StatementTimeout and set it on every operation
+// themselves, e.g. with options.Find().SetMaxTime(d).
+func WithStatementTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.statementTimeout = d
+	}
+}
+
+// StatementTimeout returns the statement timeout configured for the unit of
+// work that ctx belongs to. The boolean is false when ctx does not belong to a
+// unit of work or no statement timeout is configured.
+func StatementTimeout(ctx context.Context) (time.Duration, bool) {
+	rs := runStateFrom(ctx)
+	if rs == nil || rs.statementTimeout <= 0 {
+		return 0, false
+	}
+	return rs.statementTimeout, true
+}
+
+// WithTimeout is a per-run option bounding the whole call to Run: starting the
+// transaction, fn, and the final commit or rollback, across all attempts. The
+// limit is applied by deriving a context with the timeout before the
+// transaction starts. When fn returns after the limit has passed, the
+// transaction is rolled back instead of committed and Run returns an error
+// wrapping context.DeadlineExceeded.
+func WithTimeout(d time.Duration) RunOption {
+	return func(rc *runConfig) {
+		rc.timeout = d
+	}
+}
+
+// RunWithTimeout runs fn bounded by d. It is shorthand for
+// u.Run(ctx, fn, append(opts, WithTimeout(d))...).
+func (u *UoW) RunWithTimeout(ctx context.Context, d time.Duration, fn func(ctx context.Context) error, opts ...RunOption) error {
+	return u.Run(ctx, fn, append(opts, WithTimeout(d))...)
+}
+
+// ErrBeginTimeout is returned by Run when starting the transaction takes longer
+// than the limit set with WithBeginTimeout.
+var ErrBeginTimeout = errors.New("timed out starting transaction")
+
+// WithBeginTimeout bounds the time spent in the runner's Ctx, which covers
+// acquiring a connection from the pool and starting the transaction. When the
+// limit is exceeded Run fails with ErrBeginTimeout, so pool starvation is
+// reported promptly and distinctly from failures of fn. The limit does not
+// apply to fn or Commit. The runner must honor the cancellation of the context
+// passed to Ctx, as database/sql, the Mongo driver and BoltTx do.
+func WithBeginTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.beginTimeout = d
+	}
+}
+
+// defaultRollbackTimeout bounds a rollback that runs after the context of the
+// unit of work has ended, unless WithRollbackTimeout sets another limit.
+const defaultRollbackTimeout = 5 * time.Second
+
+// WithRollbackTimeout sets how long a rollback may take when the context of
+// the unit of work has already been cancelled or has passed its deadline, e.g.
+// because the client of the request went away. Rolling back with such a
+// context would fail right away and leave the transaction open, so it runs
+// with a context that keeps the values of the original one, such as the
+// transaction handle, but is bounded by d instead. It defaults to 5 seconds.
+func WithRollbackTimeout(d time.Duration) Option {
+	return func(c *config) {
+		c.rollbackTimeout = d
+	}
+}
+
+// rollbackContext returns the context to roll back the transaction in uowCtx
+// with: uowCtx itself while it is alive, and otherwise a copy that is not
+// cancelled and is bounded by the rollback timeout. The returned function
+// releases its resources.
+func (u *UoW) rollbackContext(uowCtx context.Context) (context.Context, context.CancelFunc) {
+	if uowCtx.Err() == nil {
+		return uowCtx, func() {}
+	}
+	d := u.config.rollbackTimeout
+	if d <= 0 {
+		d = defaultRollbackTimeout
+	}
+	return context.WithTimeout(context.WithoutCancel(uowCtx), d)
+}
+
+// begin starts the transaction of an attempt, bounded by the begin timeout.
+// The returned function releases the resources of the begin context and must
+// be called once the transaction has finished.
+func (u *UoW) begin(ctx context.Context) (context.Context, func(), error) {
+	d := u.config.beginTimeout
+	if d <= 0 {
+		uowCtx, err := u.runner.Ctx(ctx)
+		return uowCtx, func() {}, err
+	}
+
+	// Only the begin phase is bounded: the timer is stopped once Ctx returns,
+	// while the context stays alive for the rest of the transaction.
+	beginCtx, cancel := context.WithCancelCause(ctx)
+	timer := time.AfterFunc(d, func() { cancel(ErrBeginTimeout) })
+	uowCtx, err := u.runner.Ctx(beginCtx)
+	if !timer.Stop() {
+		if err == nil {
+			// The transaction started right as the limit expired; it cannot
+			// be used with a cancelled context, so abandon it.
+			rbCtx, cancelRollback := u.rollbackContext(uowCtx)
+			_ = u.runner.Rollback(rbCtx)
+			cancelRollback()
+			err = context.Cause(beginCtx)
+		}
+		cancel(nil)
+		if errors.Is(err, ErrBeginTimeout) {
+			// The error is the timeout itself; name it only once.
+			return nil, nil, fmt.Errorf("%w after %v", ErrBeginTimeout, d)
+		}
+		return nil, nil, fmt.Errorf("%w after %v: %w", ErrBeginTimeout, d, err)
+	}
+	if err != nil {
+		cancel(nil)
+		return nil, nil, err
+	}
+	return uowCtx, func() { cancel(nil) }, nil
+}
@@ -0,0 +1,139 @@
+package uow
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// timeoutCancelKey is the context key for storing the cancel function of a
+// TimeoutRunner transaction.
+var timeoutCancelKey = ctxKey{"timeout_cancel"}
+
+// TimeoutRunner implements the Runner interface by wrapping another runner
+// and bounding every transaction it starts: Ctx derives the transaction
+// context with context.WithTimeout, so statements issued by fn fail once the
+// duration has passed, and Commit and Rollback release the timer. Unlike
+// WithTimeout, which bounds single calls to Run, it applies to every unit of
+// work of the UoW it is passed to.
+//
+// A transaction whose deadline passed before Commit is rolled back instead,
+// and Commit returns an error wrapping context.DeadlineExceeded. Rollback runs
+// without the deadline so that it can still reach the data store, bounded by
+// 5 seconds instead, like the rollbacks of a UoW after its context ended.
+//
+// Savepoints, closing and compensation are forwarded to the wrapped runner,
+// so Checkpoint, WithConflictHandler, UoW.Close and MultiRunner behave as
+// without the decorator.
+var _ Runner = &TimeoutRunner{}
+
+var (
+	_ Savepointer = &TimeoutRunner{}
+	_ Compensator = &TimeoutRunner{}
+	_ io.Closer   = &TimeoutRunner{}
+)
+
+// TimeoutRunner struct holds the wrapped runner and the timeout.
+type TimeoutRunner struct {
+	runner  Runner
+	timeout time.Duration
+}
+
+// NewTimeoutRunner creates a new TimeoutRunner instance. It takes the runner
+// to wrap and the maximum duration of every transaction as arguments.
+func NewTimeoutRunner(runner Runner, timeout time.Duration) *TimeoutRunner {
+	return &TimeoutRunner{
+		runner:  runner,
+		timeout: timeout,
+	}
+}
+
+// Ctx starts a transaction on the wrapped runner with a context that expires
+// after the timeout.
+func (r *TimeoutRunner) Ctx(ctx context.Context) (context.Context, error) {
+	timeoutCtx, cancel := context.WithTimeout(ctx, r.timeout)
+	txCtx, err := r.runner.Ctx(timeoutCtx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return context.WithValue(txCtx, timeoutCancelKey, cancel), nil
+}
+
+// Get delegates to the wrapped runner.
+func (r *TimeoutRunner) Get(ctx context.Context) any {
+	return r.runner.Get(ctx)
+}
+
+// Rollback rolls back the wrapped runner's transaction, without the deadline,
+// and releases the timer.
+func (r *TimeoutRunner) Rollback(ctx context.Context) error {
+	defer r.cancel(ctx)
+	return r.rollback(ctx)
+}
+
+// Commit commits the wrapped runner's transaction and releases the timer. If
+// the deadline has already passed, the transaction is rolled back instead.
+func (r *TimeoutRunner) Commit(ctx context.Context) error {
+	defer r.cancel(ctx)
+	if err := ctx.Err(); err != nil {
+		_ = r.rollback(ctx)
+		return fmt.Errorf("transaction timed out after %v: %w", r.timeout, err)
+	}
+	return r.runner.Commit(ctx)
+}
+
+// cancel releases the timer of the transaction in ctx.
+func (r *TimeoutRunner) cancel(ctx context.Context) {
+	if cancel, ok := ctx.Value(timeoutCancelKey).(context.CancelFunc); ok {
+		cancel()
+	}
+}
+
+// rollback rolls back the wrapped runner's transaction with a context that
+// keeps the values of ctx but replaces its deadline with
+// defaultRollbackTimeout.
+func (r *TimeoutRunner) rollback(ctx context.Context) error {
+	rbCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), defaultRollbackTimeout)
+	defer cancel()
+	return r.runner.Rollback(rbCtx)
+}
+
+// unwrap returns the wrapped runner.
+func (r *TimeoutRunner) unwrap() Runner {
+	return r.runner
+}
+
+// Savepoint forwards to the wrapped runner. It returns
+// ErrSavepointsUnsupported when the wrapped runner does not implement
+// Savepointer.
+func (r *TimeoutRunner) Savepoint(ctx context.Context, name string) error {
+	return savepoint(ctx, r.runner, name)
+}
+
+// RollbackToSavepoint forwards to the wrapped runner. It returns
+// ErrSavepointsUnsupported when the wrapped runner does not implement
+// Savepointer.
+func (r *TimeoutRunner) RollbackToSavepoint(ctx context.Context, name string) error {
+	return rollbackToSavepoint(ctx, r.runner, name)
+}
+
+// ReleaseSavepoint forwards to the wrapped runner. It returns
+// ErrSavepointsUnsupported when the wrapped runner does not implement
+// Savepointer.
+func (r *TimeoutRunner) ReleaseSavepoint(ctx context.Context, name string) error {
+	return releaseSavepoint(ctx, r.runner, name)
+}
+
+// Compensate forwards to the wrapped runner. It returns
+// ErrCompensationUnsupported when the wrapped runner does not implement
+// Compensator.
+func (r *TimeoutRunner) Compensate(ctx context.Context) error {
+	return compensate(ctx, r.runner)
+}
+
+// Close closes the wrapped runner if it implements io.Closer.
+func (r *TimeoutRunner) Close() error {
+	return closeRunner(r.runner)
+}
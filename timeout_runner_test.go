@@ -0,0 +1,175 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// TestTimeoutRunner verifies that a slow fn is cancelled by the timeout and
+// that a fast one commits and releases the timer.
+func TestTimeoutRunner(t *testing.T) {
+	mt := NewMockTx()
+	txs := New(NewTimeoutRunner(mt, 20*time.Millisecond))
+
+	start := time.Now()
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+			return nil
+		}
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the slow fn to be cancelled, took %v", elapsed)
+	}
+	if n := mt.CallCount("rollback"); n != 1 {
+		t.Errorf("expected a rollback, got %d", n)
+	}
+
+	var kept context.Context
+	err = txs.Run(context.Background(), func(ctx context.Context) error {
+		kept = ctx
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := mt.CallCount("commit"); n != 1 {
+		t.Errorf("expected a commit, got %d", n)
+	}
+	if !errors.Is(kept.Err(), context.Canceled) {
+		t.Errorf("expected the timer to be released on commit, got %v", kept.Err())
+	}
+}
+
+// TestTimeoutRunner_ExpiredBeforeCommit verifies that a transaction whose
+// deadline passed while fn ignored it is rolled back instead of committed.
+func TestTimeoutRunner_ExpiredBeforeCommit(t *testing.T) {
+	runner := &liveRollbackRunner{MockTx: NewMockTx()}
+	mt := runner.MockTx
+	txs := New(NewTimeoutRunner(runner, time.Millisecond))
+
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if n := mt.CallCount("commit"); n != 0 {
+		t.Errorf("expected no commit, got %d", n)
+	}
+	if n := mt.CallCount("rollback"); n != 1 {
+		t.Errorf("expected a rollback, got %d", n)
+	}
+	if !runner.deadline {
+		t.Error("expected the rollback to be bounded by a timeout")
+	}
+}
+
+// TestTimeoutRunner_Forwarding verifies that savepoints, compensation and
+// closing are forwarded to the wrapped runner.
+func TestTimeoutRunner_Forwarding(t *testing.T) {
+	testForwarding(t, func(r Runner) Runner { return NewTimeoutRunner(r, time.Minute) })
+}
+
+// testForwarding verifies that the runner returned by wrap forwards the
+// optional runner interfaces to the runner it wraps, and reports them as
+// unsupported when the wrapped runner lacks them.
+func testForwarding(t *testing.T, wrap func(r Runner) Runner) {
+	t.Helper()
+
+	inner := &capableRunner{MockTx: NewMockTx()}
+	wrapped := wrap(inner)
+	u := New(wrapped)
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		if err := Checkpoint(ctx); err != nil {
+			return err
+		}
+		if err := RollbackToCheckpoint(ctx); err != nil {
+			return err
+		}
+		return Checkpoint(ctx)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := wrapped.(Compensator).Compensate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := u.Close(); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"savepoint uow_checkpoint_0", "rollback to uow_checkpoint_0",
+		"release uow_checkpoint_0", "savepoint uow_checkpoint_0",
+		"compensate", "close",
+	}
+	if !reflect.DeepEqual(inner.calls, want) {
+		t.Errorf("expected forwarded calls %v, got %v", want, inner.calls)
+	}
+
+	plain := wrap(NewMockTx())
+	u = New(plain)
+	err = u.Run(context.Background(), func(ctx context.Context) error {
+		if err := Checkpoint(ctx); err != nil {
+			t.Errorf("expected Checkpoint to be a no-op, got %v", err)
+		}
+		return RollbackToCheckpoint(ctx)
+	})
+	if !errors.Is(err, ErrSavepointsUnsupported) {
+		t.Errorf("expected ErrSavepointsUnsupported, got %v", err)
+	}
+	if err := plain.(Compensator).Compensate(context.Background()); !errors.Is(err, ErrCompensationUnsupported) {
+		t.Errorf("expected ErrCompensationUnsupported, got %v", err)
+	}
+	if err := u.Close(); err != nil {
+		t.Errorf("expected Close to be a no-op, got %v", err)
+	}
+
+	multi := New(NewMultiRunner(wrap(NewMockTx()), NewMockTx().WithCommitError(errors.New("commit failed"))))
+	err = multi.Run(context.Background(), func(_ context.Context) error { return nil })
+	var multiErr *MultiCommitError
+	if !errors.As(err, &multiErr) || multiErr.Outcomes[0].Status != OutcomeCommitted {
+		t.Errorf("expected the wrapped runner to be left committed, got %v", err)
+	}
+}
+
+// capableRunner is a MockTx implementing Savepointer, Compensator and
+// io.Closer, recording the calls to them.
+type capableRunner struct {
+	*MockTx
+	calls []string
+}
+
+func (r *capableRunner) Savepoint(_ context.Context, name string) error {
+	r.calls = append(r.calls, "savepoint "+name)
+	return nil
+}
+
+func (r *capableRunner) RollbackToSavepoint(_ context.Context, name string) error {
+	r.calls = append(r.calls, "rollback to "+name)
+	return nil
+}
+
+func (r *capableRunner) ReleaseSavepoint(_ context.Context, name string) error {
+	r.calls = append(r.calls, "release "+name)
+	return nil
+}
+
+func (r *capableRunner) Compensate(_ context.Context) error {
+	r.calls = append(r.calls, "compensate")
+	return nil
+}
+
+func (r *capableRunner) Close() error {
+	r.calls = append(r.calls, "close")
+	return nil
+}
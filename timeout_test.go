@@ -0,0 +1,214 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestStatementTimeout verifies that the configured statement timeout is
+// visible inside fn and absent outside a unit of work.
+func TestStatementTimeout(t *testing.T) {
+	u := New(NewMockTx(), WithStatementTimeout(250*time.Millisecond))
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		d, ok := StatementTimeout(ctx)
+		if !ok || d != 250*time.Millisecond {
+			t.Errorf("expected statement timeout of 250ms, got %v (%v)", d, ok)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := StatementTimeout(context.Background()); ok {
+		t.Error("expected no statement timeout outside a unit of work")
+	}
+}
+
+// TestStatementTimeout_GenericDialect verifies that the generic SQL dialect
+// ignores the statement timeout instead of failing the transaction.
+func TestStatementTimeout_GenericDialect(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = db.Close() }()
+
+	txs := New(NewSQLTx(db), WithStatementTimeout(time.Second))
+	err = txs.Run(context.Background(), func(ctx context.Context) error {
+		_, err := txs.Get(ctx).(*sql.Tx).ExecContext(ctx, "SELECT 1")
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// slowBeginRunner wraps a Runner and delays Ctx, like a saturated connection
+// pool would, until the delay passes or ctx is done.
+type slowBeginRunner struct {
+	Runner
+	delay time.Duration
+}
+
+func (r *slowBeginRunner) Ctx(ctx context.Context) (context.Context, error) {
+	select {
+	case <-time.After(r.delay):
+		return r.Runner.Ctx(ctx)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// TestWithBeginTimeout verifies that a slow begin fails with ErrBeginTimeout
+// without running fn.
+func TestWithBeginTimeout(t *testing.T) {
+	u := New(&slowBeginRunner{Runner: NewMockTx(), delay: time.Second}, WithBeginTimeout(10*time.Millisecond))
+
+	start := time.Now()
+	err := u.Run(context.Background(), func(_ context.Context) error {
+		t.Error("fn must not run when the begin times out")
+		return nil
+	})
+	if !errors.Is(err, ErrBeginTimeout) {
+		t.Fatalf("expected ErrBeginTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("expected the begin to be abandoned promptly, took %v", elapsed)
+	}
+}
+
+// lateBeginRunner is a Runner whose Ctx succeeds only once the context it
+// receives is done, as if the transaction started right as the limit expired.
+type lateBeginRunner struct {
+	Runner
+}
+
+func (r *lateBeginRunner) Ctx(ctx context.Context) (context.Context, error) {
+	<-ctx.Done()
+	return r.Runner.Ctx(ctx)
+}
+
+// TestWithBeginTimeout_LateBegin verifies that a transaction started right as
+// the limit expired is abandoned and the timeout is reported only once.
+func TestWithBeginTimeout_LateBegin(t *testing.T) {
+	mt := NewMockTx()
+	u := New(&lateBeginRunner{Runner: mt}, WithBeginTimeout(10*time.Millisecond))
+
+	err := u.Run(context.Background(), func(_ context.Context) error {
+		t.Error("fn must not run when the begin times out")
+		return nil
+	})
+	if !errors.Is(err, ErrBeginTimeout) {
+		t.Fatalf("expected ErrBeginTimeout, got %v", err)
+	}
+	if n := strings.Count(err.Error(), ErrBeginTimeout.Error()); n != 1 {
+		t.Errorf("expected the timeout to be named once, got %q", err)
+	}
+	if got := mt.State().Status(); got != StateRolledBack {
+		t.Errorf("expected the late transaction to be rolled back, got status %v", got)
+	}
+}
+
+// TestWithBeginTimeout_NotAppliedToFn verifies that the limit covers only the
+// begin phase: fn may outlast it and its own deadline is reported as such.
+func TestWithBeginTimeout_NotAppliedToFn(t *testing.T) {
+	mt := NewMockTx()
+	u := New(&slowBeginRunner{Runner: mt, delay: time.Millisecond}, WithBeginTimeout(20*time.Millisecond))
+
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		time.Sleep(40 * time.Millisecond)
+		return ctx.Err()
+	})
+	if err != nil {
+		t.Fatalf("expected fn to outlast the begin timeout, got %v", err)
+	}
+	if mt.state.Value() != " committed!" {
+		t.Errorf("expected commit, got %q", mt.state.Value())
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err = u.Run(ctx, func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrBeginTimeout) {
+		t.Errorf("expected the fn deadline rather than ErrBeginTimeout, got %v", err)
+	}
+}
+
+// TestRunWithTimeout verifies that a unit of work whose fn outlasts the
+// timeout is rolled back rather than committed.
+func TestRunWithTimeout(t *testing.T) {
+	mt := NewMockTx()
+	u := New(mt)
+
+	err := u.RunWithTimeout(context.Background(), 10*time.Millisecond, func(_ context.Context) error {
+		time.Sleep(30 * time.Millisecond)
+		return nil
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if got := mt.state.Value(); got != " rolled back!" {
+		t.Errorf("expected rollback, got %q", got)
+	}
+
+	mt = NewMockTx()
+	u = New(mt)
+	err = u.RunWithTimeout(context.Background(), time.Second, func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("expected fn to see the deadline")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mt.state.Value(); got != " committed!" {
+		t.Errorf("expected commit, got %q", got)
+	}
+}
+
+// liveRollbackRunner is a MockTx whose Rollback fails, like a real driver
+// would, when its context is done.
+type liveRollbackRunner struct {
+	*MockTx
+	deadline bool
+}
+
+func (r *liveRollbackRunner) Rollback(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	_, r.deadline = ctx.Deadline()
+	return r.MockTx.Rollback(ctx)
+}
+
+// TestRollbackAfterCancel verifies that a unit of work failing because its
+// context was cancelled still rolls back, with a bounded context that keeps
+// the transaction.
+func TestRollbackAfterCancel(t *testing.T) {
+	runner := &liveRollbackRunner{MockTx: NewMockTx()}
+	u := New(runner, WithRollbackTimeout(time.Second))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	err := u.Run(ctx, func(ctx context.Context) error {
+		cancel()
+		return ctx.Err()
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected context.Canceled, got %v", err)
+	}
+	if got := runner.State().Status(); got != StateRolledBack {
+		t.Errorf("expected a rollback, got status %v", got)
+	}
+	if !runner.deadline {
+		t.Error("expected the rollback context to be bounded by the rollback timeout")
+	}
+}
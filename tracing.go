@@ -0,0 +1,67 @@
+package uow
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WithTracer makes Run create spans with tracer for every unit of work: a
+// "uow.run" span covering the whole call, with child spans "uow.begin",
+// "uow.fn", "uow.commit" and "uow.rollback" for each attempt. Spans carry the
+// runner type in the "uow.runner" attribute, the "uow.run" span also carries
+// the transaction ID in "uow.tx_id", and failures are recorded on them. To use
+// the globally registered provider, pass otel.Tracer("github.com/agtabesh/uow").
+// Without a tracer no span is created and Run does not allocate for tracing.
+func WithTracer(tracer trace.Tracer) Option {
+	return func(c *config) {
+		c.tracer = tracer
+	}
+}
+
+// WithSpanLinks attaches links to the "uow.run" span created for a single call
+// to Run, correlating the transaction with upstream operations such as the
+// producer of a message being processed. It has no effect without a tracer.
+func WithSpanLinks(links ...trace.Link) RunOption {
+	return func(rc *runConfig) {
+		rc.spanLinks = append(rc.spanLinks, links...)
+	}
+}
+
+// startRunSpan starts the span covering a call to Run. It returns a nil span
+// when no tracer is configured.
+func (u *UoW) startRunSpan(ctx context.Context, rc *runConfig) (context.Context, trace.Span) {
+	if u.config.tracer == nil {
+		return ctx, nil
+	}
+	return u.config.tracer.Start(ctx, "uow.run",
+		trace.WithLinks(rc.spanLinks...),
+		trace.WithAttributes(
+			attribute.String("uow.runner", u.runnerName),
+			attribute.String("uow.tx_id", rc.txID),
+		),
+	)
+}
+
+// startSpan starts a child span with the given name. It returns a nil span
+// when no tracer is configured.
+func (u *UoW) startSpan(ctx context.Context, name string) (context.Context, trace.Span) {
+	if u.config.tracer == nil {
+		return ctx, nil
+	}
+	return u.config.tracer.Start(ctx, name, trace.WithAttributes(attribute.String("uow.runner", u.runnerName)))
+}
+
+// endSpan records err on span, if any, and ends it. A nil span is ignored.
+func endSpan(span trace.Span, err error) {
+	if span == nil {
+		return
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,136 @@
+package uow
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newRecordingTracer returns a tracer whose finished spans are captured by the
+// returned recorder.
+func newRecordingTracer(t *testing.T) (trace.Tracer, *tracetest.SpanRecorder) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = provider.Shutdown(context.Background()) })
+	return provider.Tracer("uow"), recorder
+}
+
+// TestWithSpanLinks verifies that the links passed to Run are attached to the
+// created span.
+func TestWithSpanLinks(t *testing.T) {
+	tracer, recorder := newRecordingTracer(t)
+	u := New(NewMockTx(), WithTracer(tracer))
+
+	upstream := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+	})
+	err := u.Run(context.Background(), func(_ context.Context) error {
+		return nil
+	}, WithSpanLinks(trace.Link{SpanContext: upstream}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	spans := recorder.Ended()
+	run := spans[len(spans)-1]
+	if run.Name() != "uow.run" {
+		t.Fatalf("expected uow.run to end last, got %s", run.Name())
+	}
+	links := run.Links()
+	if len(links) != 1 || !links[0].SpanContext.Equal(upstream) {
+		t.Errorf("expected the upstream span link, got %v", links)
+	}
+}
+
+// TestWithSpanLinks_NoTracer verifies that links are ignored without a tracer.
+func TestWithSpanLinks_NoTracer(t *testing.T) {
+	u := New(NewMockTx())
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		if trace.SpanFromContext(ctx).SpanContext().IsValid() {
+			t.Error("expected no span without a tracer")
+		}
+		return nil
+	}, WithSpanLinks(trace.Link{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestWithTracer_ChildSpans verifies the spans created for a failed and a
+// successful attempt, their parent, runner attribute and recorded errors.
+func TestWithTracer_ChildSpans(t *testing.T) {
+	tracer, recorder := newRecordingTracer(t)
+	u := New(NewMockTx(), WithTracer(tracer), WithMaxRetries(1), WithRetryIf(isErrRetryable))
+
+	attempts := 0
+	err := u.Run(context.Background(), func(_ context.Context) error {
+		attempts++
+		if attempts == 1 {
+			return errRetryable
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	var run sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		names = append(names, span.Name())
+		if span.Name() == "uow.run" {
+			run = span
+		}
+	}
+	want := []string{"uow.begin", "uow.fn", "uow.rollback", "uow.begin", "uow.fn", "uow.commit", "uow.run"}
+	if !reflect.DeepEqual(names, want) {
+		t.Fatalf("expected spans %v, got %v", want, names)
+	}
+
+	for _, span := range recorder.Ended() {
+		if runner := attributeValue(span, "uow.runner"); runner != "*uow.MockTx" {
+			t.Errorf("expected runner attribute on %s, got %q", span.Name(), runner)
+		}
+		if span != run && span.Parent().SpanID() != run.SpanContext().SpanID() {
+			t.Errorf("expected %s to be a child of uow.run", span.Name())
+		}
+	}
+
+	fnSpan := recorder.Ended()[1]
+	if fnSpan.Status().Code != codes.Error || len(fnSpan.Events()) == 0 {
+		t.Errorf("expected the error of the first attempt on its fn span, got %v", fnSpan.Status())
+	}
+}
+
+// TestWithTracer_NoTracerAllocations verifies that span handling does not
+// allocate when no tracer is configured.
+func TestWithTracer_NoTracerAllocations(t *testing.T) {
+	u := New(NewMockTx())
+	ctx := context.Background()
+	allocs := testing.AllocsPerRun(100, func() {
+		_, span := u.startSpan(ctx, "uow.fn")
+		endSpan(span, nil)
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations, got %v", allocs)
+	}
+}
+
+// attributeValue returns the string value of the attribute key on span.
+func attributeValue(span sdktrace.ReadOnlySpan, key attribute.Key) string {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value.AsString()
+		}
+	}
+	return ""
+}
@@ -0,0 +1,37 @@
+package uow
+
+import "context"
+
+// txIDKey is the context key for storing a transaction ID supplied by the
+// caller.
+var txIDKey = ctxKey{"tx_id"}
+
+// ContextWithTxID returns a copy of ctx carrying id as the transaction ID for
+// the next call to Run, e.g. a correlation ID received from an upstream
+// service. Without it Run generates a random version 4 UUID. The ID is shared
+// by all attempts of a retried run.
+func ContextWithTxID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, txIDKey, id)
+}
+
+// TxID returns the ID of the unit of work running in ctx, for logging or
+// propagating it downstream. The ID stays the same for the whole call to Run,
+// including retries, and is reported as "tx_id" in logs and as the
+// "uow.tx_id" attribute of the "uow.run" span. Outside a unit of work it
+// returns the ID attached with ContextWithTxID, or an empty string.
+func TxID(ctx context.Context) string {
+	if rs := runStateFrom(ctx); rs != nil {
+		return rs.txID
+	}
+	id, _ := ctx.Value(txIDKey).(string)
+	return id
+}
+
+// resolveTxID returns the transaction ID supplied with ContextWithTxID, or a
+// newly generated one.
+func resolveTxID(ctx context.Context) string {
+	if id, _ := ctx.Value(txIDKey).(string); id != "" {
+		return id
+	}
+	return newTxID()
+}
@@ -0,0 +1,84 @@
+package uow
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// TestTxID verifies that the ID is stable for the attempts of a single Run,
+// differs across runs and is reported on the run span.
+func TestTxID(t *testing.T) {
+	if id := TxID(context.Background()); id != "" {
+		t.Errorf("expected no ID outside a unit of work, got %q", id)
+	}
+
+	tracer, recorder := newRecordingTracer(t)
+	u := New(NewMockTx(), WithTracer(tracer), WithMaxRetries(1), WithRetryIf(isErrRetryable))
+
+	var ids []string
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		ids = append(ids, TxID(ctx))
+		if len(ids) == 1 {
+			return errRetryable
+		}
+		ids = append(ids, TxID(ctx))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids[0]) != 36 || ids[0] != ids[1] || ids[1] != ids[2] {
+		t.Fatalf("expected one UUID for the whole run, got %v", ids)
+	}
+
+	spans := recorder.Ended()
+	run := spans[len(spans)-1]
+	if want := attribute.String("uow.tx_id", ids[0]); !hasAttribute(run.Attributes(), want) {
+		t.Errorf("expected %v on the run span, got %v", want, run.Attributes())
+	}
+
+	var next string
+	if err := u.Run(context.Background(), func(ctx context.Context) error {
+		next = TxID(ctx)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if next == "" || next == ids[0] {
+		t.Errorf("expected a new ID for a new run, got %q after %q", next, ids[0])
+	}
+}
+
+// TestContextWithTxID verifies that an ID supplied by the caller is used
+// instead of a generated one.
+func TestContextWithTxID(t *testing.T) {
+	ctx := ContextWithTxID(context.Background(), "request-42")
+	if id := TxID(ctx); id != "request-42" {
+		t.Errorf("expected the supplied ID outside the run, got %q", id)
+	}
+
+	u := New(NewMockTx())
+	var got string
+	err := u.Run(ctx, func(ctx context.Context) error {
+		got = TxID(ctx)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != "request-42" {
+		t.Errorf("expected the supplied ID, got %q", got)
+	}
+}
+
+// hasAttribute reports whether attrs contains want.
+func hasAttribute(attrs []attribute.KeyValue, want attribute.KeyValue) bool {
+	for _, kv := range attrs {
+		if kv == want {
+			return true
+		}
+	}
+	return false
+}
@@ -2,7 +2,11 @@ package uow
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 // Runner interface defines the methods required for a unit of work (UoW) runner.
@@ -10,6 +14,12 @@ import (
 // committing changes, and rolling back in case of errors. The `Ctx` method provides a
 // context suitable for the transaction. `Get` retrieves any data associated with the UoW.
 // `Commit` and `Rollback` handle transaction completion.
+//
+// `Commit` and `Rollback` must tolerate being called with a context that
+// carries no transaction of the runner, e.g. because its `Ctx` failed or was
+// never called, as a composite runner may do when another runner failed to
+// begin: they then do nothing and return nil. Every runner in this package
+// follows this contract.
 type Runner interface {
 	// Ctx returns a context suitable for the transaction. This context may include
 	// transaction-specific information or deadlines. An error indicates a failure
@@ -21,7 +31,8 @@ type Runner interface {
 	Get(ctx context.Context) any
 
 	// Commit commits the transaction, persisting any changes made during the unit of work.
-	// An error indicates a failure to commit the transaction.
+	// An error indicates a failure to commit the transaction. A failed Commit
+	// must still end the transaction, as Rollback is not called after it.
 	Commit(ctx context.Context) error
 
 	// Rollback rolls back the transaction, undoing any changes made during the unit of work.
@@ -35,45 +46,522 @@ type Runner interface {
 type UoW struct {
 	// runner handles the underlying transaction management.
 	runner Runner
+
+	// config holds the optional behavior configured through options.
+	config config
+
+	// runnerName is the concrete type name of the runner.
+	runnerName string
+
+	// counters tracks the transactions reported by Counters.
+	counters *counters
+}
+
+// ErrAbort can be returned by fn, possibly wrapped, to roll the unit of work
+// back on purpose, e.g. when there turns out to be nothing to do. Run then
+// returns nil instead of an error. After-rollback hooks still receive
+// ErrAbort as the cause, and the attempt is never retried.
+var ErrAbort = errors.New("unit of work aborted")
+
+// Option configures optional behavior of a UoW. Options are passed to New.
+type Option func(*config)
+
+// config holds the optional settings of a UoW.
+type config struct {
+	// name identifies the unit of work in summaries.
+	name string
+
+	// metadata is attached to every summary produced by the unit of work.
+	metadata map[string]string
+
+	// auditWriter is invoked inside the transaction right before commit.
+	auditWriter func(ctx context.Context, summary Summary) error
+
+	// connLost classifies errors that indicate a lost connection.
+	connLost []ConnLostClassifier
+
+	// maxRetries is the number of additional attempts after a retryable failure.
+	maxRetries int
+
+	// retryIf classifies errors that may be retried.
+	retryIf []func(err error) bool
+
+	// retryDeadline retries context.DeadlineExceeded and never retries
+	// context.Canceled.
+	retryDeadline bool
+
+	// backoff is the delay before the first retry.
+	backoff time.Duration
+
+	// backoffMax caps the delay between retries; zero leaves it unbounded.
+	backoffMax time.Duration
+
+	// jitter randomizes the delay between retries.
+	jitter bool
+
+	// maxElapsed bounds the total time spent retrying.
+	maxElapsed time.Duration
+
+	// clock measures time for the retry loop; nil uses the time package.
+	clock clock
+
+	// statementTimeout bounds the execution time of individual statements.
+	statementTimeout time.Duration
+
+	// commitChecklist enables verification of registered resources.
+	commitChecklist bool
+
+	// conflict handles classified conflicts from fn within the transaction.
+	conflict *conflictHandler
+
+	// leaderCheck restricts the unit of work to the cluster leader.
+	leaderCheck func(ctx context.Context) (bool, error)
+
+	// metrics receives transaction outcomes.
+	metrics MetricsCollector
+
+	// afterBegin hooks run inside the transaction right after it started.
+	afterBegin []func(ctx context.Context) error
+
+	// preconditions run inside the transaction before fn.
+	preconditions []func(ctx context.Context) error
+
+	// beforeCommit hooks run inside the transaction right before commit.
+	beforeCommit []func(ctx context.Context) error
+
+	// afterCommit hooks run after a successful commit.
+	afterCommit []func(ctx context.Context)
+
+	// afterRollback hooks run after a rollback.
+	afterRollback []func(ctx context.Context, cause error)
+
+	// beginTimeout bounds the Ctx call of every attempt.
+	beginTimeout time.Duration
+
+	// rollbackTimeout bounds a rollback after the context has ended.
+	rollbackTimeout time.Duration
+
+	// readOnly runs every unit of work in a read-only transaction.
+	readOnly bool
+
+	// logger receives transaction events.
+	logger Logger
+
+	// slowThreshold is the duration of Run above which a warning is logged;
+	// zero disables the warning.
+	slowThreshold time.Duration
+
+	// tracer creates spans around Run.
+	tracer trace.Tracer
+
+	// idempotency records the keys of units of work run with RunIdempotent.
+	idempotency IdempotencyStore
+
+	// commitPolicy decides between commit and rollback after fn.
+	commitPolicy CommitPolicy
+
+	// panicHandler converts a panic in fn into the error returned by Run.
+	panicHandler func(recovered any) error
+
+	// shouldRollback decides whether an error from fn rolls back.
+	shouldRollback func(err error) bool
+
+	// eventSink receives the events buffered with AppendEvent after commit.
+	eventSink EventSink
+}
+
+// RunOption configures a single call to Run.
+type RunOption func(*runConfig)
+
+// runConfig holds the settings of a single call to Run.
+type runConfig struct {
+	// spanLinks are attached to the span created for the call.
+	spanLinks []trace.Link
+
+	// readOnly runs the call in a read-only transaction.
+	readOnly bool
+
+	// dryRun always rolls the call back.
+	dryRun bool
+
+	// timeout bounds the whole call, including commit and rollback.
+	timeout time.Duration
+
+	// txID identifies the call; it is shared by all of its attempts.
+	txID string
 }
 
-// New creates a new UoW instance with the given runner.
-func New(runner Runner) UoW {
-	return UoW{
-		runner: runner,
+// WithName sets a name identifying the unit of work. The name is reported in
+// the Summary handed to the audit writer.
+func WithName(name string) Option {
+	return func(c *config) {
+		c.name = name
 	}
 }
 
+// WithMetadata attaches a key/value pair to the unit of work. Metadata is
+// reported in the Summary handed to the audit writer.
+func WithMetadata(key, value string) Option {
+	return func(c *config) {
+		if c.metadata == nil {
+			c.metadata = make(map[string]string)
+		}
+		c.metadata[key] = value
+	}
+}
+
+// WithAuditWriter registers a function that writes an audit record for every
+// transaction. It is invoked right before commit with the transactional
+// context, so the record should be written through the handle returned by Get
+// and is committed or rolled back together with the business change. An error
+// returned by the writer rolls the transaction back.
+func WithAuditWriter(fn func(ctx context.Context, summary Summary) error) Option {
+	return func(c *config) {
+		c.auditWriter = fn
+	}
+}
+
+// Summary describes a single execution of a unit of work.
+type Summary struct {
+	// TxID uniquely identifies the transaction.
+	TxID string
+
+	// Name is the name configured with WithName.
+	Name string
+
+	// Metadata holds the key/value pairs configured with WithMetadata.
+	Metadata map[string]string
+
+	// Affected is the number of rows or documents reported with RecordAffected.
+	Affected int64
+}
+
+// New creates a new UoW instance with the given runner and options. Without
+// options, every Run starts one transaction, commits it when fn succeeds and
+// rolls it back otherwise. Options add cross-cutting behavior, e.g.
+// WithMaxRetries and WithRetryIf for retries, WithBeforeCommit and
+// WithAfterCommit for hooks, WithLogger for logging, WithTracer for tracing
+// and WithMetrics for metrics. Options are applied in order; options setting
+// a single value keep the last one, while options registering callbacks or
+// classifiers accumulate.
+func New(runner Runner, opts ...Option) UoW {
+	u := UoW{
+		runner:     runner,
+		runnerName: RunnerName(runner),
+		counters:   &counters{},
+	}
+	for _, opt := range opts {
+		opt(&u.config)
+	}
+	return u
+}
+
 // Get delegates to the underlying runner to retrieve data associated with the unit of work.
+// When ctx belongs to a unit of work that has already committed or rolled
+// back, Get returns ErrFinished instead of a handle to the ended transaction.
 func (u *UoW) Get(ctx context.Context) any {
+	if runFinished(ctx) {
+		return ErrFinished
+	}
 	return u.runner.Get(ctx)
 }
 
+// GetStrict is like Get but fails instead of returning a non-transactional
+// handle: it returns ErrNoTransaction when ctx does not belong to a unit of
+// work and ErrFinished when it belongs to one that has already committed or
+// rolled back, e.g. because the context was kept after Run returned.
+func (u *UoW) GetStrict(ctx context.Context) (any, error) {
+	if err := CheckActive(ctx); err != nil {
+		return nil, err
+	}
+	return u.runner.Get(ctx), nil
+}
+
 // Run executes a given function within a transaction managed by the runner.
 // It handles potential errors during the function execution and transaction management.
 // If the function returns an error, the transaction is rolled back. Otherwise, the transaction is committed.
-func (u *UoW) Run(ctx context.Context, fn func(ctx context.Context) error) error {
+// Per-run options, such as WithSpanLinks, apply to this call only.
+func (u *UoW) Run(ctx context.Context, fn func(ctx context.Context) error, opts ...RunOption) error {
+	_, err := u.execute(ctx, fn, opts...)
+	return err
+}
+
+// execute runs fn according to the configured policies and returns the state
+// of the final attempt.
+func (u *UoW) execute(ctx context.Context, fn func(ctx context.Context) error, opts ...RunOption) (*runState, error) {
+	var rc runConfig
+	for _, opt := range opts {
+		opt(&rc)
+	}
+
+	if err := u.checkLeader(ctx); err != nil {
+		return nil, err
+	}
+
+	if rc.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, rc.timeout)
+		defer cancel()
+	}
+
+	rc.txID = resolveTxID(ctx)
+	start := u.clock().Now()
+	ctx, span := u.startRunSpan(ctx, &rc)
+	rs, err := u.runWithRetry(ctx, fn, &rc)
+	err = u.classifyConnLost(err)
+	endSpan(span, err)
+	u.checkSlow(rs, start)
+	return rs, err
+}
+
+// run performs a single attempt of fn within a transaction.
+func (u *UoW) run(ctx context.Context, fn func(ctx context.Context) error, rs *runState) (result error) {
+	// Attach the state of this attempt so that it is reachable from fn.
+	ctx = withRunState(ctx, rs)
+	start := time.Now()
+
 	// Obtain a transaction-specific context from the runner.
-	uowCtx, err := u.runner.Ctx(ctx)
+	beginCtx, span := u.startSpan(ctx, "uow.begin")
+	u.counters.beginning()
+	uowCtx, release, err := u.begin(beginCtx)
+	u.counters.began(err == nil)
+	endSpan(span, err)
 	if err != nil {
-		// Return an error if starting the transaction fails.
-		return fmt.Errorf("failed to start transaction: %w", err)
+		u.observeBeginError(err)
+		u.logError("failed to start transaction", rs, "error", err)
+		if errors.Is(err, ErrBeginTimeout) {
+			return err
+		}
+		// Return an error if starting the transaction fails, naming the
+		// runner so that failures of several runners can be told apart.
+		return fmt.Errorf("failed to start transaction on %s: %w", describeRunner(u.runner), err)
+	}
+	defer release()
+	// Count the attempt as finished on every way out, including panics in
+	// the runner or in hooks.
+	defer func() { u.counters.finished(rs.commitSucceeded) }()
+	// Mark the attempt as finished on every way out. Commit and rollback
+	// mark it as soon as the runner returns, before any hook runs.
+	defer rs.done.Store(true)
+	if span != nil {
+		// The transaction context derives from the begin span's context; make
+		// the run span current again so that later spans are its siblings.
+		uowCtx = trace.ContextWithSpan(uowCtx, trace.SpanFromContext(ctx))
 	}
+	u.logDebug("transaction started", rs)
+
+	// Roll back when fn or the work around it panics, so that the transaction
+	// and its session are not leaked, then let the panic propagate, or return
+	// it as an error when a panic handler is configured.
+	finished := false
+	defer func() {
+		if finished {
+			return
+		}
+		if p := recover(); p != nil {
+			cause := fmt.Errorf("panic: %v", p)
+			if u.config.panicHandler != nil {
+				if err := u.config.panicHandler(p); err != nil {
+					cause = err
+				}
+			}
+			rbCtx, cancel := u.rollbackContext(uowCtx)
+			rbErr := u.runner.Rollback(rbCtx)
+			cancel()
+			rs.done.Store(true)
+			rs.rollbackErr = rbErr
+			u.observeRollback(start, cause)
+			u.runAfterRollback(ctx, cause)
+			rs.rolledBack()
+			u.logError("transaction rolled back after panic", rs, "error", cause)
+			if u.config.panicHandler == nil {
+				panic(p)
+			}
+			result = cause
+			if rbErr != nil {
+				result = &RollbackError{OpErr: cause, RollbackErr: rbErr}
+			}
+		}
+	}()
 
-	// Execute the provided function within the transaction context.
-	err = fn(uowCtx)
+	// Execute the provided function within the transaction context, once the
+	// after-begin hooks have run and its preconditions hold.
+	// An error of fn that the WithShouldRollback predicate accepts is kept
+	// aside and returned once the transaction has committed; errors of the
+	// hooks and preconditions always roll back.
+	var keptErr error
+	err = u.runAfterBegin(uowCtx)
+	if err == nil {
+		err = u.checkPreconditions(uowCtx)
+	}
+	if err == nil {
+		fnCtx, span := u.startSpan(uowCtx, "uow.fn")
+		err = u.callFn(fnCtx, fn)
+		endSpan(span, err)
+		if u.keepOnCommit(err) {
+			keptErr, err = err, nil
+		}
+	}
+	if err == nil {
+		// Perform the work that must happen inside the transaction, right
+		// before commit.
+		err = u.prepareCommit(uowCtx, rs)
+	}
+	if u.config.commitChecklist {
+		// Verify that every registered resource was released.
+		if leakErr := rs.checklist.verify(); leakErr != nil {
+			err = errors.Join(err, leakErr)
+		}
+	}
+	finished = true
+	if err == nil && ctx.Err() != nil {
+		// The context ended while fn was running; committing now would likely
+		// fail or race with the caller giving up, so roll back instead.
+		if rs.timeout > 0 {
+			err = fmt.Errorf("unit of work timed out after %v: %w", rs.timeout, ctx.Err())
+		} else {
+			err = fmt.Errorf("context expired before commit: %w", ctx.Err())
+		}
+	}
+	if err == nil && !u.markedForCommit(rs) {
+		// Under the explicit commit policy, fn did not ask to commit.
+		err = ErrAbort
+	}
+	if err == nil && (rs.readOnly || rs.dryRun) {
+		// A read-only transaction has nothing to commit, and a dry run must
+		// not persist anything.
+		err = ErrAbort
+	}
 	if err != nil {
 		// If the function returns an error, attempt to rollback the transaction.
-		rbErr := u.runner.Rollback(uowCtx)
-		if rbErr != nil {
-			// Return a combined error if both the operation and the rollback fail.
-			return fmt.Errorf("operation failed (%w) and rollback also failed: %w", err, rbErr)
+		if err := u.rollback(ctx, uowCtx, rs, start, err); err != nil || keptErr == nil {
+			return err
 		}
+		return keptErr
+	}
+
+	// If the function succeeds, commit the transaction. A failed commit is not
+	// followed by a rollback: the runner is responsible for ending the
+	// transaction when its commit fails.
+	commitCtx, span := u.startSpan(uowCtx, "uow.commit")
+	err = u.runner.Commit(commitCtx)
+	rs.done.Store(true)
+	endSpan(span, err)
+	if err != nil {
+		u.observeRollback(start, err)
+		u.logError("failed to commit transaction", rs, "error", err)
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	rs.commitSucceeded = true
+	u.observeCommit(start)
+	u.logDebug("transaction committed", rs)
+	u.runAfterCommit(ctx)
+	if events := rs.committed(); events != nil {
+		if err := u.publishEvents(ctx, events); err != nil {
+			u.logError("failed to publish events", rs, "error", err)
+			return err
+		}
+	}
+	if keptErr != nil {
+		rs.keptErr = true
+		return keptErr
+	}
+	return nil
+}
+
+// RollbackError is returned by Run when the unit of work failed and rolling
+// back its transaction failed as well. Callers can detect it with errors.As to
+// tell this case apart from a clean rollback, in which case Run returns the
+// error of the operation alone. Both errors remain reachable via errors.Is and
+// errors.As.
+type RollbackError struct {
+	// OpErr is the error that made the unit of work roll back.
+	OpErr error
+
+	// RollbackErr is the error returned by the runner's Rollback.
+	RollbackErr error
+}
+
+// Error implements the error interface.
+func (e *RollbackError) Error() string {
+	return fmt.Sprintf("operation failed (%v) and rollback also failed: %v", e.OpErr, e.RollbackErr)
+}
+
+// Unwrap returns the operation error and the rollback error.
+func (e *RollbackError) Unwrap() []error {
+	return []error{e.OpErr, e.RollbackErr}
+}
 
-		// Return the original error from the function.
+// rollback rolls back the transaction in uowCtx after cause made the attempt
+// fail and returns the error to report for the attempt. ctx is the context
+// outside the transaction.
+func (u *UoW) rollback(ctx, uowCtx context.Context, rs *runState, start time.Time, cause error) error {
+	// Roll back with a live context even when the unit of work was cancelled,
+	// so that the rollback can reach the data store.
+	rbCtx, cancel := u.rollbackContext(uowCtx)
+	defer cancel()
+	rbCtx, span := u.startSpan(rbCtx, "uow.rollback")
+	rbErr := u.runner.Rollback(rbCtx)
+	rs.done.Store(true)
+	endSpan(span, rbErr)
+	rs.rollbackErr = rbErr
+
+	// An aborted or discarded unit of work rolls back without reporting an
+	// error.
+	discarded := errors.Is(cause, ErrAbort)
+	if discarded {
+		u.observeRollback(start, nil)
+	} else {
+		u.observeRollback(start, cause)
+	}
+	u.runAfterRollback(ctx, cause)
+	rs.rolledBack()
+
+	if rbErr != nil {
+		u.logError("failed to roll back transaction", rs, "cause", cause, "error", rbErr)
+		// Return a combined error if both the operation and the rollback fail.
+		return &RollbackError{OpErr: cause, RollbackErr: rbErr}
+	}
+	if discarded {
+		u.logDebug("transaction discarded", rs)
+		return nil
+	}
+	u.logError("transaction rolled back", rs, "error", cause)
+
+	// Return the original error from the function.
+	return cause
+}
+
+// prepareCommit runs the pre-commit callbacks registered during the attempt,
+// such as write buffer flushes, then the before-commit hooks, and then writes
+// the audit record.
+func (u *UoW) prepareCommit(ctx context.Context, rs *runState) error {
+	for _, cb := range rs.preCommitCallbacks() {
+		if err := cb(ctx); err != nil {
+			return err
+		}
+	}
+	if err := u.runBeforeCommit(ctx); err != nil {
 		return err
 	}
+	if u.config.auditWriter != nil {
+		if err := u.config.auditWriter(ctx, u.summary(rs)); err != nil {
+			return fmt.Errorf("failed to write audit record: %w", err)
+		}
+	}
+	return nil
+}
 
-	// If the function succeeds, commit the transaction.
-	return u.runner.Commit(uowCtx)
+// summary builds the Summary of the run described by rs.
+func (u *UoW) summary(rs *runState) Summary {
+	metadata := make(map[string]string, len(u.config.metadata))
+	for k, v := range u.config.metadata {
+		metadata[k] = v
+	}
+	return Summary{
+		TxID:     rs.txID,
+		Name:     u.config.name,
+		Metadata: metadata,
+		Affected: rs.affected.Load(),
+	}
 }
@@ -4,8 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -111,6 +114,21 @@ func TestRun_DoubleError(t *testing.T) {
 	if !errors.Is(err, rbErr) {
 		t.Errorf("expected errors.Is(err, rbErr) to be true, got %v", err)
 	}
+	var rollbackErr *RollbackError
+	if !errors.As(err, &rollbackErr) {
+		t.Fatalf("expected a *RollbackError, got %T", err)
+	}
+	if rollbackErr.OpErr != fnErr || rollbackErr.RollbackErr != rbErr {
+		t.Errorf("expected both errors in the RollbackError, got %+v", rollbackErr)
+	}
+
+	u = New(&errorRunner{})
+	err = u.Run(ctx, func(_ context.Context) error {
+		return fnErr
+	})
+	if errors.As(err, &rollbackErr) {
+		t.Errorf("expected no RollbackError after a clean rollback, got %v", err)
+	}
 }
 
 // TestRollback tests the rollback scenario of the unit of work pattern.
@@ -131,6 +149,28 @@ func TestRollback(t *testing.T) {
 	}
 }
 
+// TestRun_Abort verifies that returning ErrAbort, possibly wrapped, rolls the
+// unit of work back and makes Run return nil.
+func TestRun_Abort(t *testing.T) {
+	for _, abortErr := range []error{ErrAbort, fmt.Errorf("nothing to do: %w", ErrAbort)} {
+		mt := NewMockTx()
+		txs := New(mt, WithMaxRetries(2), WithRetryIf(func(error) bool { return true }))
+		err := txs.Run(context.Background(), func(ctx context.Context) error {
+			txs.Get(ctx).(*State).SetValue("test state")
+			return abortErr
+		})
+		if err != nil {
+			t.Errorf("expected nil after an abort, got %v", err)
+		}
+		if mt.state.Value() != "test state rolled back!" {
+			t.Errorf("expected state to be 'test state rolled back!', got '%s'", mt.state.Value())
+		}
+		if n := mt.CallCount("ctx"); n != 1 {
+			t.Errorf("expected an aborted unit of work not to be retried, got %d attempts", n)
+		}
+	}
+}
+
 // runTestCase defines a table-driven test case for UoW.Run.
 type runTestCase struct {
 	name      string
@@ -234,6 +274,164 @@ func TestRun_CancelledContext(t *testing.T) {
 	}
 }
 
+// TestRun_ContextExpiredBeforeCommit verifies that a context ending while fn
+// runs makes Run roll back instead of committing.
+func TestRun_ContextExpiredBeforeCommit(t *testing.T) {
+	mt := NewMockTx()
+	txs := New(mt)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err := txs.Run(ctx, func(ctx context.Context) error {
+		txs.Get(ctx).(*State).SetValue("test state")
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || !strings.Contains(err.Error(), "context expired before commit") {
+		t.Errorf("expected a context expired before commit error, got %v", err)
+	}
+	if got := mt.State().Value(); got != "test state rolled back!" {
+		t.Errorf("expected state to be 'test state rolled back!', got '%s'", got)
+	}
+	if n := mt.CallCount("commit"); n != 0 {
+		t.Errorf("expected no commit, got %d", n)
+	}
+}
+
+// TestRun_ContextReusedAfterRun verifies that a context kept after Run
+// returned is reported as belonging to a finished unit of work.
+func TestRun_ContextReusedAfterRun(t *testing.T) {
+	txs := New(NewMockTx())
+	if _, err := txs.GetStrict(context.Background()); !errors.Is(err, ErrNoTransaction) {
+		t.Errorf("expected ErrNoTransaction outside a unit of work, got %v", err)
+	}
+
+	var kept context.Context
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		kept = ctx
+		if err := CheckActive(ctx); err != nil {
+			t.Errorf("expected an active unit of work inside fn, got %v", err)
+		}
+		_, err := txs.GetStrict(ctx)
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckActive(kept); !errors.Is(err, ErrFinished) {
+		t.Errorf("expected ErrFinished after Run, got %v", err)
+	}
+	if _, err := txs.GetStrict(kept); !errors.Is(err, ErrFinished) {
+		t.Errorf("expected ErrFinished from GetStrict after Run, got %v", err)
+	}
+	if got := txs.Get(kept); got != ErrFinished {
+		t.Errorf("expected ErrFinished from Get after Run, got %v", got)
+	}
+}
+
+// TestRun_FinishedInHooks verifies that the unit of work is reported as
+// finished as soon as the transaction has ended, before the after-commit and
+// after-rollback hooks run.
+func TestRun_FinishedInHooks(t *testing.T) {
+	var txs UoW
+	check := func(ctx context.Context, hook string) {
+		if err := CheckActive(ctx); !errors.Is(err, ErrFinished) {
+			t.Errorf("expected ErrFinished in the %s hook, got %v", hook, err)
+		}
+		if got := txs.Get(ctx); got != ErrFinished {
+			t.Errorf("expected ErrFinished from Get in the %s hook, got %v", hook, got)
+		}
+	}
+	txs = New(NewMockTx(),
+		WithAfterCommit(func(ctx context.Context) { check(ctx, "after-commit") }),
+		WithAfterRollback(func(ctx context.Context, _ error) { check(ctx, "after-rollback") }),
+	)
+
+	if err := txs.Run(context.Background(), func(context.Context) error { return nil }); err != nil {
+		t.Fatal(err)
+	}
+	fnErr := errors.New("fn failed")
+	if err := txs.Run(context.Background(), func(context.Context) error { return fnErr }); !errors.Is(err, fnErr) {
+		t.Fatalf("expected fn error, got %v", err)
+	}
+}
+
+// TestRun_Panic verifies that a panic inside fn rolls the transaction back and
+// is propagated to the caller.
+func TestRun_Panic(t *testing.T) {
+	mt := NewMockTx()
+	txs := New(mt)
+
+	defer func() {
+		if p := recover(); p != "boom" {
+			t.Errorf("expected the panic to propagate, got %v", p)
+		}
+		if got := mt.state.Value(); got != " rolled back!" {
+			t.Errorf("expected state ' rolled back!', got %q", got)
+		}
+	}()
+	_ = txs.Run(context.Background(), func(_ context.Context) error {
+		panic("boom")
+	})
+	t.Error("expected Run to panic")
+}
+
+// TestInTransaction verifies that InTransaction reports true inside fn,
+// including nested units of work, and false outside.
+func TestInTransaction(t *testing.T) {
+	ctx := context.Background()
+	if InTransaction(ctx) {
+		t.Error("expected false outside a unit of work")
+	}
+	u := New(NewMockTx())
+	err := u.Run(ctx, func(ctx context.Context) error {
+		if !InTransaction(ctx) {
+			t.Error("expected true inside fn")
+		}
+		return u.Run(ctx, func(ctx context.Context) error {
+			if !InTransaction(ctx) {
+				t.Error("expected true inside a nested fn")
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if InTransaction(ctx) {
+		t.Error("expected false after Run returned")
+	}
+}
+
+// TestFromContext verifies that FromContext returns the running UoW inside fn,
+// and nothing outside of it or after Run returned.
+func TestFromContext(t *testing.T) {
+	if _, ok := FromContext(context.Background()); ok {
+		t.Error("expected no UoW outside a unit of work")
+	}
+	mt := NewMockTx()
+	u := New(mt)
+	var kept context.Context
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		kept = ctx
+		got, ok := FromContext(ctx)
+		if !ok || got != &u {
+			t.Fatalf("expected the running UoW, got %v", got)
+		}
+		got.Get(ctx).(*State).SetValue("from context")
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := mt.State().Value(); got != "from context committed!" {
+		t.Errorf("expected the value set through the UoW to commit, got %q", got)
+	}
+	if _, ok := FromContext(kept); ok {
+		t.Error("expected no UoW from a context kept after Run returned")
+	}
+}
+
 // TestSqlTx_Commit verifies a SQL transaction commits successfully using an
 // in-memory SQLite database.
 func TestSqlTx_Commit(t *testing.T) {
@@ -411,3 +609,152 @@ func TestMongoTx_Integration_Rollback(t *testing.T) {
 		t.Errorf("expected 0 documents after rollback, got %d", count)
 	}
 }
+
+// openAuditDB opens an in-memory SQLite database with a data table and an
+// audit table.
+func openAuditDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec("CREATE TABLE test (id INTEGER PRIMARY KEY, name TEXT)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec("CREATE TABLE audit (tx_id TEXT, name TEXT, actor TEXT)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// countRows returns the number of rows in table.
+func countRows(t *testing.T, db *sql.DB, table string) int {
+	t.Helper()
+	var count int
+	if err := db.QueryRow("SELECT COUNT(*) FROM " + table).Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	return count
+}
+
+// TestWithAuditWriter verifies that the audit record is written inside the
+// transaction and commits or rolls back together with the data.
+func TestWithAuditWriter(t *testing.T) {
+	auditErr := errors.New("audit failed")
+
+	tests := []struct {
+		name      string
+		fnErr     error
+		auditErr  error
+		wantRows  int
+		wantAudit int
+	}{
+		{name: "committed_together", wantRows: 1, wantAudit: 1},
+		{name: "fn_error_rolls_back", fnErr: errors.New("fn failed"), wantRows: 0, wantAudit: 0},
+		{name: "audit_error_rolls_back", auditErr: auditErr, wantRows: 0, wantAudit: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := openAuditDB(t)
+			sqlTx := NewSQLTx(db)
+
+			var summary Summary
+			txs := New(sqlTx,
+				WithName("create-user"),
+				WithMetadata("actor", "alice"),
+				WithAuditWriter(func(ctx context.Context, s Summary) error {
+					summary = s
+					tx := sqlTx.Get(ctx).(*sql.Tx)
+					_, err := tx.ExecContext(ctx, "INSERT INTO audit (tx_id, name, actor) VALUES (?, ?, ?)",
+						s.TxID, s.Name, s.Metadata["actor"])
+					if err != nil {
+						return err
+					}
+					return tt.auditErr
+				}),
+			)
+
+			err := txs.Run(context.Background(), func(ctx context.Context) error {
+				tx := txs.Get(ctx).(*sql.Tx)
+				_, err := tx.ExecContext(ctx, "INSERT INTO test (name) VALUES (?)", "hello")
+				if err != nil {
+					return err
+				}
+				return tt.fnErr
+			})
+			if tt.fnErr != nil && !errors.Is(err, tt.fnErr) {
+				t.Errorf("expected fn error, got %v", err)
+			}
+			if tt.auditErr != nil && !errors.Is(err, tt.auditErr) {
+				t.Errorf("expected audit error, got %v", err)
+			}
+			if tt.fnErr == nil && tt.auditErr == nil && err != nil {
+				t.Fatal(err)
+			}
+
+			if got := countRows(t, db, "test"); got != tt.wantRows {
+				t.Errorf("expected %d data rows, got %d", tt.wantRows, got)
+			}
+			if got := countRows(t, db, "audit"); got != tt.wantAudit {
+				t.Errorf("expected %d audit rows, got %d", tt.wantAudit, got)
+			}
+			if tt.fnErr == nil {
+				if summary.TxID == "" || summary.Name != "create-user" || summary.Metadata["actor"] != "alice" {
+					t.Errorf("unexpected summary %+v", summary)
+				}
+			}
+		})
+	}
+}
+
+// TestRunners_FinishWithoutCtx verifies that every runner tolerates Commit and
+// Rollback with a context that carries none of its transactions.
+func TestRunners_FinishWithoutCtx(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = db.Close() })
+	_, readDB := openSQLitePools(t)
+	_, redisClient := newMiniredisClient(t)
+	entClient := &fakeEntClient{log: new([]string)}
+	mt := NewMockTx()
+
+	runners := map[string]Runner{
+		"MockTx":          mt,
+		"NoopRunner":      NewNoopRunner(nil),
+		"SQLTx":           NewSQLTx(db),
+		"SQLiteReadTx":    NewSQLiteReadTx(readDB),
+		"PgxTx":           NewPgxTx(nil),
+		"GormTx":          NewGormTx(openGorm(t)),
+		"SqlxTx":          NewSqlxTx(openSqlx(t)),
+		"BunTx":           NewBunTx(openBun(t)),
+		"EntTx":           NewEntTx(entClient, entClient.Tx),
+		"RedisTx":         NewRedisTx(redisClient),
+		"BoltTx":          NewBoltTx(openBolt(t)),
+		"MongoTx":         NewMongoTx(newLazyMongoClient(t), "test"),
+		"FirestoreTx":     NewFirestoreTx(newLazyFirestoreClient(t)),
+		"MultiRunner":     NewMultiRunner(NewMockTx(), NewNoopRunner(nil)),
+		"TimeoutRunner":   NewTimeoutRunner(NewMockTx(), time.Second),
+		"RecordingRunner": NewRecordingRunner(NewMockTx()),
+	}
+	for name, runner := range runners {
+		t.Run(name, func(t *testing.T) {
+			if err := runner.Rollback(context.Background()); err != nil {
+				t.Errorf("expected Rollback without a transaction to do nothing, got %v", err)
+			}
+			if err := runner.Commit(context.Background()); err != nil {
+				t.Errorf("expected Commit without a transaction to do nothing, got %v", err)
+			}
+		})
+	}
+	if got := mt.State().Value(); got != "" {
+		t.Errorf("expected the mock state to be unchanged, got %q", got)
+	}
+}
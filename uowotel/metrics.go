@@ -0,0 +1,92 @@
+// Package uowotel integrates the uow package with OpenTelemetry. It lives in
+// its own package so that importing uow does not pull in the OpenTelemetry
+// metrics API.
+package uowotel
+
+import (
+	"context"
+	"time"
+
+	"github.com/agtabesh/uow"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+)
+
+// Metrics implements uow.MetricsCollector on top of the OpenTelemetry metrics
+// API. It records transaction outcomes as counters and transaction latency as
+// a histogram, all labeled with the runner type.
+var _ uow.MetricsCollector = &Metrics{}
+
+// Metrics struct holds the instruments created on the meter.
+type Metrics struct {
+	commits     metric.Int64Counter
+	rollbacks   metric.Int64Counter
+	beginErrors metric.Int64Counter
+	duration    metric.Float64Histogram
+}
+
+// NewMetrics creates the instruments on meter and returns a collector to pass
+// to uow.WithMetrics. The following instruments are registered:
+//
+//   - uow.commits: number of committed transactions
+//   - uow.rollbacks: number of rolled back transactions
+//   - uow.begin_errors: number of transactions that failed to start
+//   - uow.transaction.duration: transaction latency in seconds, with an
+//     "outcome" attribute of "commit" or "rollback"
+//
+// Every instrument carries a "runner" attribute with the runner type name.
+func NewMetrics(meter metric.Meter) (*Metrics, error) {
+	commits, err := meter.Int64Counter("uow.commits",
+		metric.WithDescription("Number of committed transactions."))
+	if err != nil {
+		return nil, err
+	}
+	rollbacks, err := meter.Int64Counter("uow.rollbacks",
+		metric.WithDescription("Number of rolled back transactions."))
+	if err != nil {
+		return nil, err
+	}
+	beginErrors, err := meter.Int64Counter("uow.begin_errors",
+		metric.WithDescription("Number of transactions that failed to start."))
+	if err != nil {
+		return nil, err
+	}
+	duration, err := meter.Float64Histogram("uow.transaction.duration",
+		metric.WithDescription("Duration of transactions."),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &Metrics{
+		commits:     commits,
+		rollbacks:   rollbacks,
+		beginErrors: beginErrors,
+		duration:    duration,
+	}, nil
+}
+
+// ObserveCommit records a committed transaction.
+func (m *Metrics) ObserveCommit(runner string, d time.Duration) {
+	ctx := context.Background()
+	m.commits.Add(ctx, 1, metric.WithAttributes(attribute.String("runner", runner)))
+	m.duration.Record(ctx, d.Seconds(), metric.WithAttributes(
+		attribute.String("runner", runner),
+		attribute.String("outcome", "commit"),
+	))
+}
+
+// ObserveRollback records a rolled back transaction.
+func (m *Metrics) ObserveRollback(runner string, d time.Duration, _ error) {
+	ctx := context.Background()
+	m.rollbacks.Add(ctx, 1, metric.WithAttributes(attribute.String("runner", runner)))
+	m.duration.Record(ctx, d.Seconds(), metric.WithAttributes(
+		attribute.String("runner", runner),
+		attribute.String("outcome", "rollback"),
+	))
+}
+
+// ObserveBeginError records a transaction that failed to start.
+func (m *Metrics) ObserveBeginError(runner string, _ error) {
+	m.beginErrors.Add(context.Background(), 1, metric.WithAttributes(attribute.String("runner", runner)))
+}
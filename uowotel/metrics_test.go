@@ -0,0 +1,111 @@
+package uowotel
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/agtabesh/uow"
+	"go.opentelemetry.io/otel/attribute"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+)
+
+// collect gathers the metrics recorded by reader, keyed by instrument name.
+func collect(t *testing.T, reader *sdkmetric.ManualReader) map[string]metricdata.Aggregation {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatal(err)
+	}
+	got := make(map[string]metricdata.Aggregation)
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			got[m.Name] = m.Data
+		}
+	}
+	return got
+}
+
+// sum returns the total of an Int64 sum aggregation for the given runner.
+func sum(t *testing.T, data metricdata.Aggregation, runner string) int64 {
+	t.Helper()
+	s, ok := data.(metricdata.Sum[int64])
+	if !ok {
+		t.Fatalf("expected Sum[int64], got %T", data)
+	}
+	var total int64
+	for _, dp := range s.DataPoints {
+		if v, _ := dp.Attributes.Value(attribute.Key("runner")); v.AsString() == runner {
+			total += dp.Value
+		}
+	}
+	return total
+}
+
+// TestMetrics verifies that commits, rollbacks and durations are recorded with
+// the runner attribute.
+func TestMetrics(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+
+	metrics, err := NewMetrics(provider.Meter("uow"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := uow.New(uow.NewMockTx(), uow.WithMetrics(metrics))
+	ctx := context.Background()
+
+	_ = u.Run(ctx, func(_ context.Context) error { return nil })
+	_ = u.Run(ctx, func(_ context.Context) error { return nil })
+	_ = u.Run(ctx, func(_ context.Context) error { return errors.New("fn failed") })
+
+	got := collect(t, reader)
+	const runner = "*uow.MockTx"
+	if n := sum(t, got["uow.commits"], runner); n != 2 {
+		t.Errorf("expected 2 commits, got %d", n)
+	}
+	if n := sum(t, got["uow.rollbacks"], runner); n != 1 {
+		t.Errorf("expected 1 rollback, got %d", n)
+	}
+
+	hist, ok := got["uow.transaction.duration"].(metricdata.Histogram[float64])
+	if !ok {
+		t.Fatalf("expected Histogram[float64], got %T", got["uow.transaction.duration"])
+	}
+	var count uint64
+	for _, dp := range hist.DataPoints {
+		count += dp.Count
+	}
+	if count != 3 {
+		t.Errorf("expected 3 duration samples, got %d", count)
+	}
+}
+
+// failingRunner fails to start transactions.
+type failingRunner struct {
+	uow.Runner
+}
+
+func (failingRunner) Ctx(_ context.Context) (context.Context, error) {
+	return nil, errors.New("begin failed")
+}
+
+// TestMetrics_BeginError verifies that failed begins are counted.
+func TestMetrics_BeginError(t *testing.T) {
+	reader := sdkmetric.NewManualReader()
+	provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	defer func() { _ = provider.Shutdown(context.Background()) }()
+
+	metrics, err := NewMetrics(provider.Meter("uow"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := uow.New(failingRunner{}, uow.WithMetrics(metrics))
+	_ = u.Run(context.Background(), func(_ context.Context) error { return nil })
+
+	if n := sum(t, collect(t, reader)["uow.begin_errors"], "uowotel.failingRunner"); n != 1 {
+		t.Errorf("expected 1 begin error, got %d", n)
+	}
+}
@@ -0,0 +1,79 @@
+// Package uowprom integrates the uow package with Prometheus. It lives in its
+// own package so that importing uow does not pull in the Prometheus client.
+package uowprom
+
+import (
+	"time"
+
+	"github.com/agtabesh/uow"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics implements uow.MetricsCollector on top of the Prometheus client. It
+// records transaction outcomes as counters and transaction latency as a
+// histogram, all labeled with the runner type.
+var _ uow.MetricsCollector = &Metrics{}
+
+// Metrics struct holds the registered collectors.
+type Metrics struct {
+	commits     *prometheus.CounterVec
+	rollbacks   *prometheus.CounterVec
+	beginErrors *prometheus.CounterVec
+	duration    *prometheus.HistogramVec
+}
+
+// NewMetrics registers the collectors with reg and returns a collector to pass
+// to uow.WithMetrics. The following metrics are registered:
+//
+//   - uow_commits_total: number of committed transactions
+//   - uow_rollbacks_total: number of rolled back transactions
+//   - uow_begin_errors_total: number of transactions that failed to start
+//   - uow_transaction_duration_seconds: transaction latency, with an
+//     "outcome" label of "commit" or "rollback"
+//
+// Every metric carries a "runner" label with the runner type name.
+func NewMetrics(reg prometheus.Registerer) (*Metrics, error) {
+	m := &Metrics{
+		commits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "uow_commits_total",
+			Help: "Number of committed transactions.",
+		}, []string{"runner"}),
+		rollbacks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "uow_rollbacks_total",
+			Help: "Number of rolled back transactions.",
+		}, []string{"runner"}),
+		beginErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "uow_begin_errors_total",
+			Help: "Number of transactions that failed to start.",
+		}, []string{"runner"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "uow_transaction_duration_seconds",
+			Help:    "Duration of transactions.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"runner", "outcome"}),
+	}
+
+	for _, c := range []prometheus.Collector{m.commits, m.rollbacks, m.beginErrors, m.duration} {
+		if err := reg.Register(c); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+// ObserveCommit records a committed transaction.
+func (m *Metrics) ObserveCommit(runner string, d time.Duration) {
+	m.commits.WithLabelValues(runner).Inc()
+	m.duration.WithLabelValues(runner, "commit").Observe(d.Seconds())
+}
+
+// ObserveRollback records a rolled back transaction.
+func (m *Metrics) ObserveRollback(runner string, d time.Duration, _ error) {
+	m.rollbacks.WithLabelValues(runner).Inc()
+	m.duration.WithLabelValues(runner, "rollback").Observe(d.Seconds())
+}
+
+// ObserveBeginError records a transaction that failed to start.
+func (m *Metrics) ObserveBeginError(runner string, _ error) {
+	m.beginErrors.WithLabelValues(runner).Inc()
+}
@@ -0,0 +1,68 @@
+package uowprom
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/agtabesh/uow"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestMetrics verifies that commits, rollbacks and durations are recorded with
+// the runner label.
+func TestMetrics(t *testing.T) {
+	reg := prometheus.NewPedanticRegistry()
+	metrics, err := NewMetrics(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := uow.New(uow.NewMockTx(), uow.WithMetrics(metrics))
+	ctx := context.Background()
+
+	_ = u.Run(ctx, func(_ context.Context) error { return nil })
+	_ = u.Run(ctx, func(_ context.Context) error { return nil })
+	_ = u.Run(ctx, func(_ context.Context) error { return errors.New("fn failed") })
+
+	const runner = "*uow.MockTx"
+	if n := testutil.ToFloat64(metrics.commits.WithLabelValues(runner)); n != 2 {
+		t.Errorf("expected 2 commits, got %v", n)
+	}
+	if n := testutil.ToFloat64(metrics.rollbacks.WithLabelValues(runner)); n != 1 {
+		t.Errorf("expected 1 rollback, got %v", n)
+	}
+	if n := testutil.CollectAndCount(metrics.duration, "uow_transaction_duration_seconds"); n != 2 {
+		t.Errorf("expected duration series for commit and rollback, got %d", n)
+	}
+	problems, err := testutil.GatherAndLint(reg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(problems) != 0 {
+		t.Errorf("expected no lint problems, got %v", problems)
+	}
+}
+
+// failingRunner fails to start transactions.
+type failingRunner struct {
+	uow.Runner
+}
+
+func (failingRunner) Ctx(_ context.Context) (context.Context, error) {
+	return nil, errors.New("begin failed")
+}
+
+// TestMetrics_BeginError verifies that failed begins are counted.
+func TestMetrics_BeginError(t *testing.T) {
+	metrics, err := NewMetrics(prometheus.NewRegistry())
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := uow.New(failingRunner{}, uow.WithMetrics(metrics))
+	_ = u.Run(context.Background(), func(_ context.Context) error { return nil })
+
+	if n := testutil.ToFloat64(metrics.beginErrors.WithLabelValues("uowprom.failingRunner")); n != 1 {
+		t.Errorf("expected 1 begin error, got %v", n)
+	}
+}
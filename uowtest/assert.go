@@ -0,0 +1,42 @@
+package uowtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/agtabesh/uow"
+)
+
+// AssertCommitted fails the test when the last transaction of mt was not
+// committed, reporting the calls made on the mock.
+func AssertCommitted(t testing.TB, mt *uow.MockTx) {
+	t.Helper()
+	if got := mt.State().Status(); got != uow.StateCommitted {
+		t.Errorf("expected the transaction to be committed, got %v after calls %v", got, mt.Ops())
+	}
+}
+
+// AssertRolledBack fails the test when the last transaction of mt was not
+// rolled back, reporting the calls made on the mock.
+func AssertRolledBack(t testing.TB, mt *uow.MockTx) {
+	t.Helper()
+	if got := mt.State().Status(); got != uow.StateRolledBack {
+		t.Errorf("expected the transaction to be rolled back, got %v after calls %v", got, mt.Ops())
+	}
+}
+
+// RunAndExpectError runs fn through u and fails the test unless Run returns
+// an error matching wantErr with errors.Is, or no error when wantErr is nil.
+// It returns the error of Run for further assertions.
+func RunAndExpectError(t testing.TB, u *uow.UoW, fn func(ctx context.Context) error, wantErr error) error {
+	t.Helper()
+	err := u.Run(context.Background(), fn)
+	switch {
+	case wantErr == nil && err != nil:
+		t.Errorf("expected no error, got %v", err)
+	case wantErr != nil && !errors.Is(err, wantErr):
+		t.Errorf("expected error %v, got %v", wantErr, err)
+	}
+	return err
+}
@@ -0,0 +1,57 @@
+package uowtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/agtabesh/uow"
+)
+
+// recordingT is a testing.TB that records failures instead of failing the
+// test.
+type recordingT struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recordingT) Helper() {}
+
+func (r *recordingT) Errorf(format string, args ...any) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+// TestAssertions verifies that the assertions pass for the matching outcome
+// and report a failure otherwise.
+func TestAssertions(t *testing.T) {
+	mt := uow.NewMockTx()
+	u := uow.New(mt)
+	fnErr := errors.New("fn failed")
+
+	rt := &recordingT{TB: t}
+	RunAndExpectError(rt, &u, func(_ context.Context) error { return nil }, nil)
+	AssertCommitted(rt, mt)
+	if len(rt.failures) != 0 {
+		t.Errorf("expected no failures after a commit, got %v", rt.failures)
+	}
+	AssertRolledBack(rt, mt)
+	if len(rt.failures) != 1 {
+		t.Errorf("expected AssertRolledBack to fail after a commit, got %v", rt.failures)
+	}
+
+	rt = &recordingT{TB: t}
+	err := RunAndExpectError(rt, &u, func(_ context.Context) error { return fnErr }, fnErr)
+	if !errors.Is(err, fnErr) {
+		t.Errorf("expected the error of Run to be returned, got %v", err)
+	}
+	AssertRolledBack(rt, mt)
+	if len(rt.failures) != 0 {
+		t.Errorf("expected no failures after a rollback, got %v", rt.failures)
+	}
+	AssertCommitted(rt, mt)
+	RunAndExpectError(rt, &u, func(_ context.Context) error { return nil }, fnErr)
+	if len(rt.failures) != 2 {
+		t.Errorf("expected AssertCommitted and RunAndExpectError to fail, got %v", rt.failures)
+	}
+}
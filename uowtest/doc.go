@@ -0,0 +1,3 @@
+// Package uowtest provides test doubles and helpers for code built on the uow
+// package.
+package uowtest
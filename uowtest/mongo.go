@@ -0,0 +1,162 @@
+package uowtest
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/agtabesh/uow"
+)
+
+// ctxKey is an unexported struct type used for context value keys, so that the
+// keys cannot collide with those of other packages.
+type ctxKey struct {
+	name string
+}
+
+// sessionKey is the context key for storing the fake session.
+var sessionKey = ctxKey{"fake_mongo_session"}
+
+// joinedKey is the context key marking a unit of work that joined the session
+// of an enclosing one.
+var joinedKey = ctxKey{"fake_mongo_joined"}
+
+// ErrSessionEnded is returned when a fake session is committed or aborted after
+// it already ended, mirroring the error the MongoDB driver returns.
+var ErrSessionEnded = errors.New("ended session was used")
+
+// FakeMongoRunner implements the uow.Runner interface and mimics the context
+// and session propagation of uow.MongoTx without a transactional MongoDB
+// server. Ctx stores a FakeSession in the context, Get returns the configured
+// database, and Commit/Rollback commit or abort the session and end it, just
+// like the real runner. This lets code written against MongoTx be unit-tested
+// without a replica set.
+var _ uow.Runner = &FakeMongoRunner{}
+
+// FakeMongoRunner struct holds the value returned by Get and the sessions it
+// started.
+type FakeMongoRunner struct {
+	db any
+
+	mu       sync.Mutex
+	sessions []*FakeSession
+}
+
+// NewFakeMongoRunner creates a new FakeMongoRunner. db is returned by Get and
+// is typically a *mongo.Database from a client that never connects, or any
+// test double the code under test expects.
+func NewFakeMongoRunner(db any) *FakeMongoRunner {
+	return &FakeMongoRunner{
+		db: db,
+	}
+}
+
+// FakeSession stands in for a mongo.Session. It records how the transaction
+// started on it was finished.
+type FakeSession struct {
+	// ID is the 1-based sequence number of the session within its runner.
+	ID int
+
+	mu        sync.Mutex
+	committed bool
+	aborted   bool
+	ended     bool
+}
+
+// Committed reports whether the transaction on the session was committed.
+func (s *FakeSession) Committed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.committed
+}
+
+// Aborted reports whether the transaction on the session was aborted.
+func (s *FakeSession) Aborted() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.aborted
+}
+
+// Ended reports whether the session was ended.
+func (s *FakeSession) Ended() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ended
+}
+
+// finish marks the session as committed or aborted and ends it.
+func (s *FakeSession) finish(commit bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ended {
+		return ErrSessionEnded
+	}
+	if commit {
+		s.committed = true
+	} else {
+		s.aborted = true
+	}
+	s.ended = true
+	return nil
+}
+
+// SessionFromContext returns the fake session stored in ctx by a
+// FakeMongoRunner, or nil when there is none.
+func SessionFromContext(ctx context.Context) *FakeSession {
+	sess, _ := ctx.Value(sessionKey).(*FakeSession)
+	return sess
+}
+
+// Sessions returns the sessions started by the runner, in order.
+func (f *FakeMongoRunner) Sessions() []*FakeSession {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*FakeSession(nil), f.sessions...)
+}
+
+// Ctx starts a new fake session and stores it in the returned context. Like
+// uow.MongoTx, it joins the session already present in ctx instead, leaving
+// Commit and Rollback to the outermost unit of work.
+func (f *FakeMongoRunner) Ctx(ctx context.Context) (context.Context, error) {
+	if sess := SessionFromContext(ctx); sess != nil {
+		return context.WithValue(ctx, joinedKey, sess), nil
+	}
+
+	f.mu.Lock()
+	sess := &FakeSession{ID: len(f.sessions) + 1}
+	f.sessions = append(f.sessions, sess)
+	f.mu.Unlock()
+
+	return context.WithValue(ctx, sessionKey, sess), nil
+}
+
+// Get returns the configured database, with or without an active session, as
+// the real runner does.
+func (f *FakeMongoRunner) Get(_ context.Context) any {
+	return f.db
+}
+
+// Rollback aborts the transaction on the session in the context and ends the
+// session. It is a no-op when there is no session or the session was joined.
+func (f *FakeMongoRunner) Rollback(ctx context.Context) error {
+	if sess := SessionFromContext(ctx); sess != nil && !joined(ctx, sess) {
+		return sess.finish(false)
+	}
+	return nil
+}
+
+// Commit commits the transaction on the session in the context and ends the
+// session. It is a no-op when there is no session or the session was joined.
+func (f *FakeMongoRunner) Commit(ctx context.Context) error {
+	if sess := SessionFromContext(ctx); sess != nil && !joined(ctx, sess) {
+		return sess.finish(true)
+	}
+	return nil
+}
+
+// joined reports whether ctx belongs to a unit of work that joined sess
+// instead of starting it.
+func joined(ctx context.Context, sess *FakeSession) bool {
+	outer, _ := ctx.Value(joinedKey).(*FakeSession)
+	return outer == sess
+}
@@ -0,0 +1,115 @@
+package uowtest
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/agtabesh/uow"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// newDatabase returns a *mongo.Database from a client that never connects.
+func newDatabase(t *testing.T) *mongo.Database {
+	t.Helper()
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI("mongodb://localhost:1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = client.Disconnect(context.Background()) })
+	return client.Database("test")
+}
+
+// TestFakeMongoRunner_Commit verifies that the fake propagates the session
+// marker into fn and commits and ends the session on success.
+func TestFakeMongoRunner_Commit(t *testing.T) {
+	db := newDatabase(t)
+	fake := NewFakeMongoRunner(db)
+	txs := uow.New(fake)
+
+	var inFn *FakeSession
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		inFn = SessionFromContext(ctx)
+		if got := txs.Get(ctx).(*mongo.Database); got != db {
+			t.Errorf("expected configured database, got %v", got)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessions := fake.Sessions()
+	if len(sessions) != 1 || inFn != sessions[0] {
+		t.Fatalf("expected fn to see the single started session, got %v", sessions)
+	}
+	if !inFn.Committed() || inFn.Aborted() || !inFn.Ended() {
+		t.Errorf("expected committed and ended session, got committed=%v aborted=%v ended=%v",
+			inFn.Committed(), inFn.Aborted(), inFn.Ended())
+	}
+}
+
+// TestFakeMongoRunner_Rollback verifies that the fake aborts and ends the
+// session when fn fails.
+func TestFakeMongoRunner_Rollback(t *testing.T) {
+	fake := NewFakeMongoRunner(newDatabase(t))
+	txs := uow.New(fake)
+	fnErr := errors.New("fn failed")
+
+	err := txs.Run(context.Background(), func(_ context.Context) error {
+		return fnErr
+	})
+	if !errors.Is(err, fnErr) {
+		t.Fatalf("expected fn error, got %v", err)
+	}
+
+	sess := fake.Sessions()[0]
+	if sess.Committed() || !sess.Aborted() || !sess.Ended() {
+		t.Errorf("expected aborted and ended session, got committed=%v aborted=%v ended=%v",
+			sess.Committed(), sess.Aborted(), sess.Ended())
+	}
+	if err := fake.Rollback(context.WithValue(context.Background(), sessionKey, sess)); !errors.Is(err, ErrSessionEnded) {
+		t.Errorf("expected ErrSessionEnded when reusing the session, got %v", err)
+	}
+}
+
+// TestFakeMongoRunner_NoSession verifies that the fake has no session outside
+// a unit of work and that Commit/Rollback are no-ops there.
+func TestFakeMongoRunner_NoSession(t *testing.T) {
+	fake := NewFakeMongoRunner(nil)
+	ctx := context.Background()
+	if SessionFromContext(ctx) != nil {
+		t.Error("expected no session outside a unit of work")
+	}
+	if err := fake.Commit(ctx); err != nil {
+		t.Errorf("expected no-op commit, got %v", err)
+	}
+	if err := fake.Rollback(ctx); err != nil {
+		t.Errorf("expected no-op rollback, got %v", err)
+	}
+}
+
+// TestFakeMongoRunner_Nested verifies that a nested Run joins the session of
+// the enclosing one, as uow.MongoTx does.
+func TestFakeMongoRunner_Nested(t *testing.T) {
+	fake := NewFakeMongoRunner(newDatabase(t))
+	txs := uow.New(fake)
+
+	err := txs.Run(context.Background(), func(ctx context.Context) error {
+		return txs.Run(ctx, func(ctx context.Context) error {
+			if sess := SessionFromContext(ctx); sess == nil || sess.Ended() {
+				t.Error("expected the inner unit of work to use the open outer session")
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sessions := fake.Sessions()
+	if len(sessions) != 1 || !sessions[0].Committed() {
+		t.Errorf("expected a single committed session, got %d", len(sessions))
+	}
+}
@@ -0,0 +1,41 @@
+package uow
+
+import "context"
+
+// SetValue stores value under key for the rest of the attempt ctx belongs
+// to, so that code called from fn, such as repositories, can read it with
+// GetValue without it being passed along explicitly. The values are
+// discarded when the attempt ends: a retried unit of work starts without
+// them. Like context keys, key should be of a type defined by the caller to
+// avoid collisions. SetValue reports false, and does nothing, when ctx does
+// not belong to a unit of work.
+func SetValue(ctx context.Context, key, value any) bool {
+	rs := runStateFrom(ctx)
+	if rs == nil {
+		return false
+	}
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.values == nil {
+		rs.values = make(map[any]any)
+	}
+	rs.values[key] = value
+	return true
+}
+
+// GetValue returns the value stored under key with SetValue in the unit of
+// work ctx belongs to. Inside a nested Run, values stored by the enclosing
+// units of work are visible too, while values stored by the nested one are
+// not visible outside of it. GetValue reports false when no value is stored
+// under key.
+func GetValue(ctx context.Context, key any) (any, bool) {
+	for rs := runStateFrom(ctx); rs != nil; rs = rs.parent {
+		rs.mu.Lock()
+		v, ok := rs.values[key]
+		rs.mu.Unlock()
+		if ok {
+			return v, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,54 @@
+package uow
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// valueKey is the key type used by the value tests.
+type valueKey string
+
+// TestValues verifies that values are visible within the unit of work and its
+// nested units, and are discarded when it ends.
+func TestValues(t *testing.T) {
+	const userKey valueKey = "user"
+	if SetValue(context.Background(), userKey, "alice") {
+		t.Error("expected SetValue to report false outside a unit of work")
+	}
+
+	u := New(NewMockTx())
+	err := u.Run(context.Background(), func(ctx context.Context) error {
+		if !SetValue(ctx, userKey, "alice") {
+			t.Error("expected SetValue to report true inside a unit of work")
+		}
+		err := u.Run(ctx, func(ctx context.Context) error {
+			if v, ok := GetValue(ctx, userKey); !ok || v != "alice" {
+				t.Errorf("expected the outer value in a nested unit of work, got %v", v)
+			}
+			SetValue(ctx, valueKey("inner"), true)
+			return nil
+		})
+		if _, ok := GetValue(ctx, valueKey("inner")); ok {
+			t.Error("expected the nested value not to leak into the outer unit of work")
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	attempts := 0
+	u = New(NewMockTx(), WithMaxRetries(1), WithRetryIf(func(error) bool { return true }))
+	_ = u.Run(context.Background(), func(ctx context.Context) error {
+		attempts++
+		if _, ok := GetValue(ctx, userKey); ok {
+			t.Errorf("expected attempt %d to start without values", attempts)
+		}
+		SetValue(ctx, userKey, "alice")
+		return errors.New("fn failed")
+	})
+	if attempts != 2 {
+		t.Errorf("expected 2 attempts, got %d", attempts)
+	}
+}
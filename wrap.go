@@ -0,0 +1,68 @@
+package uow
+
+import "context"
+
+// runnerWrapper is implemented by decorators wrapping another runner, such as
+// TimeoutRunner. Decorators forward the optional runner interfaces to the
+// wrapped runner, so they implement them even when the wrapped runner does
+// not; supports looks through them to tell.
+type runnerWrapper interface {
+	unwrap() Runner
+}
+
+// supports reports whether r implements the optional interface T, looking
+// through decorators to the runner they wrap.
+func supports[T any](r Runner) bool {
+	for {
+		if _, ok := r.(T); !ok {
+			return false
+		}
+		w, ok := r.(runnerWrapper)
+		if !ok {
+			return true
+		}
+		r = w.unwrap()
+	}
+}
+
+// savepoint creates a savepoint on r, for runners wrapping another runner. It
+// returns ErrSavepointsUnsupported when r does not implement Savepointer.
+func savepoint(ctx context.Context, r Runner, name string) error {
+	sp, ok := r.(Savepointer)
+	if !ok {
+		return ErrSavepointsUnsupported
+	}
+	return sp.Savepoint(ctx, name)
+}
+
+// rollbackToSavepoint rolls r back to a savepoint, for runners wrapping another
+// runner. It returns ErrSavepointsUnsupported when r does not implement
+// Savepointer.
+func rollbackToSavepoint(ctx context.Context, r Runner, name string) error {
+	sp, ok := r.(Savepointer)
+	if !ok {
+		return ErrSavepointsUnsupported
+	}
+	return sp.RollbackToSavepoint(ctx, name)
+}
+
+// releaseSavepoint releases a savepoint on r, for runners wrapping another
+// runner. It returns ErrSavepointsUnsupported when r does not implement
+// Savepointer.
+func releaseSavepoint(ctx context.Context, r Runner, name string) error {
+	sp, ok := r.(Savepointer)
+	if !ok {
+		return ErrSavepointsUnsupported
+	}
+	return sp.ReleaseSavepoint(ctx, name)
+}
+
+// compensate compensates r, for runners wrapping another runner. It returns
+// ErrCompensationUnsupported when r does not implement Compensator.
+func compensate(ctx context.Context, r Runner) error {
+	c, ok := r.(Compensator)
+	if !ok {
+		return ErrCompensationUnsupported
+	}
+	return c.Compensate(ctx)
+}